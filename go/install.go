@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"xiacutai-server/internal/api"
+	"xiacutai-server/internal/component/sqllite"
+	"xiacutai-server/internal/utils"
+)
+
+// install 离线安装本地模型压缩包，与 POST /init/install 相同，安装完成后退出
+func install(args []string) int {
+	fs := flag.NewFlagSet("install", flag.ExitOnError)
+	checksum := fs.String("sha256", "", "压缩包的 sha256，可选")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "使用方法: xiacutai-server install [-sha256 <hex>] <model.zip>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	utils.InitDirs()
+	sqllite.Init()
+
+	key, err := api.InstallModelPackage(fs.Arg(0), *checksum)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "安装失败:", err)
+		return 1
+	}
+	fmt.Println("安装完成:", key)
+	return 0
+}
@@ -2,8 +2,6 @@ package api
 
 import (
 	"encoding/json"
-	"net/http"
-	"strconv"
 	"strings"
 	"xiacutai-server/internal/component/errs"
 	"xiacutai-server/internal/component/log"
@@ -33,17 +31,23 @@ type storageUpdateRequest struct {
 }
 
 func DataStorageGet(ctx *gin.Context) {
-	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
-	if err != nil || id <= 0 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+	var req taskOperateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Err(ctx, err)
+		return
+	}
+	if req.ID <= 0 {
+		Err(ctx, errs.ParamError)
 		return
 	}
-	record, err := service.DataStorage.GetStorage(id)
+	record, err := service.DataStorage.GetStorage(req.ID)
 	if err != nil {
 		Err(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, record)
+	OK(ctx, gin.H{
+		"data": record,
+	})
 }
 
 func DataStorageList(ctx *gin.Context) {
@@ -180,12 +184,7 @@ func DataStorageDelete(ctx *gin.Context) {
 		Err(ctx, errs.ParamError)
 		return
 	}
-	current, err := service.DataTask.GetTask(req.ID)
-	if current.Status == domain.TaskStatusRunning {
-		Err(ctx, errs.New("不可删除运行中任务"))
-		return
-	}
-	if err != nil {
+	if _, err := service.DataStorage.GetStorage(req.ID); err != nil {
 		Err(ctx, err)
 		return
 	}
@@ -199,8 +198,17 @@ func DataStorageDelete(ctx *gin.Context) {
 	})
 }
 
+type storageClearRequest struct {
+	Biz string `json:"biz"`
+}
+
 func DataStorageClear(ctx *gin.Context) {
-	biz := strings.TrimSpace(ctx.Query("biz"))
+	var req storageClearRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Err(ctx, err)
+		return
+	}
+	biz := strings.TrimSpace(req.Biz)
 	if biz == "" {
 		Err(ctx, errs.ParamError)
 		return
@@ -209,5 +217,7 @@ func DataStorageClear(ctx *gin.Context) {
 		Err(ctx, err)
 		return
 	}
-	ctx.Status(http.StatusNoContent)
+	OK(ctx, gin.H{
+		"data": biz,
+	})
 }
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"encoding/json"
 	"time"
 	"xiacutai-server/internal/component/errs"
 	"xiacutai-server/internal/component/sqllite"
@@ -13,22 +12,16 @@ import (
 
 const TypeSoundClone = "soundClone"
 
-type taskCreateRequest struct {
-	Text            string                 `json:"text"`
-	Type            string                 `json:"type"` // 类型
-	ServerKey       string                 `json:"serverKey"`
-	VideoTemplateId int64                  `json:"videoTemplateId"` // 数字人模板ID
-	Param           map[string]any         `json:"param"`
-	SoundAsr        map[string]any         `json:"soundAsr"`
-	SoundGenerate   map[string]any         `json:"soundGenerate"`
-	Extra           map[string]interface{} `json:"-"`
-	PromptId        int64                  `json:"promptId"` // 声音克隆-声音ID
-	Audio           string                 `json:"audio"`    // 语音转文字-声音文件
-	Video           string                 `json:"video"`    // 声音替换-视频文件
-}
 type taskOperateRequest struct {
 	ID int64 `json:"id"`
 }
+type taskPriorityRequest struct {
+	ID       int64 `json:"id"`
+	Priority int   `json:"priority"`
+}
+type taskReorderRequest struct {
+	IDs []int64 `json:"ids"` // 排队中任务的新顺序，靠前的先执行
+}
 type taskUpdateRequest struct {
 	ID    int64  `json:"id"`
 	Title string `json:"title"`
@@ -39,198 +32,18 @@ type soundReplaceConfirmRequest struct {
 	Records []service.SoundReplaceConfirmRecord `json:"records"`
 }
 
-var TypeBizMap = map[string]string{
-	"soundTts":     "SoundGenerate",
-	"soundClone":   "SoundGenerate",
-	"soundAsr":     "SoundAsr",
-	"videoGen":     "VideoGen",
-	"videoGenFlow": "VideoGenFlow",
-	"soundReplace": "SoundReplace",
-}
-
-type PromptContent struct {
-	URL        string `json:"url"`
-	PromptText string `json:"promptText"`
-}
-
 func DataTaskCreate(ctx *gin.Context) {
-	var req taskCreateRequest
+	var req service.TaskCreateRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		Err(ctx, err)
 		return
 	}
-
-	typeStr := req.Type
-	modelConfig := map[string]any{}
-
-	serverKey := req.ServerKey
-	model := &domain.LocalModelConfigInfo{}
-	if serverKey != "" {
-
-		dbModel, err := service.Model.Get(serverKey)
-		if err != nil {
-			Err(ctx, err)
-			return
-		}
-		model = dbModel
-	}
-
-	switch typeStr {
-	case domain.FunctionSoundTts:
-
-		modelConfig = map[string]any{
-			"type":         typeStr,
-			"ttsServerKey": serverKey,
-			"ttsParam":     req.Param,
-			"text":         req.Text,
-		}
-	case domain.FunctionSoundClone:
-
-		promptId := req.PromptId
-		storageModel, _ := service.DataStorage.GetStorage(promptId)
-		var promptContent PromptContent
-		json.Unmarshal([]byte(storageModel.Content), &promptContent)
-		modelConfig = map[string]any{
-			"type":           typeStr,
-			"cloneServerKey": serverKey,
-			"cloneParam":     req.Param,
-			"text":           req.Text,
-			"promptId":       promptId,
-			"promptTitle":    storageModel.Title,
-			"promptUrl":      promptContent.URL,
-			"promptText":     promptContent.PromptText,
-		}
-	case domain.FunctionVideoGen:
-		modelConfig = map[string]any{
-			"type":      typeStr,
-			"serverKey": serverKey,
-			"video":     req.Video,
-			"audio":     req.Audio,
-			"param":     req.Param,
-		}
-	case domain.FunctionVideoGenFlow:
-
-		cloneServerKey, _ := req.SoundGenerate["cloneServerKey"].(string)
-		ttsServerKey, _ := req.SoundGenerate["ttsServerKey"].(string)
-
-		soundGenerateServerKey := cloneServerKey
-		if soundGenerateServerKey == "" {
-			soundGenerateServerKey = ttsServerKey
-		}
-		if soundGenerateServerKey != "" {
-			soundGenerateModel, err := service.Model.Get(soundGenerateServerKey)
-			if err != nil {
-				Err(ctx, err)
-				return
-			}
-			req.SoundGenerate["serverName"] = soundGenerateModel.Name
-			req.SoundGenerate["serverTitle"] = soundGenerateModel.Title
-			req.SoundGenerate["serverVersion"] = soundGenerateModel.Version
-		}
-
-		promptId, ok := req.SoundGenerate["promptId"].(float64)
-		if ok {
-			storageModel, err := service.DataStorage.GetStorage(int64(promptId))
-			if err == nil {
-				var promptContent PromptContent
-				json.Unmarshal([]byte(storageModel.Content), &promptContent)
-				req.SoundGenerate["promptTitle"] = storageModel.Title
-				req.SoundGenerate["promptUrl"] = promptContent.URL
-				req.SoundGenerate["promptText"] = promptContent.PromptText
-			}
-		}
-		videoTemplateId := req.VideoTemplateId
-		videoTemplate, err := service.DataVideoTemplate.Get(int64(videoTemplateId))
-		if err != nil {
-			Err(ctx, err)
-			return
-		}
-		modelConfig = map[string]any{
-			"type":              typeStr,
-			"videoTemplateId":   req.VideoTemplateId,
-			"videoTemplateName": videoTemplate.Name,
-			"videoTemplateUrl":  videoTemplate.Video,
-			"soundGenerate":     req.SoundGenerate,
-			"text":              req.Text,
-		}
-	case domain.FunctionSoundReplace:
-
-		// 补充soundAsr
-
-		req.Param = map[string]any{}
-
-		// 补充 soundGenerate
-		cloneServerKey, _ := req.SoundGenerate["cloneServerKey"].(string)
-		cloneModel, err := service.Model.Get(cloneServerKey)
-		if err != nil {
-			Err(ctx, err)
-			return
-		}
-		req.SoundGenerate["serverName"] = cloneModel.Name
-		req.SoundGenerate["serverTitle"] = cloneModel.Title
-		req.SoundGenerate["serverVersion"] = cloneModel.Version
-
-		promptId := req.SoundGenerate["promptId"].(float64)
-		storageModel, err := service.DataStorage.GetStorage(int64(promptId))
-		if err != nil {
-			Err(ctx, err)
-			return
-		}
-		var promptContent PromptContent
-		json.Unmarshal([]byte(storageModel.Content), &promptContent)
-		req.SoundGenerate["promptTitle"] = storageModel.Title
-		req.SoundGenerate["promptUrl"] = promptContent.URL
-		req.SoundGenerate["promptText"] = promptContent.PromptText
-
-		modelConfig = map[string]any{
-			"type":          typeStr,
-			"video":         req.Video,
-			"soundAsr":      req.SoundAsr,
-			"soundGenerate": req.SoundGenerate,
-		}
-	case domain.FunctionSoundAsr:
-		serverKey := req.ServerKey
-		paramRaw, err := json.Marshal(map[string]any{})
-		if err != nil {
-			Err(ctx, err)
-			return
-		}
-		modelConfig = map[string]any{
-			"type":      typeStr,
-			"serverKey": serverKey,
-			"audio":     req.Audio,
-			"param":     string(paramRaw),
-		}
-	default:
-		Err(ctx, errs.New("不支持的功能"))
-		return
-	}
-
-	modelConfigRaw, err := json.Marshal(modelConfig)
-	if err != nil {
-		Err(ctx, err)
+	if req.CallbackURL != "" && !service.ValidWebhookURL(req.CallbackURL) {
+		Err(ctx, errs.New("回调地址无效，仅支持 http/https"))
 		return
 	}
 
-	paramRaw, err := json.Marshal(map[string]any{})
-	if err != nil {
-		Err(ctx, err)
-		return
-	}
-	task := domain.DataTaskModel{
-		Biz:           TypeBizMap[typeStr],
-		Title:         req.Text,
-		Status:        "queue",
-		ServerName:    model.Name,
-		ServerTitle:   model.Title,
-		ServerVersion: model.Version,
-		Param:         string(paramRaw),
-		ModelConfig:   string(modelConfigRaw),
-		JobResult:     "{}",
-		Result:        "{}",
-		Type:          1,
-	}
-	created, err := service.DataTask.CreateTask(task)
+	created, err := service.DataTask.CreateFromRequest(req)
 	if err != nil {
 		Err(ctx, err)
 		return
@@ -262,12 +75,75 @@ func DataTaskList(ctx *gin.Context) {
 		Err(ctx, err)
 		return
 	}
+	if err := service.DataTask.FillQueuePositions(list); err != nil {
+		Err(ctx, err)
+		return
+	}
 
 	OK(ctx, gin.H{
 		"data": list,
 	})
 }
 
+func DataTaskPriority(ctx *gin.Context) {
+	var req taskPriorityRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Err(ctx, err)
+		return
+	}
+	if req.ID <= 0 {
+		Err(ctx, errs.ParamError)
+		return
+	}
+	task, err := service.DataTask.UpdatePriority(req.ID, req.Priority)
+	if err != nil {
+		Err(ctx, err)
+		return
+	}
+	OK(ctx, gin.H{
+		"data": task,
+	})
+}
+
+func DataTaskMoveTop(ctx *gin.Context) {
+	var req taskOperateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Err(ctx, err)
+		return
+	}
+	if req.ID <= 0 {
+		Err(ctx, errs.ParamError)
+		return
+	}
+	task, err := service.DataTask.MoveToTop(req.ID)
+	if err != nil {
+		Err(ctx, err)
+		return
+	}
+	OK(ctx, gin.H{
+		"data": task,
+	})
+}
+
+func DataTaskReorder(ctx *gin.Context) {
+	var req taskReorderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Err(ctx, err)
+		return
+	}
+	if len(req.IDs) == 0 {
+		Err(ctx, errs.ParamError)
+		return
+	}
+	if err := service.DataTask.Reorder(req.IDs); err != nil {
+		Err(ctx, err)
+		return
+	}
+	OK(ctx, gin.H{
+		"data": req.IDs,
+	})
+}
+
 func DataTaskCancel(ctx *gin.Context) {
 	var req taskOperateRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -325,10 +201,7 @@ func DataTaskContinue(ctx *gin.Context) {
 		return
 	}
 	if current.Status == domain.TaskStatusFail {
-		task, err := service.DataTask.UpdateTask(req.ID, map[string]any{
-			"status":    domain.TaskStatusQueue,
-			"statusMsg": "",
-		})
+		task, err := service.DataTask.Continue(req.ID)
 		if err != nil {
 			Err(ctx, err)
 			return
@@ -416,3 +289,24 @@ func DataTaskUpdate(ctx *gin.Context) {
 		"data": task,
 	})
 }
+
+type taskWebhookListRequest struct {
+	TaskID int64 `json:"taskId"`
+}
+
+// DataTaskWebhookList 任务回调投递记录
+func DataTaskWebhookList(ctx *gin.Context) {
+	var req taskWebhookListRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Err(ctx, err)
+		return
+	}
+	deliveries, err := service.Webhook.ListDeliveries(req.TaskID)
+	if err != nil {
+		Err(ctx, err)
+		return
+	}
+	OK(ctx, gin.H{
+		"data": deliveries,
+	})
+}
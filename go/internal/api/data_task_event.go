@@ -0,0 +1,50 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"xiacutai-server/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const taskEventPingInterval = 15 * time.Second
+
+// DataTaskEvents 以 Server-Sent Events 推送任务事件
+// 可选查询参数 taskId、biz 过滤事件
+func DataTaskEvents(ctx *gin.Context) {
+	filter := service.TaskEventFilter{Biz: ctx.Query("biz")}
+	if raw := ctx.Query("taskId"); raw != "" {
+		filter.TaskID, _ = strconv.ParseInt(raw, 10, 64)
+	}
+
+	events, unsubscribe := service.TaskEvents.Subscribe(filter)
+	defer unsubscribe()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	// 立即返回响应头，客户端无需等到第一个事件才建立连接
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	// 定期发送 ping，避免代理因空闲断开连接
+	ticker := time.NewTicker(taskEventPingInterval)
+	defer ticker.Stop()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case event := <-events:
+			ctx.SSEvent(event.Type, event)
+			return true
+		case <-ticker.C:
+			ctx.SSEvent("ping", time.Now().UnixMilli())
+			return true
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"xiacutai-server/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDataTaskEventsUnsubscribeOnDisconnect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/data/task/events", DataTaskEvents)
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	before := service.TaskEvents.Subscribers()
+	reqCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/data/task/events?taskId=42&biz=SoundGenerate", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+	// 响应头返回时已经订阅
+	if n := service.TaskEvents.Subscribers(); n != before+1 {
+		t.Fatalf("expected %d subscribers, got %d", before+1, n)
+	}
+
+	// 不匹配的事件不推送
+	service.TaskEvents.Publish(service.TaskEvent{Type: "running", TaskID: 41, Biz: "SoundGenerate", Status: "running"})
+	service.TaskEvents.Publish(service.TaskEvent{Type: "running", TaskID: 42, Biz: "VideoGen", Status: "running"})
+	service.TaskEvents.Publish(service.TaskEvent{Type: "running", TaskID: 42, Biz: "SoundGenerate", Status: "running"})
+
+	lines := make(chan string, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < 2 {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatalf("stream closed early, got %v", got)
+			}
+			if line != "" {
+				got = append(got, line)
+			}
+		case <-timeout:
+			t.Fatalf("no event received, got %v", got)
+		}
+	}
+	if got[0] != "event:running" || !strings.Contains(got[1], `"taskId":42`) || !strings.Contains(got[1], `"biz":"SoundGenerate"`) {
+		t.Fatalf("unexpected event %v", got)
+	}
+
+	// 客户端断开后释放订阅
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for service.TaskEvents.Subscribers() != before {
+		if time.Now().After(deadline) {
+			t.Fatalf("subscriber not released, %d left", service.TaskEvents.Subscribers())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
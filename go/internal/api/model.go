@@ -2,7 +2,8 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
-	"net/http"
+	"xiacutai-server/internal/component/errs"
+	"xiacutai-server/internal/domain"
 	"xiacutai-server/internal/service"
 )
 
@@ -51,13 +52,13 @@ func ModelSetting(c *gin.Context) {
 
 	var req UpdateModelSettingReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": err.Error()})
+		Err(c, errs.ParamError)
 		return
 	}
 
 	err := service.Model.ModelUpdateSetting(req.Name, req.Version, req.Setting)
 	if err != nil {
-		c.JSON(http.StatusOK, gin.H{"code": 1, "msg": err.Error()})
+		Err(c, err)
 		return
 	}
 
@@ -73,20 +74,133 @@ func ModelDelete(c *gin.Context) {
 
 	var req deleteReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code": 400,
-			"msg":  "参数错误",
-		})
+		Err(c, errs.ParamError)
 		return
 	}
 
 	if err := service.Model.ModelDelete(req.Name, req.Version); err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"code": 1,
-			"msg":  err.Error(),
-		})
+		Err(c, err)
 		return
 	}
 
 	OK(c, gin.H{})
 }
+
+// modelKeyRequest 按 key 或 name + version 指定模型
+type modelKeyRequest struct {
+	Key     string `json:"key"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+func (r modelKeyRequest) key() string {
+	if r.Key != "" {
+		return r.Key
+	}
+	if r.Name == "" {
+		return ""
+	}
+	return domain.ModelKey(r.Name, r.Version)
+}
+
+func bindModelKey(ctx *gin.Context) (string, bool) {
+	var req modelKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.key() == "" {
+		Err(ctx, errs.ParamError)
+		return "", false
+	}
+	return req.key(), true
+}
+
+// ModelSchema 返回模型设置和各功能参数的 JSON Schema，前端据此渲染表单
+func ModelSchema(ctx *gin.Context) {
+	key, ok := bindModelKey(ctx)
+	if !ok {
+		return
+	}
+	out, err := service.Model.ModelSchema(key)
+	if err != nil {
+		Err(ctx, err)
+		return
+	}
+	OK(ctx, gin.H{
+		"data": out,
+	})
+}
+
+// ModelStart 启动模型，常驻模型等到可以接收任务后返回
+func ModelStart(ctx *gin.Context) {
+	key, ok := bindModelKey(ctx)
+	if !ok {
+		return
+	}
+	out, err := service.Model.ModelStart(key)
+	if err != nil {
+		Err(ctx, err)
+		return
+	}
+	OK(ctx, gin.H{
+		"data": out,
+	})
+}
+
+// ModelStop 停止模型，进行中的调用会被取消
+func ModelStop(ctx *gin.Context) {
+	key, ok := bindModelKey(ctx)
+	if !ok {
+		return
+	}
+	out, err := service.Model.ModelStop(key)
+	if err != nil {
+		Err(ctx, err)
+		return
+	}
+	OK(ctx, gin.H{
+		"data": out,
+	})
+}
+
+// ModelStatus 模型运行状态，不指定模型时返回全部
+func ModelStatus(ctx *gin.Context) {
+	var req modelKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Err(ctx, errs.ParamError)
+		return
+	}
+	if req.key() == "" {
+		list, err := service.Model.ModelStatusList()
+		if err != nil {
+			Err(ctx, err)
+			return
+		}
+		OK(ctx, gin.H{
+			"data": list,
+		})
+		return
+	}
+	out, err := service.Model.ModelStatus(req.key())
+	if err != nil {
+		Err(ctx, err)
+		return
+	}
+	OK(ctx, gin.H{
+		"data": out,
+	})
+}
+
+// ModelCloudAdd 注册云端模型（OpenAI 兼容的语音合成 / 语音识别接口）
+func ModelCloudAdd(ctx *gin.Context) {
+	var req service.CloudModelRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Err(ctx, err)
+		return
+	}
+	out, err := service.Model.ModelCloudAdd(req)
+	if err != nil {
+		Err(ctx, err)
+		return
+	}
+	OK(ctx, gin.H{
+		"data": out,
+	})
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"encoding/json"
 	"time"
 	"xiacutai-server/internal/component/errs"
 	"xiacutai-server/internal/component/sqllite"
@@ -14,6 +13,7 @@ import (
 type SoundCloneCreateRequest struct {
 	Text     string `json:"text"`
 	PromptId int64  `json:"promptId"` // 声音克隆-声音ID
+	Priority int    `json:"priority"` // 优先级，越大越先执行
 }
 
 func SoundCloneCreate(ctx *gin.Context) {
@@ -37,51 +37,14 @@ func SoundCloneCreate(ctx *gin.Context) {
 	param["seed"] = 403048
 	param["_seed"] = "随机种子"
 
-	modelConfig := map[string]any{}
-
-	model := modelList[0]
-	serverKey := model.Key
-
-	promptId := req.PromptId
-	storageModel, _ := service.DataStorage.GetStorage(promptId)
-	var promptContent PromptContent
-	json.Unmarshal([]byte(storageModel.Content), &promptContent)
-	modelConfig = map[string]any{
-		"type":           TypeSoundClone,
-		"cloneServerKey": serverKey,
-		"cloneParam":     param,
-		"text":           req.Text,
-		"promptId":       promptId,
-		"promptTitle":    storageModel.Title,
-		"promptUrl":      promptContent.URL,
-		"promptText":     promptContent.PromptText,
-	}
-
-	modelConfigRaw, err := json.Marshal(modelConfig)
-	if err != nil {
-		Err(ctx, err)
-		return
-	}
-
-	paramRaw, err := json.Marshal(map[string]any{})
-	if err != nil {
-		Err(ctx, err)
-		return
-	}
-	task := domain.DataTaskModel{
-		Biz:           TypeBizMap[TypeSoundClone],
-		Title:         req.Text,
-		Status:        "queue",
-		ServerName:    model.Name,
-		ServerTitle:   model.Title,
-		ServerVersion: model.Version,
-		Param:         string(paramRaw),
-		ModelConfig:   string(modelConfigRaw),
-		JobResult:     "{}",
-		Result:        "{}",
-		Type:          1,
-	}
-	created, err := service.DataTask.CreateTask(task)
+	created, err := service.DataTask.CreateFromRequest(service.TaskCreateRequest{
+		Type:      TypeSoundClone,
+		ServerKey: modelList[0].Key,
+		Param:     param,
+		Text:      req.Text,
+		PromptId:  req.PromptId,
+		Priority:  req.Priority,
+	})
 	if err != nil {
 		Err(ctx, err)
 		return
@@ -107,6 +70,10 @@ func SoundCloneList(ctx *gin.Context) {
 		Err(ctx, err)
 		return
 	}
+	if err := service.DataTask.FillQueuePositions(list); err != nil {
+		Err(ctx, err)
+		return
+	}
 
 	OK(ctx, gin.H{
 		"data": list,
@@ -170,10 +137,7 @@ func SoundCloneContinue(ctx *gin.Context) {
 		return
 	}
 	if current.Status == domain.TaskStatusFail {
-		task, err := service.DataTask.UpdateTask(req.ID, map[string]any{
-			"status":    domain.TaskStatusQueue,
-			"statusMsg": "",
-		})
+		task, err := service.DataTask.Continue(req.ID)
 		if err != nil {
 			Err(ctx, err)
 			return
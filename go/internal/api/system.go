@@ -4,7 +4,6 @@ import (
 	"archive/zip"
 	"bufio"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -74,15 +73,11 @@ func checkModelExist() bool {
 // ==============================
 // 复用 HTTP Client（避免每次 new Transport）
 // ==============================
-var sysHTTPClient = &http.Client{
-	Timeout: 60 * time.Second,
-	Transport: &http.Transport{
-		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
-		MaxIdleConns:        50,
-		MaxIdleConnsPerHost: 10,
-		IdleConnTimeout:     90 * time.Second,
-	},
-}
+var (
+	sysHTTPClient = newSysHTTPClient(tlsInsecure(), 60*time.Second)
+	// 下载模型不限制总时长，由 modelDownloader 的空闲超时判断连接中断
+	sysDownloadClient = newSysHTTPClient(tlsInsecure(), 0)
+)
 
 // ==============================
 // sys_config 拉取 + 解析工具
@@ -107,7 +102,7 @@ type sysConfigRespTyped struct {
 func fetchSysConfig(ctx context.Context) (*sysConfigRespTyped, []byte, error) {
 	defer step("拉取远端 sys_config")()
 
-	url := "https://www.xiacut.com/vapi/app/ai/client/sys_config"
+	url := utils.GetEnv("AIGCPANEL_SYS_CONFIG_URL", "https://www.xiacut.com/vapi/app/ai/client/sys_config")
 	info("准备请求 sys_config", zap.String("url", url))
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
@@ -179,8 +174,14 @@ func buildSysConfigMap(items []sysConfigItem) map[string]interface{} {
 	return out
 }
 
+// remoteModelInfo model_infos 中的一项，sha256 为压缩包的校验值（可选）
+type remoteModelInfo struct {
+	domain.LocalModelRegistryModel
+	Sha256 string `json:"sha256"`
+}
+
 // 专门解析 model_infos（兼容：content 是 JSON 字符串 或 直接 JSON）
-func parseModelInfos(modelInfosVal interface{}) ([]domain.LocalModelRegistryModel, error) {
+func parseModelInfos(modelInfosVal interface{}) ([]remoteModelInfo, error) {
 	defer step("解析 model_infos")()
 
 	if modelInfosVal == nil {
@@ -217,7 +218,7 @@ func parseModelInfos(modelInfosVal interface{}) ([]domain.LocalModelRegistryMode
 		info("model_infos 类型=fallback", zap.String("type", fmt.Sprintf("%T", v)), zap.Int("bytes", len(payload)))
 	}
 
-	var models []domain.LocalModelRegistryModel
+	var models []remoteModelInfo
 	if err := json.Unmarshal(payload, &models); err != nil {
 		errlog("解析 registryModels 失败", zap.Error(err), zap.String("payload_preview", previewBytes(payload, 512)))
 		return nil, err
@@ -228,7 +229,7 @@ func parseModelInfos(modelInfosVal interface{}) ([]domain.LocalModelRegistryMode
 }
 
 // ==============================
-// GET /sys_config
+// POST /config
 // ==============================
 func SysConfig(c *gin.Context) {
 	defer step("SysConfig 接口处理")()
@@ -273,6 +274,12 @@ type modelProgress struct {
 	Message  string    `json:"message"`
 	Error    string    `json:"error,omitempty"`
 	Updated  time.Time `json:"updated"`
+
+	// 下载阶段的字节级进度
+	Downloaded int64   `json:"downloaded,omitempty"` // 已下载字节
+	Total      int64   `json:"total,omitempty"`      // 压缩包大小，未知时为 0
+	Percent    int     `json:"percent,omitempty"`    // 下载百分比
+	Speed      float64 `json:"speed,omitempty"`      // 下载速度（字节/秒）
 }
 
 type sysInitTask struct {
@@ -320,7 +327,7 @@ func calcTotalProgress(models map[string]*modelProgress) int {
 	return int(math.Round(float64(sum) / float64(n)))
 }
 
-// GET /sys_init
+// POST /init
 func SysInit(c *gin.Context) {
 	defer step("SysInit 接口处理")()
 
@@ -374,7 +381,7 @@ func SysInit(c *gin.Context) {
 
 	// ✅ 关键修复：创建 task 时先查 DB，把已就绪的直接标成“就绪”，不要“排队中”
 	for _, rm := range registryModels {
-		key := strings.TrimSpace(rm.Key)
+		key := registryKey(rm.LocalModelRegistryModel)
 		if key == "" {
 			continue
 		}
@@ -431,11 +438,11 @@ func SysInit(c *gin.Context) {
 	})
 }
 
-func runInit(models []domain.LocalModelRegistryModel) {
+func runInit(models []remoteModelInfo) {
 	defer step("runInit 模型初始化总流程", zap.Int("models", len(models)))()
 
 	for i, rm := range models {
-		key := strings.TrimSpace(rm.Key)
+		key := registryKey(rm.LocalModelRegistryModel)
 		if key == "" {
 			warn("跳过模型：key 为空", zap.Int("index", i))
 			continue
@@ -478,38 +485,48 @@ func runInit(models []domain.LocalModelRegistryModel) {
 			info("模型已存在，跳过下载", zap.String("key", key))
 		}
 
-		localModelConfigInfo, err = service.Model.GetByDB(key)
-		if err != nil {
-			updateModel(key, -1, 100, "DB 查询失败", err.Error())
-			continue
-		}
-		if localModelConfigInfo == nil {
-			updateModel(key, -1, 100, "模型信息缺失", "db returned nil after download")
-			continue
-		}
+		finishModelInstall(key)
+	}
 
-		if localModelConfigInfo.Status == "3" {
-			// 安装依赖（占位）
-			updateModel(key, 4, 75, "安装依赖中", "")
-			info("开始安装依赖", zap.String("key", key))
+	finishInitTask()
+}
 
-			t2 := time.Now()
-			err = installDepsPlaceholder(key)
-			info("安装依赖完成", zap.String("key", key), zap.Duration("耗时", time.Since(t2)), zap.Error(err))
-			if err != nil {
-				updateModel(key, -1, 100, "依赖安装失败", err.Error())
-				continue
-			}
+// finishModelInstall 模型文件就绪后安装依赖并标记就绪，失败时返回 false
+func finishModelInstall(key string) bool {
+	localModelConfigInfo, err := service.Model.GetByDB(key)
+	if err != nil {
+		updateModel(key, -1, 100, "DB 查询失败", err.Error())
+		return false
+	}
+	if localModelConfigInfo == nil {
+		updateModel(key, -1, 100, "模型信息缺失", "db returned nil after download")
+		return false
+	}
 
-			updateModel(key, 5, 100, "就绪", "")
-			info("模型就绪", zap.String("key", key))
-		} else if localModelConfigInfo.Status == "5" {
-			// 双保险：可能 DB 状态被其他流程更新到 5
-			updateModel(key, 5, 100, "就绪", "")
+	if localModelConfigInfo.Status == "3" {
+		// 安装依赖（占位）
+		updateModel(key, 4, 75, "安装依赖中", "")
+		info("开始安装依赖", zap.String("key", key))
+
+		t2 := time.Now()
+		err = installDeps(key)
+		info("安装依赖完成", zap.String("key", key), zap.Duration("耗时", time.Since(t2)), zap.Error(err))
+		if err != nil {
+			updateModel(key, -1, 100, "依赖安装失败", err.Error())
+			return false
 		}
+
+		updateModel(key, 5, 100, "就绪", "")
+		info("模型就绪", zap.String("key", key))
+	} else if localModelConfigInfo.Status == "5" {
+		// 双保险：可能 DB 状态被其他流程更新到 5
+		updateModel(key, 5, 100, "就绪", "")
 	}
+	return true
+}
 
-	// 任务收尾
+// finishInitTask 所有模型处理完后结束初始化任务
+func finishInitTask() {
 	initMu.Lock()
 	defer initMu.Unlock()
 
@@ -538,11 +555,14 @@ func runInit(models []domain.LocalModelRegistryModel) {
 // 你可以把 models 根目录做成配置项；这里先用相对路径示例
 var modelsRootDir = "models"
 
+// installDeps 安装模型依赖，测试中替换
+var installDeps = installDepsPlaceholder
+
 // downloadModelPlaceholder：下载 zip -> 解压 -> 找 config -> ModelAdd
-func downloadModelPlaceholder(rm domain.LocalModelRegistryModel) error {
+func downloadModelPlaceholder(rm remoteModelInfo) error {
 	defer step("下载模型完整流程", zap.String("key", rm.Key))()
 
-	key := strings.TrimSpace(rm.Key)
+	key := registryKey(rm.LocalModelRegistryModel)
 	if key == "" {
 		errlog("模型 key 为空，无法下载")
 		return errors.New("empty model key")
@@ -554,17 +574,19 @@ func downloadModelPlaceholder(rm domain.LocalModelRegistryModel) error {
 		return fmt.Errorf("model %s missing download url", key)
 	}
 
-	info("下载参数", zap.String("key", key), zap.String("url", url))
+	info("下载参数", zap.String("key", key), zap.String("url", url), zap.Bool("校验", rm.Sha256 != ""))
 
-	// 下载 zip
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
+	// 下载 zip：未完成的部分保留在 .download 目录，下次初始化时续传
+	zipPath := filepath.Join(modelsRootDir, ".download", getDirByKey(key)+".zip")
+	downloader := newModelDownloader(func(done, total int64, speed float64) {
+		updateDownload(key, done, total, speed)
+	})
 
 	t0 := time.Now()
-	tmpZipPath, err := downloadToTempZip(ctx, sysHTTPClient, url)
+	err := downloader.Download(context.Background(), url, zipPath, rm.Sha256)
 	info("下载压缩包完成",
 		zap.String("key", key),
-		zap.String("tmp_zip", tmpZipPath),
+		zap.String("zip", zipPath),
 		zap.Duration("耗时", time.Since(t0)),
 		zap.Error(err),
 	)
@@ -572,10 +594,15 @@ func downloadModelPlaceholder(rm domain.LocalModelRegistryModel) error {
 		return err
 	}
 	defer func() {
-		_ = os.Remove(tmpZipPath)
-		info("清理临时压缩包", zap.String("tmp_zip", tmpZipPath), zap.String("key", key))
+		_ = os.Remove(zipPath)
+		info("清理压缩包", zap.String("zip", zipPath), zap.String("key", key))
 	}()
 
+	return extractAndAddModel(key, zipPath)
+}
+
+// extractAndAddModel 解压模型压缩包到 models 目录 -> 找 config -> ModelAdd
+func extractAndAddModel(key, zipPath string) error {
 	// 解压
 	info("解压目标目录", zap.String("dest_dir", modelsRootDir), zap.String("key", key))
 
@@ -585,7 +612,7 @@ func downloadModelPlaceholder(rm domain.LocalModelRegistryModel) error {
 	}
 
 	t1 := time.Now()
-	files, err := unzipSafeWithCount(tmpZipPath, modelsRootDir)
+	files, err := unzipSafeWithCount(zipPath, modelsRootDir)
 	info("解压完成",
 		zap.String("key", key),
 		zap.Int("files", files),
@@ -610,6 +637,12 @@ func downloadModelPlaceholder(rm domain.LocalModelRegistryModel) error {
 		return fmt.Errorf("model %s: %w", key, err)
 	}
 
+	// 已注册的模型（上次依赖安装失败后重新安装）只覆盖文件，不重复注册
+	if local, err := service.Model.GetByDB(key); err == nil && local.Key != "" {
+		info("模型已注册，跳过 ModelAdd", zap.String("key", key), zap.String("path", local.LocalPath))
+		return nil
+	}
+
 	// ModelAdd
 	info("开始调用 ModelAdd", zap.String("key", key), zap.String("cfg_path", cfgPath))
 	t3 := time.Now()
@@ -628,66 +661,20 @@ func downloadModelPlaceholder(rm domain.LocalModelRegistryModel) error {
 	return nil
 }
 
-func getDirByKey(key string) string {
-	keySplits := strings.Split(key, "|")
-	dirName := keySplits[0] + "-win-x86-v" + keySplits[1]
-	return dirName
-}
-
-// 下载 URL 到一个临时 zip 文件，返回临时文件路径
-func downloadToTempZip(ctx context.Context, client *http.Client, url string) (string, error) {
-	defer step("下载压缩包", zap.String("url", url))()
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		errlog("创建下载请求失败", zap.Error(err))
-		return "", err
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		errlog("下载请求失败", zap.Error(err))
-		return "", err
+// registryKey sys_config 中模型对应的注册表 key，key 没有版本号时用 name、version 补齐
+func registryKey(rm domain.LocalModelRegistryModel) string {
+	if _, _, ok := domain.ParseModelKey(rm.Key); !ok && strings.TrimSpace(rm.Name) != "" && strings.TrimSpace(rm.Version) != "" {
+		return domain.ModelKey(rm.Name, rm.Version)
 	}
-	defer resp.Body.Close()
-
-	info("下载响应", zap.String("status", resp.Status), zap.Int("status_code", resp.StatusCode), zap.Int64("content_length", resp.ContentLength))
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 8*1024))
-		errlog("下载失败（非 2xx）", zap.String("status", resp.Status), zap.String("body_preview", string(b)))
-		return "", fmt.Errorf("download failed: %s: %s", resp.Status, string(b))
-	}
-
-	const maxBytes = int64(2 << 30) // 2GB
-	body := io.LimitReader(resp.Body, maxBytes+1)
-
-	f, err := os.CreateTemp("", "model_*.zip")
-	if err != nil {
-		errlog("创建临时文件失败", zap.Error(err))
-		return "", err
-	}
-	defer func() {
-		_ = f.Close()
-		if err != nil {
-			_ = os.Remove(f.Name())
-		}
-	}()
-
-	info("临时 zip 路径", zap.String("tmp_zip", f.Name()))
+	return domain.NormalizeModelKey(rm.Key)
+}
 
-	n, err := io.Copy(f, body)
-	if err != nil {
-		errlog("写入临时 zip 失败", zap.Error(err))
-		return "", err
-	}
-	if n > maxBytes {
-		errlog("压缩包过大", zap.Int64("bytes", n), zap.Int64("max", maxBytes))
-		return "", fmt.Errorf("zip too large: %d > %d", n, maxBytes)
+func getDirByKey(key string) string {
+	name, version, ok := domain.ParseModelKey(key)
+	if !ok {
+		return name
 	}
-
-	info("写入 zip 完成", zap.Int64("bytes", n))
-	return f.Name(), nil
+	return name + "-win-x86-v" + version
 }
 
 // 安全解压：防止 Zip Slip（.. 路径穿越），并统计写入文件数
@@ -885,6 +872,33 @@ func updateModel(key string, status, progress int, msg string, errMsg string) {
 	}
 }
 
+// updateDownload 下载阶段的进度，整体进度在 20~60 之间按下载百分比推进
+func updateDownload(key string, done, total int64, speed float64) {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	if initTask == nil {
+		return
+	}
+	p, ok := initTask.Models[key]
+	if !ok {
+		p = &modelProgress{Key: key}
+		initTask.Models[key] = p
+	}
+	p.Status = 2
+	p.Downloaded = done
+	p.Total = total
+	p.Speed = speed
+	p.Message = "下载中"
+	if total > 0 {
+		p.Percent = int(done * 100 / total)
+		p.Progress = 20 + p.Percent*40/100
+		p.Message = fmt.Sprintf("下载中 %d%%", p.Percent)
+	}
+	p.Updated = time.Now()
+	initTask.UpdatedAt = time.Now()
+}
+
 func snapshotInit(task *sysInitTask) *sysInitTask {
 	initMu.Lock()
 	defer initMu.Unlock()
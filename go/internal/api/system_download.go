@@ -0,0 +1,311 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"xiacutai-server/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// ==============================
+// 模型下载：HTTP Range 断点续传 + sha256 校验 + 字节级进度
+// ==============================
+
+// maxDownloadBytes 单个模型压缩包大小上限
+const maxDownloadBytes = int64(2 << 30) // 2GB
+
+// tlsInsecure 关闭 HTTPS 证书校验（AIGCPANEL_TLS_INSECURE=1），仅用于自签名证书的内网下载源
+func tlsInsecure() bool {
+	switch strings.ToLower(strings.TrimSpace(utils.GetEnv("AIGCPANEL_TLS_INSECURE", ""))) {
+	case "1", "true", "on", "yes":
+		return true
+	}
+	return false
+}
+
+// newSysHTTPClient timeout 为 0 时不限制整个请求的时长（下载大文件），只限制等待响应头的时间
+func newSysHTTPClient(insecure bool, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			TLSClientConfig:       &tls.Config{InsecureSkipVerify: insecure},
+			MaxIdleConns:          50,
+			MaxIdleConnsPerHost:   10,
+			IdleConnTimeout:       90 * time.Second,
+			ResponseHeaderTimeout: 60 * time.Second,
+		},
+	}
+}
+
+// downloadProgressFunc 下载进度：已下载字节、总字节（未知时为 0）、最近的速度（字节/秒）
+type downloadProgressFunc func(done, total int64, speed float64)
+
+// modelDownloader 下载到 dest + ".part"，中断后保留 .part，下次从已下载的位置续传；
+// 下载完成并校验通过后改名为 dest
+type modelDownloader struct {
+	Client        *http.Client
+	Retries       int           // 连接中断后的续传次数
+	RetryDelay    time.Duration // 续传前等待
+	IdleTimeout   time.Duration // 超过该时间没有收到数据视为连接中断
+	ProgressEvery time.Duration // 进度回调的最小间隔
+	Progress      downloadProgressFunc
+}
+
+func newModelDownloader(progress downloadProgressFunc) *modelDownloader {
+	return &modelDownloader{
+		Client:        sysDownloadClient,
+		Retries:       5,
+		RetryDelay:    3 * time.Second,
+		IdleTimeout:   60 * time.Second,
+		ProgressEvery: 500 * time.Millisecond,
+		Progress:      progress,
+	}
+}
+
+// errChecksum 下载完成但 sha256 不一致，.part 已删除，重试没有意义
+var errChecksum = errors.New("sha256 不一致")
+
+// Download checksum 为空时不校验；dest 已存在且校验通过时直接返回
+func (d *modelDownloader) Download(ctx context.Context, url, dest, checksum string) error {
+	defer step("下载压缩包", zap.String("url", url), zap.String("dest", dest))()
+
+	checksum = strings.TrimSpace(checksum)
+	if _, err := os.Stat(dest); err == nil {
+		if checksum == "" || verifyFileSha256(dest, checksum) == nil {
+			info("压缩包已下载，跳过", zap.String("dest", dest))
+			return nil
+		}
+		_ = os.Remove(dest)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+
+	part := dest + ".part"
+	var err error
+	for attempt := 0; attempt <= d.Retries; attempt++ {
+		if attempt > 0 {
+			warn("下载中断，准备续传", zap.String("url", url), zap.Int("attempt", attempt), zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(d.RetryDelay):
+			}
+		}
+		if err = d.fetch(ctx, url, part); err == nil {
+			break
+		}
+		var status *downloadStatusError
+		if ctx.Err() != nil || (errors.As(err, &status) && !status.retryable()) {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if checksum != "" {
+		if err := verifyFileSha256(part, checksum); err != nil {
+			_ = os.Remove(part)
+			return fmt.Errorf("%w: %s", errChecksum, url)
+		}
+	}
+	return os.Rename(part, dest)
+}
+
+// downloadStatusError 下载地址返回非 2xx
+type downloadStatusError struct {
+	Status int
+	Body   string
+}
+
+func (e *downloadStatusError) Error() string {
+	return fmt.Sprintf("download failed: %d: %s", e.Status, e.Body)
+}
+
+// retryable 429 / 5xx 可以续传重试，其它 4xx 直接失败
+func (e *downloadStatusError) retryable() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= 500
+}
+
+// fetch 从 part 的当前大小开始请求剩余部分并追加写入
+func (d *modelDownloader) fetch(ctx context.Context, url, part string) error {
+	var offset int64
+	if st, err := os.Stat(part); err == nil {
+		offset = st.Size()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	idle := time.AfterFunc(d.IdleTimeout, cancel)
+	defer idle.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	info("下载响应",
+		zap.String("status", resp.Status),
+		zap.Int64("offset", offset),
+		zap.Int64("content_length", resp.ContentLength),
+	)
+
+	total := int64(0)
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			// 服务端返回的区间对不上，丢弃已下载的部分重新下载
+			_ = os.Remove(part)
+			return fmt.Errorf("unexpected content-range %q for offset %d", resp.Header.Get("Content-Range"), offset)
+		}
+		total = size
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// .part 已经是完整文件（或比远端文件大），按 Content-Range 中的总大小判断
+		if _, size, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && size == offset {
+			return nil
+		}
+		_ = os.Remove(part)
+		return fmt.Errorf("range not satisfiable for offset %d", offset)
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		// 不支持 Range，从头下载
+		if offset > 0 {
+			warn("下载地址不支持断点续传，从头下载", zap.String("url", url))
+		}
+		offset = 0
+		if resp.ContentLength > 0 {
+			total = resp.ContentLength
+		}
+		flags |= os.O_TRUNC
+	default:
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 8*1024))
+		errlog("下载失败（非 2xx）", zap.String("status", resp.Status), zap.String("body_preview", string(b)))
+		return &downloadStatusError{Status: resp.StatusCode, Body: string(b)}
+	}
+	if total > maxDownloadBytes {
+		return fmt.Errorf("zip too large: %d > %d", total, maxDownloadBytes)
+	}
+
+	f, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := &progressWriter{
+		w:       f,
+		done:    offset,
+		total:   total,
+		every:   d.ProgressEvery,
+		report:  d.Progress,
+		onWrite: func() { idle.Reset(d.IdleTimeout) },
+	}
+	w.start()
+	_, err = io.Copy(w, io.LimitReader(resp.Body, maxDownloadBytes-offset+1))
+	w.flush()
+	if err != nil {
+		return err
+	}
+	if w.done > maxDownloadBytes {
+		_ = os.Remove(part)
+		return fmt.Errorf("zip too large: %d > %d", w.done, maxDownloadBytes)
+	}
+	if total > 0 && w.done != total {
+		return fmt.Errorf("download incomplete: %d / %d", w.done, total)
+	}
+	info("下载完成", zap.String("part", part), zap.Int64("bytes", w.done))
+	return nil
+}
+
+// parseContentRange 解析 "bytes 100-199/200" 和 "bytes */200"，返回起始位置和总大小
+func parseContentRange(v string) (start, size int64, ok bool) {
+	v = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(v), "bytes"))
+	rng, total, found := strings.Cut(v, "/")
+	if !found {
+		return 0, 0, false
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(total), 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if rng = strings.TrimSpace(rng); rng == "*" {
+		return 0, size, true
+	}
+	first, _, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+// progressWriter 统计写入字节，按间隔回调进度和速度
+type progressWriter struct {
+	w       io.Writer
+	done    int64
+	total   int64
+	every   time.Duration
+	report  downloadProgressFunc
+	onWrite func()
+
+	lastAt   time.Time
+	lastDone int64
+}
+
+func (p *progressWriter) start() {
+	p.lastAt = time.Now()
+	p.lastDone = p.done
+	if p.report != nil {
+		p.report(p.done, p.total, 0)
+	}
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	if p.onWrite != nil {
+		p.onWrite()
+	}
+	if time.Since(p.lastAt) >= p.every {
+		p.flush()
+	}
+	return n, err
+}
+
+func (p *progressWriter) flush() {
+	elapsed := time.Since(p.lastAt).Seconds()
+	speed := 0.0
+	if elapsed > 0 {
+		speed = float64(p.done-p.lastDone) / elapsed
+	}
+	p.lastAt = time.Now()
+	p.lastDone = p.done
+	if p.report != nil {
+		p.report(p.done, p.total, speed)
+	}
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testPayload(t *testing.T, n int) ([]byte, string) {
+	t.Helper()
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	return data, hex.EncodeToString(sum[:])
+}
+
+func testDownloader(client *http.Client) *modelDownloader {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &modelDownloader{
+		Client:      client,
+		Retries:     2,
+		IdleTimeout: 2 * time.Second,
+	}
+}
+
+// serveFile 支持 Range 的下载源，记录每次请求的 Range 头
+func serveFile(t *testing.T, data []byte) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	ranges := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		http.ServeContent(w, r, "model.zip", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &ranges
+}
+
+func assertDownloaded(t *testing.T, dest string, want []byte) {
+	t.Helper()
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("content mismatch: got %d bytes, want %d", len(got), len(want))
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Fatalf("partial file left behind: %v", err)
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	data, sum := testPayload(t, 256*1024)
+	srv, ranges := serveFile(t, data)
+
+	dest := filepath.Join(t.TempDir(), "model.zip")
+	if err := os.WriteFile(dest+".part", data[:100*1024], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var last [2]int64
+	d := testDownloader(nil)
+	d.Progress = func(done, total int64, speed float64) { last = [2]int64{done, total} }
+	if err := d.Download(context.Background(), srv.URL, dest, sum); err != nil {
+		t.Fatal(err)
+	}
+	assertDownloaded(t, dest, data)
+	if len(*ranges) != 1 || (*ranges)[0] != "bytes=102400-" {
+		t.Fatalf("expected one ranged request, got %q", *ranges)
+	}
+	if last != [2]int64{int64(len(data)), int64(len(data))} {
+		t.Fatalf("unexpected final progress %v", last)
+	}
+
+	// 已下载且校验通过的文件不再请求
+	if err := d.Download(context.Background(), srv.URL, dest, sum); err != nil {
+		t.Fatal(err)
+	}
+	if len(*ranges) != 1 {
+		t.Fatalf("expected no new request, got %q", *ranges)
+	}
+}
+
+func TestDownloadInterrupted(t *testing.T) {
+	data, sum := testPayload(t, 128*1024)
+	var mu sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		first := requests == 1
+		mu.Unlock()
+		if first {
+			// 发送一半后断开连接
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			_, _ = w.Write(data[:len(data)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "model.zip", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), "model.zip")
+	if err := testDownloader(nil).Download(context.Background(), srv.URL, dest, sum); err != nil {
+		t.Fatal(err)
+	}
+	assertDownloaded(t, dest, data)
+	if requests != 2 {
+		t.Fatalf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestDownloadWithoutRangeSupport(t *testing.T) {
+	data, sum := testPayload(t, 64*1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), "model.zip")
+	if err := os.WriteFile(dest+".part", []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := testDownloader(nil).Download(context.Background(), srv.URL, dest, sum); err != nil {
+		t.Fatal(err)
+	}
+	assertDownloaded(t, dest, data)
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	data, _ := testPayload(t, 32*1024)
+	srv, _ := serveFile(t, data)
+
+	dest := filepath.Join(t.TempDir(), "model.zip")
+	err := testDownloader(nil).Download(context.Background(), srv.URL, dest, hex.EncodeToString(make([]byte, 32)))
+	if !errors.Is(err, errChecksum) {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+	for _, p := range []string{dest, dest + ".part"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Fatalf("%s should be removed: %v", p, err)
+		}
+	}
+}
+
+func TestDownloadStatusNotRetried(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	err := testDownloader(nil).Download(context.Background(), srv.URL, filepath.Join(t.TempDir(), "model.zip"), "")
+	var status *downloadStatusError
+	if !errors.As(err, &status) || status.Status != http.StatusNotFound {
+		t.Fatalf("expected 404, got %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("expected no retry, got %d requests", requests)
+	}
+}
+
+func TestDownloadIdleTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1024")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	d := testDownloader(nil)
+	d.Retries = 0
+	d.IdleTimeout = 200 * time.Millisecond
+	start := time.Now()
+	if err := d.Download(context.Background(), srv.URL, filepath.Join(t.TempDir(), "model.zip"), ""); err == nil {
+		t.Fatal("expected idle timeout")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("idle timeout not applied: %v", time.Since(start))
+	}
+}
+
+func TestDownloadTLSVerification(t *testing.T) {
+	data, sum := testPayload(t, 1024)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), "model.zip")
+	d := testDownloader(newSysHTTPClient(false, 0))
+	d.Retries = 0
+	if err := d.Download(context.Background(), srv.URL, dest, sum); err == nil {
+		t.Fatal("expected certificate verification error")
+	}
+
+	d.Client = newSysHTTPClient(true, 0)
+	if err := d.Download(context.Background(), srv.URL, dest, sum); err != nil {
+		t.Fatal(err)
+	}
+	assertDownloaded(t, dest, data)
+}
+
+func TestParseContentRange(t *testing.T) {
+	cases := []struct {
+		in          string
+		start, size int64
+		ok          bool
+	}{
+		{"bytes 100-199/200", 100, 200, true},
+		{"bytes */200", 0, 200, true},
+		{"bytes 0-9/*", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, c := range cases {
+		start, size, ok := parseContentRange(c.in)
+		if start != c.start || size != c.size || ok != c.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v", c.in, start, size, ok)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package api
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"xiacutai-server/internal/component/errs"
+	"xiacutai-server/internal/domain"
+	"xiacutai-server/internal/service"
+	"xiacutai-server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ==============================
+// 离线安装：从本地压缩包安装模型（内网机器无法访问 sys_config 的下载地址）
+// ==============================
+
+// manifestName 模型压缩包根目录下的清单文件
+const manifestName = "manifest.json"
+
+// modelManifest 压缩包清单：模型名、版本，以及需要校验的文件（压缩包内路径 -> sha256）
+type modelManifest struct {
+	Name    string            `json:"name"`
+	Version string            `json:"version"`
+	Files   map[string]string `json:"files"`
+}
+
+type installPackageRequest struct {
+	Path   string `json:"path"`   // 本地压缩包路径
+	Sha256 string `json:"sha256"` // 可选，压缩包的 sha256
+}
+
+// POST /init/install
+func SysInstall(c *gin.Context) {
+	defer step("SysInstall 接口处理")()
+
+	var req installPackageRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Path) == "" {
+		Err(c, errs.ParamError)
+		return
+	}
+
+	key, manifest, err := beginInstall(req.Path)
+	if err != nil {
+		Err(c, err)
+		return
+	}
+	go func() {
+		_ = runInstall(key, req.Path, req.Sha256, manifest)
+	}()
+
+	initMu.Lock()
+	snap := snapshotInitLocked(initTask)
+	total := calcTotalProgress(initTask.Models)
+	initMu.Unlock()
+
+	OK(c, gin.H{
+		"running":        true,
+		"key":            key,
+		"progress_info":  snap,
+		"total_progress": total,
+		"log_path":       snap.LogPath,
+	})
+}
+
+// InstallModelPackage 从本地压缩包安装模型并等待完成，进度与 /init 一样写入初始化任务
+func InstallModelPackage(zipPath, checksum string) (string, error) {
+	key, manifest, err := beginInstall(zipPath)
+	if err != nil {
+		return "", err
+	}
+	return key, runInstall(key, zipPath, checksum, manifest)
+}
+
+// beginInstall 读取清单并创建初始化任务；已有任务在执行时返回错误
+func beginInstall(zipPath string) (string, *modelManifest, error) {
+	st, err := os.Stat(zipPath)
+	if err != nil || st.IsDir() {
+		return "", nil, errs.New("模型压缩包不存在: " + zipPath)
+	}
+	manifest, err := readManifest(zipPath)
+	if err != nil {
+		return "", nil, err
+	}
+	key := domain.ModelKey(manifest.Name, manifest.Version)
+	if local, err := service.Model.GetByDB(key); err == nil && local.Status == "5" {
+		return "", nil, errs.New("模型已安装，如需重新安装请先删除: " + key)
+	}
+
+	initMu.Lock()
+	defer initMu.Unlock()
+	if initTask != nil && initTask.Status == initRunning {
+		return "", nil, errs.New("初始化任务执行中，请稍后再试")
+	}
+	initTask = &sysInitTask{
+		Status:    initRunning,
+		StartedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Models: map[string]*modelProgress{
+			key: {Key: key, Message: "排队中", Updated: time.Now()},
+		},
+		LogPath: filepath.Join(utils.LogDir, "runtime.log"),
+	}
+	info("离线安装任务已创建", zap.String("key", key), zap.String("zip", zipPath))
+	return key, manifest, nil
+}
+
+// runInstall 校验 -> 解压 -> 找 config -> ModelAdd -> 安装依赖，进度节点与 runInit 一致
+func runInstall(key, zipPath, checksum string, manifest *modelManifest) error {
+	defer step("离线安装模型", zap.String("key", key), zap.String("zip", zipPath))()
+	defer finishInitTask()
+
+	fail := func(msg string, err error) error {
+		updateModel(key, -1, 100, msg, err.Error())
+		return err
+	}
+
+	if checksum = strings.TrimSpace(checksum); checksum != "" {
+		updateModel(key, 2, 10, "校验压缩包", "")
+		if err := verifyFileSha256(zipPath, checksum); err != nil {
+			return fail("压缩包校验失败", err)
+		}
+	}
+
+	updateModel(key, 2, 20, "校验清单", "")
+	if err := verifyManifest(zipPath, key, manifest); err != nil {
+		return fail("清单校验失败", err)
+	}
+
+	if err := extractAndAddModel(key, zipPath); err != nil {
+		return fail("解压/添加失败", err)
+	}
+	updateModel(key, 3, 60, "解压完成", "")
+
+	if !finishModelInstall(key) {
+		initMu.Lock()
+		defer initMu.Unlock()
+		if p := initTask.Models[key]; p != nil && p.Error != "" {
+			return errs.New(p.Message + ": " + p.Error)
+		}
+		return errs.New("模型安装失败: " + key)
+	}
+	return nil
+}
+
+// readManifest 读取压缩包根目录下的 manifest.json，name 和 version 必填
+func readManifest(zipPath string) (*modelManifest, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, errs.New("无法打开模型压缩包: " + err.Error())
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.Name != manifestName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		var manifest modelManifest
+		if err := json.NewDecoder(io.LimitReader(rc, 4*1024*1024)).Decode(&manifest); err != nil {
+			return nil, errs.New("manifest.json 解析失败: " + err.Error())
+		}
+		if strings.TrimSpace(manifest.Name) == "" || strings.TrimSpace(manifest.Version) == "" {
+			return nil, errs.New("manifest.json 缺少 name 或 version")
+		}
+		return &manifest, nil
+	}
+	return nil, errs.New("模型压缩包缺少 " + manifestName)
+}
+
+// verifyManifest 检查模型目录存在、config.json 与清单的 name/version 一致、清单列出的文件 sha256 一致
+func verifyManifest(zipPath, key string, manifest *modelManifest) error {
+	defer step("校验清单", zap.String("key", key), zap.Int("files", len(manifest.Files)))()
+
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	entries := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		entries[path.Clean(f.Name)] = f
+	}
+
+	dir := getDirByKey(key)
+	if cfg, ok := entries[path.Join(dir, "config.json")]; ok {
+		var config struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		if err := readZipJSON(cfg, &config); err != nil {
+			return fmt.Errorf("config.json 解析失败: %w", err)
+		}
+		if got := domain.ModelKey(config.Name, config.Version); got != key {
+			return fmt.Errorf("config.json 为 %s，与清单 %s 不一致", got, key)
+		}
+	} else if !hasZipDir(r.File, dir) {
+		return fmt.Errorf("压缩包中缺少模型目录 %s", dir)
+	}
+
+	for name, want := range manifest.Files {
+		f, ok := entries[path.Clean(filepath.ToSlash(name))]
+		if !ok {
+			return fmt.Errorf("清单中的文件不存在: %s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		got, err := sha256Hex(rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(got, strings.TrimSpace(want)) {
+			errlog("文件校验失败", zap.String("file", name), zap.String("want", want), zap.String("got", got))
+			return fmt.Errorf("文件校验失败: %s", name)
+		}
+	}
+	return nil
+}
+
+func readZipJSON(f *zip.File, v any) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(io.LimitReader(rc, 4*1024*1024)).Decode(v)
+}
+
+func hasZipDir(files []*zip.File, dir string) bool {
+	prefix := dir + "/"
+	for _, f := range files {
+		if strings.HasPrefix(f.Name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyFileSha256 计算文件 sha256 并与期望值比较（不区分大小写）
+func verifyFileSha256(file, want string) error {
+	defer step("校验 sha256", zap.String("file", file))()
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	got, err := sha256Hex(f)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(got, want) {
+		errlog("sha256 不一致", zap.String("want", want), zap.String("got", got))
+		return fmt.Errorf("sha256 不一致: want %s, got %s", want, got)
+	}
+	return nil
+}
+
+func sha256Hex(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
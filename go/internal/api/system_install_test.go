@@ -0,0 +1,127 @@
+package api
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"xiacutai-server/internal/component/sqllite"
+	"xiacutai-server/internal/service"
+	"xiacutai-server/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "api-test-*")
+	if err != nil {
+		panic(err)
+	}
+	_ = os.Setenv("DataDir", dir)
+	utils.InitDirs()
+	sqllite.Init()
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func sha256String(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeZip 生成压缩包，files 为压缩包内路径 -> 内容
+func writeZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "model.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	return path
+}
+
+func TestInstallModelPackage(t *testing.T) {
+	root := t.TempDir()
+	oldRoot, oldDeps := modelsRootDir, installDeps
+	t.Cleanup(func() { modelsRootDir, installDeps = oldRoot, oldDeps })
+	modelsRootDir = root
+	var depsKeys []string
+	installDeps = func(key string) error {
+		depsKeys = append(depsKeys, key)
+		return nil
+	}
+
+	config := `{"name":"offline","version":"1.0","title":"离线模型","entry":"__EasyServer__",` +
+		`"easyServer":{"entry":"${ROOT}/run.sh"},"functions":["soundTts"],"settings":[]}`
+	manifest, _ := json.Marshal(map[string]any{
+		"name":    "offline",
+		"version": "1.0",
+		"files":   map[string]string{"offline-win-x86-v1.0/config.json": sha256String(config)},
+	})
+	zipPath := writeZip(t, map[string]string{
+		"manifest.json":                      string(manifest),
+		"offline-win-x86-v1.0/config.json":   config,
+		"offline-win-x86-v1.0/run.sh":        "#!/bin/sh\n",
+		"offline-win-x86-v1.0/weights/a.bin": "weights",
+	})
+	raw, err := os.ReadFile(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum := sha256String(string(raw))
+
+	key, err := InstallModelPackage(zipPath, strings.ToUpper(checksum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = service.Model.ModelDelete("offline", "1.0") })
+	if key != "offline|1.0" {
+		t.Fatalf("unexpected key %q", key)
+	}
+	if len(depsKeys) != 1 || depsKeys[0] != key {
+		t.Fatalf("dependencies should be installed once, got %v", depsKeys)
+	}
+
+	// 解压到模型目录并注册，状态为就绪
+	modelDir := filepath.Join(root, "offline-win-x86-v1.0")
+	if _, err := os.Stat(filepath.Join(modelDir, "weights", "a.bin")); err != nil {
+		t.Fatalf("model files not extracted: %v", err)
+	}
+	local, err := service.Model.GetByDB(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if local.Status != "5" || filepath.Clean(local.LocalPath) != modelDir {
+		t.Fatalf("unexpected registry row: status %s path %s", local.Status, local.LocalPath)
+	}
+
+	initMu.Lock()
+	status, progress := initTask.Status, initTask.Models[key]
+	initMu.Unlock()
+	if status != initDone || progress == nil || progress.Status != 5 || progress.Progress != 100 || progress.Error != "" {
+		t.Fatalf("unexpected init task: %s %+v", status, progress)
+	}
+
+	// 已安装的模型不能重复安装
+	if _, err := InstallModelPackage(zipPath, ""); err == nil || !strings.Contains(err.Error(), "已安装") {
+		t.Fatalf("expected already installed error, got %v", err)
+	}
+	if len(depsKeys) != 1 {
+		t.Fatalf("second install should not run, got %v", depsKeys)
+	}
+}
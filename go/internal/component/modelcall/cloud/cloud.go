@@ -0,0 +1,227 @@
+// Package cloud 云端/远程模型的适配层
+// 远程模型不需要启动本地进程，按 provider 调用对应的 HTTP 接口，结果转换成与 easyserver 相同的 TaskResult
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"xiacutai-server/internal/component/errs"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+)
+
+// ProviderOpenAI OpenAI 兼容接口（/audio/speech、/audio/transcriptions）
+const ProviderOpenAI = "openai"
+
+// defaultTimeout 单次调用默认最长执行时间
+const defaultTimeout = 300 * time.Second
+
+// Config 模型 config.json 中的 cloud 配置
+type Config struct {
+	Provider       string            `json:"provider"`       // 适配器，默认 openai
+	Endpoint       string            `json:"endpoint"`       // 接口地址，如 https://api.openai.com/v1
+	APIKey         string            `json:"apiKey"`         // 凭证，以 Bearer 方式发送
+	APIKeyEnv      string            `json:"apiKeyEnv"`      // 从环境变量读取凭证，apiKey 为空时使用
+	Model          string            `json:"model"`          // 远程模型名
+	Voice          string            `json:"voice"`          // 语音合成默认音色
+	ResponseFormat string            `json:"responseFormat"` // 语音合成输出格式，默认 wav
+	Timeout        int               `json:"timeout"`        // 单次调用最长执行时间（秒），0 使用默认值
+	Headers        map[string]string `json:"headers"`        // 额外的请求头
+}
+
+// Key 实际使用的凭证
+func (c Config) Key() string {
+	if c.APIKey != "" {
+		return c.APIKey
+	}
+	if c.APIKeyEnv != "" {
+		return os.Getenv(c.APIKeyEnv)
+	}
+	return ""
+}
+
+// Validate 检查必填项
+func (c Config) Validate() error {
+	if strings.TrimSpace(c.Endpoint) == "" {
+		return errs.New("endpoint 不能为空")
+	}
+	if !strings.HasPrefix(c.Endpoint, "http://") && !strings.HasPrefix(c.Endpoint, "https://") {
+		return errs.New("endpoint 必须是 http(s) 地址")
+	}
+	if strings.TrimSpace(c.Model) == "" {
+		return errs.New("model 不能为空")
+	}
+	if _, ok := lookupProvider(c.providerName()); !ok {
+		return errs.New("不支持的 provider: " + c.Provider)
+	}
+	return nil
+}
+
+func (c Config) providerName() string {
+	if c.Provider == "" {
+		return ProviderOpenAI
+	}
+	return c.Provider
+}
+
+// Provider 一种远程接口的适配器，返回值与本地模型结果的 data 部分一致（url / records）
+type Provider interface {
+	SoundTts(ctx context.Context, data easyserver.ServerFunctionDataType) (map[string]interface{}, error)
+	Asr(ctx context.Context, data easyserver.ServerFunctionDataType) (map[string]interface{}, error)
+}
+
+// ProviderFactory 根据配置创建适配器，outputDir 为生成文件的保存目录
+type ProviderFactory func(cfg Config, client *http.Client, outputDir string) Provider
+
+var providers = struct {
+	sync.RWMutex
+	factories map[string]ProviderFactory
+}{
+	factories: map[string]ProviderFactory{
+		ProviderOpenAI: newOpenAI,
+	},
+}
+
+// RegisterProvider 注册适配器，同名时后者覆盖前者
+func RegisterProvider(name string, factory ProviderFactory) {
+	providers.Lock()
+	defer providers.Unlock()
+	providers.factories[name] = factory
+}
+
+func lookupProvider(name string) (ProviderFactory, bool) {
+	providers.RLock()
+	defer providers.RUnlock()
+	factory, ok := providers.factories[name]
+	return factory, ok
+}
+
+// Server 一个远程模型，方法与 easyserver.EasyServer 对应，便于调度器同样地调用和取消
+type Server struct {
+	Config          Config
+	TimeoutOverride time.Duration // 任务指定的最长执行时间，0 使用配置
+
+	provider Provider
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// NewServer 创建远程模型，client 为 nil 时使用 http.DefaultClient
+func NewServer(cfg Config, client *http.Client, outputDir string) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	factory, _ := lookupProvider(cfg.providerName())
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		Config:   cfg,
+		provider: factory(cfg, client, outputDir),
+		ctx:      ctx,
+		cancel:   cancel,
+	}, nil
+}
+
+// Cancel 取消正在进行的调用
+func (s *Server) Cancel() error {
+	s.cancel()
+	return nil
+}
+
+func (s *Server) timeout() time.Duration {
+	if s.TimeoutOverride > 0 {
+		return s.TimeoutOverride
+	}
+	if s.Config.Timeout > 0 {
+		return time.Duration(s.Config.Timeout) * time.Second
+	}
+	return defaultTimeout
+}
+
+// SoundTts 语音合成
+func (s *Server) SoundTts(data easyserver.ServerFunctionDataType) (*easyserver.TaskResult, error) {
+	return s.call(data, s.provider.SoundTts)
+}
+
+// Asr 语音识别
+func (s *Server) Asr(data easyserver.ServerFunctionDataType) (*easyserver.TaskResult, error) {
+	return s.call(data, s.provider.Asr)
+}
+
+// Call 按功能名调用
+func (s *Server) Call(function easyserver.ServerFunction, data easyserver.ServerFunctionDataType) (*easyserver.TaskResult, error) {
+	switch function {
+	case easyserver.FunctionSoundTts:
+		return s.SoundTts(data)
+	case easyserver.FunctionAsr:
+		return s.Asr(data)
+	}
+	return nil, errs.New("云端模型不支持的功能: " + string(function))
+}
+
+// Supported 云端模型支持的功能
+func Supported(function string) bool {
+	return function == string(easyserver.FunctionSoundTts) || function == string(easyserver.FunctionAsr)
+}
+
+func (s *Server) call(data easyserver.ServerFunctionDataType, fn func(context.Context, easyserver.ServerFunctionDataType) (map[string]interface{}, error)) (*easyserver.TaskResult, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, s.timeout())
+	defer cancel()
+
+	start := time.Now().Unix()
+	out, err := fn(ctx, data)
+	if err != nil {
+		return nil, classify(ctx, s.ctx, err)
+	}
+	return &easyserver.TaskResult{
+		Code: 0,
+		Msg:  "ok",
+		Data: map[string]interface{}{
+			"type":  "success",
+			"start": start,
+			"end":   time.Now().Unix(),
+			"data":  out,
+		},
+	}, nil
+}
+
+// statusError 远程接口返回的非 2xx 响应
+type statusError struct {
+	Status int
+	Msg    string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("云端模型调用失败(%d): %s", e.Status, e.Msg)
+}
+
+// classify 转换成 easyserver.CallError，调度器按类别决定是否重试：
+// 超时、取消与本地模型一致；网络错误、429 和 5xx 视为服务暂不可用可以重试；其余 4xx 为参数或凭证错误
+func classify(ctx, parent context.Context, err error) error {
+	if parent.Err() != nil {
+		return &easyserver.CallError{Kind: easyserver.ErrorKindCancelled, Msg: "任务已取消"}
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &easyserver.CallError{Kind: easyserver.ErrorKindTimeout, Msg: "云端模型调用超时"}
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		if se.Status == http.StatusTooManyRequests || se.Status >= 500 {
+			return &easyserver.CallError{Kind: easyserver.ErrorKindProcess, Msg: se.Error()}
+		}
+		return errs.New(se.Error())
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return &easyserver.CallError{Kind: easyserver.ErrorKindProcess, Msg: "云端模型连接失败: " + err.Error()}
+	}
+	return err
+}
@@ -0,0 +1,186 @@
+package cloud_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"xiacutai-server/internal/component/modelcall/cloud"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+)
+
+// standIn 本地的 OpenAI 兼容接口替身
+func standIn(t *testing.T, handler http.HandlerFunc) cloud.Config {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return cloud.Config{Endpoint: srv.URL + "/v1", APIKey: "sk-test", Model: "tts-1", Voice: "nova"}
+}
+
+func newServer(t *testing.T, cfg cloud.Config) *cloud.Server {
+	t.Helper()
+	server, err := cloud.NewServer(cfg, nil, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func resultData(t *testing.T, result *easyserver.TaskResult) map[string]interface{} {
+	t.Helper()
+	data := result.Data.(map[string]interface{})
+	if data["type"] != "success" {
+		t.Fatalf("unexpected result type: %v", data["type"])
+	}
+	return data["data"].(map[string]interface{})
+}
+
+func TestSoundTts(t *testing.T) {
+	audio := []byte("RIFF----WAVEfmt fake audio")
+	cfg := standIn(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/speech" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("unexpected authorization %q", got)
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] != "tts-1" || body["input"] != "你好" || body["voice"] != "echo" || body["response_format"] != "wav" || body["speed"] != 1.5 {
+			t.Errorf("unexpected body %v", body)
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		_, _ = w.Write(audio)
+	})
+
+	result, err := newServer(t, cfg).SoundTts(easyserver.ServerFunctionDataType{
+		ID:    "task-1",
+		Text:  "你好",
+		Param: map[string]interface{}{"voice": "echo", "speed": 1.5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, _ := resultData(t, result)["url"].(string)
+	if filepath.Ext(url) != ".wav" {
+		t.Fatalf("unexpected url %q", url)
+	}
+	got, err := os.ReadFile(url)
+	if err != nil || string(got) != string(audio) {
+		t.Fatalf("audio not saved: %v", err)
+	}
+}
+
+func TestAsr(t *testing.T) {
+	cfg := standIn(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("file missing: %v", err)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		if header.Filename != "in.wav" || string(content) != "audio" {
+			t.Errorf("unexpected file %s %q", header.Filename, content)
+		}
+		if r.FormValue("model") != "whisper-1" || r.FormValue("response_format") != "verbose_json" {
+			t.Errorf("unexpected form %v", r.MultipartForm.Value)
+		}
+		_, _ = w.Write([]byte(`{"text":"你好 世界","duration":2.0,"segments":[{"start":0,"end":0.8,"text":" 你好"},{"start":1.0,"end":1.85,"text":"世界"}]}`))
+	})
+	cfg.Model = "whisper-1"
+
+	audio := filepath.Join(t.TempDir(), "in.wav")
+	if err := os.WriteFile(audio, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := newServer(t, cfg).Call(easyserver.FunctionAsr, easyserver.ServerFunctionDataType{ID: "task-2", Audio: audio})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := resultData(t, result)["records"].([]interface{})
+	segments := records[0].(map[string]interface{})["segments"].([]interface{})
+	if len(segments) != 2 {
+		t.Fatalf("expected 2 segments, got %v", segments)
+	}
+	second := segments[1].(map[string]interface{})
+	if second["start"] != int64(1000) || second["end"] != int64(1850) || second["text"] != "世界" {
+		t.Fatalf("unexpected segment %v", second)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		kind   string
+	}{
+		{"unauthorized", http.StatusUnauthorized, ""},
+		{"rate limited", http.StatusTooManyRequests, easyserver.ErrorKindProcess},
+		{"unavailable", http.StatusServiceUnavailable, easyserver.ErrorKindProcess},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := standIn(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(c.status)
+				_, _ = w.Write([]byte(`{"error":{"message":"stand-in failure"}}`))
+			})
+			_, err := newServer(t, cfg).SoundTts(easyserver.ServerFunctionDataType{ID: "e", Text: "hi"})
+			if err == nil || !strings.Contains(err.Error(), "stand-in failure") {
+				t.Fatalf("expected remote message, got %v", err)
+			}
+			if kind := easyserver.ErrorKind(err); kind != c.kind {
+				t.Fatalf("expected kind %q, got %q", c.kind, kind)
+			}
+		})
+	}
+}
+
+func TestTimeoutAndCancel(t *testing.T) {
+	release := make(chan struct{})
+	cfg := standIn(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	defer close(release)
+
+	server := newServer(t, cfg)
+	server.TimeoutOverride = 200 * time.Millisecond
+	_, err := server.SoundTts(easyserver.ServerFunctionDataType{ID: "t", Text: "hi"})
+	if kind := easyserver.ErrorKind(err); kind != easyserver.ErrorKindTimeout {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+
+	server = newServer(t, cfg)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = server.Cancel()
+	}()
+	_, err = server.SoundTts(easyserver.ServerFunctionDataType{ID: "c", Text: "hi"})
+	if kind := easyserver.ErrorKind(err); kind != easyserver.ErrorKindCancelled {
+		t.Fatalf("expected cancelled, got %v", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if _, err := cloud.NewServer(cloud.Config{Endpoint: "ftp://x", Model: "m"}, nil, ""); err == nil {
+		t.Fatal("expected invalid endpoint error")
+	}
+	if _, err := cloud.NewServer(cloud.Config{Endpoint: "http://x", Model: "m", Provider: "unknown"}, nil, ""); err == nil {
+		t.Fatal("expected unknown provider error")
+	}
+	_ = os.Setenv("CLOUD_TEST_KEY", "sk-env")
+	defer os.Unsetenv("CLOUD_TEST_KEY")
+	if key := (cloud.Config{APIKeyEnv: "CLOUD_TEST_KEY"}).Key(); key != "sk-env" {
+		t.Fatalf("expected key from env, got %q", key)
+	}
+}
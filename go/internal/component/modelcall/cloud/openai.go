@@ -0,0 +1,225 @@
+package cloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"xiacutai-server/internal/component/errs"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+)
+
+// openAI OpenAI 兼容接口，param 中的 model / voice / speed / format / language / prompt 覆盖配置
+type openAI struct {
+	cfg       Config
+	client    *http.Client
+	outputDir string
+}
+
+func newOpenAI(cfg Config, client *http.Client, outputDir string) Provider {
+	return &openAI{cfg: cfg, client: client, outputDir: outputDir}
+}
+
+func (p *openAI) url(path string) string {
+	return strings.TrimRight(p.cfg.Endpoint, "/") + path
+}
+
+func (p *openAI) newRequest(ctx context.Context, path string, body io.Reader, contentType string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url(path), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if key := p.cfg.Key(); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	for k, v := range p.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+func (p *openAI) do(req *http.Request) ([]byte, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &statusError{Status: resp.StatusCode, Msg: errorMessage(body)}
+	}
+	return body, nil
+}
+
+// errorMessage 取 {"error": {"message": ...}}，取不到时返回原文
+func errorMessage(body []byte) string {
+	var out struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &out) == nil && len(out.Error) > 0 {
+		var detail struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(out.Error, &detail) == nil && detail.Message != "" {
+			return detail.Message
+		}
+		var msg string
+		if json.Unmarshal(out.Error, &msg) == nil && msg != "" {
+			return msg
+		}
+	}
+	msg := strings.TrimSpace(string(body))
+	if len(msg) > 200 {
+		msg = msg[:200]
+	}
+	return msg
+}
+
+// SoundTts POST /audio/speech，返回的音频保存到 outputDir
+func (p *openAI) SoundTts(ctx context.Context, data easyserver.ServerFunctionDataType) (map[string]interface{}, error) {
+	if strings.TrimSpace(data.Text) == "" {
+		return nil, errs.New("text 不能为空")
+	}
+	format := firstNonEmpty(param(data, "format"), p.cfg.ResponseFormat, "wav")
+	payload := map[string]interface{}{
+		"model":           firstNonEmpty(param(data, "model"), p.cfg.Model),
+		"input":           data.Text,
+		"voice":           firstNonEmpty(param(data, "voice"), p.cfg.Voice, "alloy"),
+		"response_format": format,
+	}
+	if speed, ok := data.Param["speed"]; ok {
+		payload["speed"] = speed
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := p.newRequest(ctx, "/audio/speech", bytes.NewReader(raw), "application/json")
+	if err != nil {
+		return nil, err
+	}
+	audio, err := p.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(audio) == 0 {
+		return nil, errs.New("云端模型返回的音频为空")
+	}
+
+	if err := os.MkdirAll(p.outputDir, 0755); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s_%d.%s", firstNonEmpty(data.ID, "tts"), time.Now().UnixNano(), format)
+	path := filepath.Join(p.outputDir, name)
+	if err := os.WriteFile(path, audio, 0644); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"url": path}, nil
+}
+
+// Asr POST /audio/transcriptions（verbose_json），分段时间转换成毫秒的 records
+func (p *openAI) Asr(ctx context.Context, data easyserver.ServerFunctionDataType) (map[string]interface{}, error) {
+	file, err := os.Open(data.Audio)
+	if err != nil {
+		return nil, errs.New(fmt.Sprintf("读取音频失败: %v", err))
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(data.Audio))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, err
+	}
+	fields := map[string]string{
+		"model":           firstNonEmpty(param(data, "model"), p.cfg.Model),
+		"response_format": "verbose_json",
+		"language":        param(data, "language"),
+		"prompt":          param(data, "prompt"),
+	}
+	for k, v := range fields {
+		if v == "" {
+			continue
+		}
+		if err := form.WriteField(k, v); err != nil {
+			return nil, err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := p.newRequest(ctx, "/audio/transcriptions", &body, form.FormDataContentType())
+	if err != nil {
+		return nil, err
+	}
+	raw, err := p.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Text     string  `json:"text"`
+		Duration float64 `json:"duration"`
+		Segments []struct {
+			Start float64 `json:"start"`
+			End   float64 `json:"end"`
+			Text  string  `json:"text"`
+		} `json:"segments"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, errs.New("云端模型返回格式错误: " + errorMessage(raw))
+	}
+
+	segments := make([]interface{}, 0, len(out.Segments))
+	for _, s := range out.Segments {
+		segments = append(segments, map[string]interface{}{
+			"start": int64(s.Start * 1000),
+			"end":   int64(s.End * 1000),
+			"text":  strings.TrimSpace(s.Text),
+		})
+	}
+	// 不返回分段的接口（response_format 只支持 json）整段作为一条
+	if len(segments) == 0 && strings.TrimSpace(out.Text) != "" {
+		segments = append(segments, map[string]interface{}{
+			"start": int64(0),
+			"end":   int64(out.Duration * 1000),
+			"text":  strings.TrimSpace(out.Text),
+		})
+	}
+	return map[string]interface{}{
+		"records": []interface{}{
+			map[string]interface{}{"text": strings.TrimSpace(out.Text), "segments": segments},
+		},
+	}, nil
+}
+
+func param(data easyserver.ServerFunctionDataType, name string) string {
+	if v, ok := data.Param[name]; ok && v != nil {
+		return strings.TrimSpace(fmt.Sprint(v))
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,412 @@
+package modelcall
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/utils"
+)
+
+// 检查结果级别
+const (
+	CheckOK    = "ok"
+	CheckWarn  = "warn"
+	CheckError = "error"
+)
+
+// CheckItem 一项检查结果
+type CheckItem struct {
+	Level string `json:"level"`
+	Name  string `json:"name"`
+	Msg   string `json:"msg"`
+}
+
+// CheckReport 模型包的检查报告
+type CheckReport struct {
+	Dir   string      `json:"dir"`
+	Items []CheckItem `json:"items"`
+}
+
+// OK 没有 error 级别的检查项
+func (r *CheckReport) OK() bool {
+	for _, item := range r.Items {
+		if item.Level == CheckError {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *CheckReport) add(level, name, format string, args ...interface{}) {
+	r.Items = append(r.Items, CheckItem{Level: level, Name: name, Msg: fmt.Sprintf(format, args...)})
+}
+
+// CheckOptions 检查选项
+type CheckOptions struct {
+	Run         bool          // 实际调用模型，检查结果输出
+	Functions   []string      // 要调用的功能，为空时调用 config.json 中声明的全部功能
+	Timeout     time.Duration // 单次调用超时，默认 10 分钟
+	SampleAudio string        // 调用时使用的音频，为空时生成一段静音
+	SampleVideo string        // videoGen 使用的视频
+	SampleImage string        // imageEdit / imageUpscale 使用的图片，为空时生成一张纯色图片
+}
+
+// conformanceChecks 各功能结果的检查方法
+var conformanceChecks = map[easyserver.ServerFunction]func(data map[string]interface{}) error{
+	easyserver.FunctionSoundTts:     checkAudioOutput,
+	easyserver.FunctionSoundClone:   checkAudioOutput,
+	easyserver.FunctionVideoGen:     checkFileOutput,
+	easyserver.FunctionAsr:          checkAsrOutput,
+	easyserver.FunctionImageGen:     checkImageOutput,
+	easyserver.FunctionImageEdit:    checkImageOutput,
+	easyserver.FunctionImageUpscale: checkImageOutput,
+}
+
+var rePlaceholder = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// CheckModel 检查模型包是否符合 EasyServer 协议：config.json、入口和占位符，Run 时实际调用并检查结果
+func CheckModel(dir string, opts CheckOptions) *CheckReport {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	report := &CheckReport{Dir: dir}
+	configPath := filepath.Join(dir, "config.json")
+
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		report.add(CheckError, "config.json", "读取失败: %v", err)
+		return report
+	}
+	rawConfig := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &rawConfig); err != nil {
+		report.add(CheckError, "config.json", "不是有效的 JSON: %v", err)
+		return report
+	}
+	config, err := LoadConfigFromJSON(configPath)
+	if err != nil {
+		report.add(CheckError, "config.json", "%v", err)
+		return report
+	}
+	report.add(CheckOK, "config.json", "%s %s", config.Name, config.Version)
+
+	checkFields(report, config)
+	checkEasyServer(report, dir, config)
+	checkFunctions(report, config, rawConfig)
+	if !report.OK() || !opts.Run {
+		return report
+	}
+	runConformance(report, dir, config, opts)
+	return report
+}
+
+func checkFields(report *CheckReport, config *easyserver.ServerConfig) {
+	for _, field := range [][2]string{{"name", config.Name}, {"version", config.Version}, {"title", config.Title}} {
+		if strings.TrimSpace(field[1]) == "" {
+			report.add(CheckError, field[0], "不能为空")
+		}
+	}
+	if config.Entry != "__EasyServer__" {
+		report.add(CheckWarn, "entry", "应为 __EasyServer__，当前为 %q", config.Entry)
+	}
+}
+
+func checkEasyServer(report *CheckReport, dir string, config *easyserver.ServerConfig) {
+	es := config.EasyServer
+	if es == nil || es.Entry == "" {
+		report.add(CheckError, "easyServer.entry", "不能为空（entry 为 launcher 时使用 launcher.entry）")
+		return
+	}
+
+	// 占位符：入口和参数只能使用 ${CONFIG} ${ROOT} ${PORT}，环境变量中还可以引用其它变量
+	allowed := map[string]bool{"CONFIG": true, "ROOT": true}
+	if es.Transport == easyserver.TransportHTTP {
+		allowed["PORT"] = true
+	}
+	usesConfig, usesPort := false, false
+	for _, arg := range append([]string{es.Entry}, es.EntryArgs...) {
+		for _, m := range rePlaceholder.FindAllStringSubmatch(arg, -1) {
+			if !allowed[m[1]] {
+				report.add(CheckError, "easyServer.entryArgs", "不支持的占位符 ${%s}", m[1])
+			}
+			usesConfig = usesConfig || m[1] == "CONFIG"
+			usesPort = usesPort || m[1] == "PORT"
+		}
+	}
+	envs := append([]string{}, es.Envs...)
+	for _, items := range es.PlatformEnvs {
+		envs = append(envs, items...)
+	}
+	for _, env := range envs {
+		if !strings.Contains(env, "=") {
+			report.add(CheckError, "easyServer.envs", "%q 不是 KEY=VALUE 格式", env)
+			continue
+		}
+		usesConfig = usesConfig || strings.Contains(env, "${CONFIG}")
+		usesPort = usesPort || strings.Contains(env, "${PORT}")
+	}
+	if !usesConfig {
+		report.add(CheckError, "easyServer.entryArgs", "没有使用 ${CONFIG}，模型无法读取调用配置")
+	} else {
+		report.add(CheckOK, "placeholders", "入口参数和环境变量的占位符有效")
+	}
+
+	switch es.Transport {
+	case "", easyserver.TransportStdout:
+	case easyserver.TransportHTTP:
+		if !usesPort {
+			report.add(CheckWarn, "easyServer.transport", "http 协议没有使用 ${PORT}，模型需要读取 AIGCPANEL_SERVER_PORT 或配置中的 port")
+		}
+	default:
+		report.add(CheckError, "easyServer.transport", "不支持 %q，只能是 stdout 或 http", es.Transport)
+	}
+	if es.Timeout < 0 || es.IdleTimeout < 0 || es.IdleShutdown < 0 {
+		report.add(CheckError, "easyServer.timeout", "timeout / idleTimeout / idleShutdown 不能为负数")
+	}
+
+	checkEntry(report, dir, es.Entry)
+}
+
+// checkEntry 入口是路径时必须存在，只写命令名时在虚拟环境和 PATH 中查找
+func checkEntry(report *CheckReport, dir, entry string) {
+	path, err := easyserver.ResolveEntry(dir, entry)
+	if err != nil {
+		report.add(CheckError, "easyServer.entry", "%v", err)
+		return
+	}
+	if rel, err := filepath.Rel(dir, path); err != nil || strings.HasPrefix(rel, "..") {
+		report.add(CheckWarn, "easyServer.entry", "使用系统中的 %s，模型包没有自带", path)
+		return
+	}
+	report.add(CheckOK, "easyServer.entry", "%s", path)
+}
+
+func checkFunctions(report *CheckReport, config *easyserver.ServerConfig, rawConfig map[string]interface{}) {
+	if len(config.Functions) == 0 {
+		report.add(CheckError, "functions", "没有声明任何功能")
+		return
+	}
+	declared := map[string]bool{}
+	for _, fn := range config.Functions {
+		declared[string(fn)] = true
+		if !easyserver.Supported(fn) {
+			report.add(CheckWarn, "functions", "未知的功能 %s", fn)
+		}
+	}
+	if config.EasyServer != nil {
+		for fn := range config.EasyServer.Functions {
+			if !declared[fn] {
+				report.add(CheckWarn, "easyServer.functions", "%s 没有在 functions 中声明", fn)
+			}
+		}
+	}
+	if _, ok := rawConfig["settings"].([]interface{}); rawConfig["settings"] != nil && !ok {
+		report.add(CheckError, "settings", "必须是数组")
+	}
+}
+
+// runConformance 按声明的功能逐个调用模型
+func runConformance(report *CheckReport, dir string, config *easyserver.ServerConfig, opts CheckOptions) {
+	workDir, err := os.MkdirTemp("", "aigcpanel-check-*")
+	if err != nil {
+		report.add(CheckError, "run", "创建临时目录失败: %v", err)
+		return
+	}
+	defer os.RemoveAll(workDir)
+	if utils.JsonDir == "" {
+		utils.JsonDir = workDir
+	}
+	audio := opts.SampleAudio
+	if audio == "" {
+		audio = filepath.Join(workDir, "sample.wav")
+		if err := os.WriteFile(audio, silenceWav(2*time.Second), 0644); err != nil {
+			report.add(CheckError, "run", "生成测试音频失败: %v", err)
+			return
+		}
+	}
+	sampleImage := opts.SampleImage
+	if sampleImage == "" {
+		sampleImage = filepath.Join(workDir, "sample.png")
+		if err := os.WriteFile(sampleImage, samplePng(), 0644); err != nil {
+			report.add(CheckError, "run", "生成测试图片失败: %v", err)
+			return
+		}
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = easyserver.DefaultCallTimeout
+	}
+	if config.EasyServer.Persistent() {
+		defer easyserver.StopAllResidents()
+	}
+
+	functions := opts.Functions
+	if len(functions) == 0 {
+		for _, fn := range config.Functions {
+			functions = append(functions, string(fn))
+		}
+	}
+	for i, fn := range functions {
+		check, ok := conformanceChecks[easyserver.ServerFunction(fn)]
+		if !ok {
+			report.add(CheckWarn, fn, "不支持调用检查")
+			continue
+		}
+		if fn == string(easyserver.FunctionVideoGen) && opts.SampleVideo == "" {
+			report.add(CheckWarn, fn, "没有指定测试视频，跳过")
+			continue
+		}
+
+		server := easyserver.NewEasyServer(*config)
+		server.ServerInfo = &easyserver.ServerInfo{LocalPath: dir, Name: config.Name, Version: config.Version, Setting: map[string]interface{}{}, Config: *config}
+		server.TimeoutOverride = easyserver.CallTimeout{Total: timeout}
+		progress := 0
+		server.OnProgress = func(easyserver.Progress) { progress++ }
+		_ = server.Start()
+
+		data := easyserver.ServerFunctionDataType{
+			ID:          fmt.Sprintf("check-%d-%d", time.Now().Unix(), i),
+			Param:       map[string]interface{}{},
+			Result:      map[string]interface{}{},
+			Text:        "你好，这是一次模型接入测试。",
+			Audio:       audio,
+			PromptAudio: audio,
+			PromptText:  "你好，这是一次模型接入测试。",
+			Video:       opts.SampleVideo,
+			Image:       sampleImage,
+		}
+		start := time.Now()
+		result, err := server.Call(easyserver.ServerFunction(fn), data)
+		_ = server.Stop()
+		if err != nil {
+			report.add(CheckError, fn, "调用失败: %v", err)
+			continue
+		}
+		payload, _ := result.Data.(map[string]interface{})
+		output, _ := payload["data"].(map[string]interface{})
+		if err := check(output); err != nil {
+			report.add(CheckError, fn, "结果不符合要求: %v", err)
+			continue
+		}
+		report.add(CheckOK, fn, "耗时 %s，进度 %d 条", time.Since(start).Round(time.Millisecond), progress)
+	}
+}
+
+func checkFileOutput(data map[string]interface{}) error {
+	url, _ := data["url"].(string)
+	if url == "" {
+		return fmt.Errorf("缺少 url")
+	}
+	info, err := os.Stat(url)
+	if err != nil {
+		return fmt.Errorf("结果文件不存在: %s", url)
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("结果文件为空: %s", url)
+	}
+	return nil
+}
+
+func checkAudioOutput(data map[string]interface{}) error {
+	if err := checkFileOutput(data); err != nil {
+		return err
+	}
+	url := data["url"].(string)
+	f, err := os.Open(url)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	header := make([]byte, 12)
+	if _, err := f.Read(header); err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(url), ".wav") && (!bytes.HasPrefix(header, []byte("RIFF")) || string(header[8:12]) != "WAVE") {
+		return fmt.Errorf("不是有效的 wav 文件: %s", url)
+	}
+	return nil
+}
+
+// checkImageOutput url / urls 中的每个文件都必须是能识别的图片
+func checkImageOutput(data map[string]interface{}) error {
+	urls, _ := data["urls"].([]interface{})
+	if len(urls) == 0 {
+		urls = []interface{}{data["url"]}
+	}
+	for _, item := range urls {
+		if err := checkFileOutput(map[string]interface{}{"url": item}); err != nil {
+			return err
+		}
+		url := item.(string)
+		f, err := os.Open(url)
+		if err != nil {
+			return err
+		}
+		_, _, err = image.DecodeConfig(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("不是有效的图片: %s", url)
+		}
+	}
+	return nil
+}
+
+// samplePng 一张 64x64 的灰色图片
+func samplePng() []byte {
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func checkAsrOutput(data map[string]interface{}) error {
+	records, ok := data["records"].([]interface{})
+	if !ok || len(records) == 0 {
+		return fmt.Errorf("records 为空")
+	}
+	for _, record := range records {
+		m, _ := record.(map[string]interface{})
+		segments, _ := m["segments"].([]interface{})
+		if len(segments) == 0 {
+			return fmt.Errorf("record 缺少 segments")
+		}
+		for _, segment := range segments {
+			s, _ := segment.(map[string]interface{})
+			start, _ := s["start"].(float64)
+			end, _ := s["end"].(float64)
+			if _, ok := s["text"].(string); !ok || end <= start {
+				return fmt.Errorf("segment 需要 start < end 和 text: %v", segment)
+			}
+		}
+	}
+	return nil
+}
+
+// silenceWav 16kHz 单声道 16bit 静音，作为调用检查的输入音频
+func silenceWav(d time.Duration) []byte {
+	const rate = 16000
+	dataSize := int(d.Seconds()*rate) * 2
+	buf := bytes.NewBuffer(make([]byte, 0, 44+dataSize))
+	buf.WriteString("RIFF")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVEfmt ")
+	for _, v := range []interface{}{uint32(16), uint16(1), uint16(1), uint32(rate), uint32(rate * 2), uint16(2), uint16(16)} {
+		_ = binary.Write(buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("data")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(dataSize))
+	buf.Write(make([]byte, dataSize))
+	return buf.Bytes()
+}
@@ -0,0 +1,56 @@
+package modelcall
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"xiacutai-server/internal/component/modelcall/modeltest"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	modeltest.Cleanup()
+	os.Exit(code)
+}
+
+func TestCheckModelFakeModel(t *testing.T) {
+	dir := modeltest.ModelDir(t, "basic")
+	report := CheckModel(dir, CheckOptions{Run: true, Timeout: 30 * time.Second})
+	if !report.OK() {
+		t.Fatalf("expected fake model to pass: %+v", report.Items)
+	}
+	called := 0
+	for _, item := range report.Items {
+		if item.Name == "soundTts" || item.Name == "asr" {
+			called++
+		}
+	}
+	if called != 2 {
+		t.Fatalf("expected soundTts and asr to be called: %+v", report.Items)
+	}
+}
+
+func TestCheckModelBrokenConfig(t *testing.T) {
+	dir := modeltest.ModelDir(t, "basic")
+	raw, _ := os.ReadFile(filepath.Join(dir, "config.json"))
+	broken := strings.Replace(string(raw), `"entryArgs": ["${CONFIG}"]`, `"entryArgs": ["${CONF}"]`, 1)
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report := CheckModel(dir, CheckOptions{})
+	if report.OK() {
+		t.Fatal("expected broken placeholders to fail")
+	}
+	msgs := []string{}
+	for _, item := range report.Items {
+		if item.Level == CheckError {
+			msgs = append(msgs, item.Msg)
+		}
+	}
+	joined := strings.Join(msgs, "\n")
+	if !strings.Contains(joined, "${CONF}") || !strings.Contains(joined, "${CONFIG}") {
+		t.Fatalf("unexpected errors: %s", joined)
+	}
+}
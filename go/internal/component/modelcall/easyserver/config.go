@@ -0,0 +1,54 @@
+package easyserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"xiacutai-server/internal/component/errs"
+)
+
+// EntryLauncher easyServer.entry 为该值时使用 config.json 中 launcher 的入口、参数和环境变量
+const EntryLauncher = "launcher"
+
+// ParseServerConfig 解析模型 config.json 的内容
+// easyServer 缺省时也会生成一个空配置，easyServer.entry 为 launcher 时展开 launcher 配置
+func ParseServerConfig(data []byte) (*ServerConfig, error) {
+	var raw struct {
+		ServerConfig
+		Launcher struct {
+			Entry     string   `json:"entry"`
+			EntryArgs []string `json:"entryArgs"`
+			Envs      []string `json:"envs"`
+		} `json:"launcher"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, errs.New(fmt.Sprintf("解析配置文件失败: %v", err))
+	}
+
+	config := raw.ServerConfig
+	if config.EasyServer == nil {
+		config.EasyServer = &EasyServerConfig{}
+	}
+	if config.EasyServer.Entry == EntryLauncher {
+		config.EasyServer.Entry = raw.Launcher.Entry
+		config.EasyServer.EntryArgs = raw.Launcher.EntryArgs
+		config.EasyServer.Envs = raw.Launcher.Envs
+	}
+	return &config, nil
+}
+
+// UnmarshalJSON 选项既可以是 {"value": ..., "label": ...}，也可以直接是值
+func (o *ServerSettingOption) UnmarshalJSON(data []byte) error {
+	var full struct {
+		Value interface{} `json:"value"`
+		Label string      `json:"label"`
+	}
+	if err := json.Unmarshal(data, &full); err == nil {
+		o.Value, o.Label = full.Value, full.Label
+		return nil
+	}
+	if err := json.Unmarshal(data, &o.Value); err != nil {
+		return err
+	}
+	o.Label = fmt.Sprint(o.Value)
+	return nil
+}
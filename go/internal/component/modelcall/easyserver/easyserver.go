@@ -3,6 +3,7 @@ package easyserver
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
@@ -10,6 +11,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 	"xiacutai-server/internal/component/errs"
 	"xiacutai-server/internal/domain"
@@ -24,8 +26,13 @@ type EasyServer struct {
 	ServerRuntime struct {
 		StartTime int64 // 启动时间
 	}
+	mu         sync.Mutex
 	controller *exec.Cmd // 控制进程
 	CancelChan chan struct{}
+	// TimeoutOverride 单个任务指定的超时，零值字段沿用模型配置
+	TimeoutOverride CallTimeout
+	// OnProgress 模型上报进度时回调，在读取日志的协程中调用，不要阻塞
+	OnProgress func(Progress)
 }
 
 // NewEasyServer 创建一个新的 EasyServer 实例
@@ -65,6 +72,8 @@ func (es *EasyServer) Config() (*TaskResult, error) {
 // 返回:
 //   - error: 错误信息
 func (es *EasyServer) Start() error {
+	es.mu.Lock()
+	defer es.mu.Unlock()
 	es.IsRunning = true
 	es.ServerRuntime.StartTime = time.Now().Unix()
 	es.CancelChan = make(chan struct{})
@@ -80,20 +89,20 @@ func (es *EasyServer) Ping() (bool, error) {
 }
 
 // Stop 停止 EasyServer
+// 正在执行的模型进程由执行循环结束整个进程树（先发终止信号，超过 AIGCPANEL_KILL_GRACE_MS 后强制结束）
 // 返回:
 //   - error: 错误信息
 func (es *EasyServer) Stop() error {
-	if es.controller != nil {
-		if err := es.controller.Process.Kill(); err != nil {
-			return errs.New(fmt.Sprintf("failed to kill process: %v", err.Error()))
-		}
-		//	es.controller.Wait()
-		es.controller = nil
-	}
+	es.mu.Lock()
+	defer es.mu.Unlock()
 
 	// 通知执行循环退出
 	if es.CancelChan != nil {
-		close(es.CancelChan)
+		select {
+		case <-es.CancelChan:
+		default:
+			close(es.CancelChan)
+		}
 	}
 
 	es.IsRunning = false
@@ -122,10 +131,6 @@ func (es *EasyServer) CallFunc(
 	resultDataCalculator func(ServerFunctionDataType, LauncherResultType) (map[string]interface{}, error),
 ) (*TaskResult, error) {
 
-	_ = map[string]int{
-		"timeout": 24 * 3600, // 24 hours default
-	}
-
 	resultData := map[string]interface{}{
 		"type":  "success",
 		"start": 0,
@@ -161,30 +166,6 @@ func (es *EasyServer) CallFunc(
 	// Add settings to config data
 	configData["setting"] = es.ServerInfo.Setting
 
-	// Prepare config JSON file
-	configJsonPath, err := es.prepareConfigJson(configData)
-	if err != nil {
-		return nil, errs.New(fmt.Sprintf("failed to prepare config JSON: %v", err))
-	}
-
-	// Clean up config file when done
-	defer os.Remove(configJsonPath)
-
-	// Prepare command
-	command := []string{es.ServerConfig.EasyServer.Entry}
-	if es.ServerConfig.EasyServer.EntryArgs != nil {
-		command = append(command, es.ServerConfig.EasyServer.EntryArgs...)
-	}
-
-	// Replace placeholders
-	for i := range command {
-		command[i] = strings.ReplaceAll(command[i], "${CONFIG}", configJsonPath)
-		command[i] = strings.ReplaceAll(command[i], "${ROOT}", es.ServerInfo.LocalPath)
-	}
-
-	// Prepare environment variables
-	envMap := es.prepareEnvironment()
-
 	// Prepare launcher result
 	launcherResult := LauncherResultType{
 		Result:  map[string]interface{}{},
@@ -192,9 +173,30 @@ func (es *EasyServer) CallFunc(
 	}
 
 	// Execute command
-	err = es.executeCommand(command, envMap, configJsonPath, data.ID, &launcherResult)
+	timeout := es.resolveTimeout(callFunctionName(configData))
+	if es.ServerConfig.EasyServer.Persistent() {
+		// 常驻模型：任务写入队列目录或提交到模型 HTTP 服务，由常驻进程执行
+		err = es.callResident(configData, data.ID, timeout, &launcherResult)
+	} else {
+		err = es.callOnce(configData, data.ID, timeout, &launcherResult)
+	}
 	if err != nil {
-		return nil, errs.New(fmt.Sprintf("failed to execute command: %v", err))
+		kind := ErrorKind(err)
+		if kind == "" {
+			kind = ErrorKindProcess
+		}
+		var callErr *CallError
+		if errors.As(err, &callErr) {
+			if kind == ErrorKindLimit {
+				return nil, callErr
+			}
+			return nil, &CallError{Kind: kind, Msg: fmt.Sprintf("failed to execute command: %v", callErr.Msg), Cleanup: callErr.Cleanup}
+		}
+		return nil, &CallError{Kind: kind, Msg: fmt.Sprintf("failed to execute command: %v", err)}
+	}
+
+	if err := es.checkJailResult(launcherResult.Result); err != nil {
+		return nil, err
 	}
 
 	// Calculate end time
@@ -215,15 +217,63 @@ func (es *EasyServer) CallFunc(
 		Data: resultData,
 	}, nil
 }
+
+// callOnce 启动一个模型进程执行单个任务，拿到结果后结束进程
+func (es *EasyServer) callOnce(configData map[string]interface{}, taskID string, timeout CallTimeout, launcherResult *LauncherResultType) error {
+	// Prepare config JSON file
+	configJsonPath, err := es.prepareConfigJson(configData)
+	if err != nil {
+		return errs.New(fmt.Sprintf("failed to prepare config JSON: %v", err))
+	}
+
+	// Clean up config file when done
+	defer os.Remove(configJsonPath)
+
+	return es.executeCommand(es.buildCommand(configJsonPath), es.prepareEnvironment(), configJsonPath, taskID, timeout, launcherResult)
+}
+
+// buildCommand 生成启动命令并替换占位符
+func (es *EasyServer) buildCommand(configPath string) []string {
+	command := []string{es.ServerConfig.EasyServer.Entry}
+	if es.ServerConfig.EasyServer.EntryArgs != nil {
+		command = append(command, es.ServerConfig.EasyServer.EntryArgs...)
+	}
+
+	// Replace placeholders
+	for i := range command {
+		command[i] = strings.ReplaceAll(command[i], "${CONFIG}", configPath)
+		command[i] = strings.ReplaceAll(command[i], "${ROOT}", es.ServerInfo.LocalPath)
+	}
+	return command
+}
+
+// buildEnv 生成进程环境变量并替换占位符
+func (es *EasyServer) buildEnv(envMap map[string]string, configPath string) []string {
+	env := []string{}
+	for k, v := range envMap {
+		v = strings.ReplaceAll(v, "${CONFIG}", configPath)
+		v = strings.ReplaceAll(v, "${ROOT}", es.ServerInfo.LocalPath)
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	return env
+}
+
+// queueDir 常驻模型的任务队列目录
+func (es *EasyServer) queueDir() string {
+	return filepath.Join(es.ServerInfo.LocalPath, "algorithms", "task_queue")
+}
+
+// enqueueTask 写入任务队列文件，文件名带上任务 ID 方便排查和撤回
 func (es *EasyServer) enqueueTask(config map[string]interface{}) (string, error) {
 
-	queueDir := filepath.Join(es.ServerInfo.LocalPath, "algorithms", "task_queue")
+	queueDir := es.queueDir()
 	if err := os.MkdirAll(queueDir, 0755); err != nil {
 		return "", err
 	}
 
 	ts := time.Now().UnixMilli()
-	filename := fmt.Sprintf("%d.queue.json", ts)
+	id, _ := config["id"].(string)
+	filename := fmt.Sprintf("%d-%s.queue.json", ts, queueFileSafe.ReplaceAllString(id, "_"))
 
 	tmp := filepath.Join(queueDir, filename+".tmp")
 	final := filepath.Join(queueDir, filename)
@@ -281,99 +331,89 @@ func (es *EasyServer) prepareConfigJson(configData map[string]interface{}) (stri
 	return file.Name(), nil
 }
 
-// prepareEnvironment prepares environment variables
-func (es *EasyServer) prepareEnvironment() map[string]string {
-	envMap := make(map[string]string)
-
-	// Copy system environment variables
-	for _, e := range os.Environ() {
-		parts := strings.SplitN(e, "=", 2)
-		if len(parts) == 2 {
-			envMap[strings.ToUpper(parts[0])] = parts[1]
-
-		}
-	}
-
-	// Add custom paths
-	path := envMap["PATH"]
-	envMap["PATH"] = fmt.Sprintf("%s;%s;%s/binary", path, es.ServerInfo.LocalPath, es.ServerInfo.LocalPath)
-
-	// 添加 _aienv 方便调试
-	venv := es.ServerInfo.LocalPath + string(os.PathSeparator) + "_aienv"
-	python := venv + string(os.PathSeparator) + "Scripts"
-	torchlib := venv + string(os.PathSeparator) + "Lib" + string(os.PathSeparator) + "site-packages" + string(os.PathSeparator) + "torch" + string(os.PathSeparator) + "lib"
-	oldPath := envMap["PATH"]
-	newPath := fmt.Sprintf("%s;%s;%s;%s",
-		oldPath,
-		venv,
-		python,
-		torchlib,
-	)
-	envMap["PATH"] = newPath
-
-	// Add other environment variables
-	envMap["PYTHONIOENCODING"] = "utf-8"
-	envMap["AIGCPANEL_SERVER_PLACEHOLDER_CONFIG"] = "" // Will be set per execution
-	envMap["AIGCPANEL_SERVER_PLACEHOLDER_ROOT"] = es.ServerInfo.LocalPath
-
-	// Add custom environment variables from config
-	if es.ServerConfig.EasyServer.Envs != nil {
-		for _, e := range es.ServerConfig.EasyServer.Envs {
-			parts := strings.SplitN(e, "=", 2)
-			if len(parts) == 2 {
-				envMap[parts[0]] = parts[1]
-			}
-		}
-	}
-
-	return envMap
-}
-
 // executeCommand executes the model command
 func (es *EasyServer) executeCommand(
 	command []string,
 	envMap map[string]string,
 	configPath string,
 	taskID string,
+	timeout CallTimeout,
 	launcherResult *LauncherResultType,
 ) error {
 
-	es.CancelChan = make(chan struct{})
+	es.mu.Lock()
+	cancelChan := es.CancelChan
+	es.mu.Unlock()
+	if cancelChan == nil {
+		cancelChan = make(chan struct{})
+	}
+	if err := es.checkJailEntry(command); err != nil {
+		return err
+	}
 
 	cmd := exec.Command(command[0], command[1:]...)
 	cmd.Dir = es.ServerInfo.LocalPath
+	// 独立进程组，结束时连同模型启动的子进程一起结束
+	setProcessGroup(cmd)
 
 	// ---------- env ----------
-	env := []string{}
-	for k, v := range envMap {
-		v = strings.ReplaceAll(v, "${CONFIG}", configPath)
-		v = strings.ReplaceAll(v, "${ROOT}", es.ServerInfo.LocalPath)
-		env = append(env, fmt.Sprintf("%s=%s", k, v))
-	}
-	cmd.Env = env
-
-	es.controller = cmd
+	cmd.Env = es.buildEnv(envMap, configPath)
 
 	// ---------- pipe ----------
-	stdout, err := cmd.StdoutPipe()
+	// 不用 StdoutPipe：Wait 会关闭它，进程退出时还没读到的最后几行（通常就是结果）会丢失
+	stdout, stdoutW, err := os.Pipe()
 	if err != nil {
 		return err
 	}
-	stderr, err := cmd.StderrPipe()
+	defer stdout.Close()
+	stderr, stderrW, err := os.Pipe()
 	if err != nil {
+		_ = stdoutW.Close()
 		return err
 	}
+	defer stderr.Close()
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
 
-	if err := cmd.Start(); err != nil {
+	sb := es.newSandbox()
+	sb.prepare(cmd)
+	err = cmd.Start()
+	// 子进程已持有写端，父进程关闭后，所有进程退出时读端才会 EOF
+	_ = stdoutW.Close()
+	_ = stderrW.Close()
+	if err != nil {
+		sb.release()
 		return err
 	}
+	pid := cmd.Process.Pid
+	writePidFile(pid, taskID, command[0])
+	defer removePidFile(pid)
+	if err := sb.attach(pid); err != nil {
+		_ = killGroup(pid)
+		_ = cmd.Wait()
+		return &CallError{Kind: ErrorKindLimit, Msg: err.Error()}
+	}
+	defer sb.release()
+
+	es.mu.Lock()
+	es.controller = cmd
+	es.mu.Unlock()
+	defer func() {
+		es.mu.Lock()
+		es.controller = nil
+		es.mu.Unlock()
+	}()
 
 	done := make(chan struct{})
-	waitDone := make(chan error, 1)
+	var doneOnce sync.Once
+	exited := make(chan struct{})
+	var waitErr error
+	alive := make(chan struct{}, 1)
 
 	// ⭐ Wait 只允许在这里
 	go func() {
-		waitDone <- cmd.Wait()
+		waitErr = cmd.Wait()
+		close(exited)
 	}()
 
 	// ---------- 读取日志 ----------
@@ -384,7 +424,7 @@ func (es *EasyServer) executeCommand(
 
 		for {
 			select {
-			case <-es.CancelChan:
+			case <-cancelChan:
 				return
 			default:
 			}
@@ -399,53 +439,146 @@ func (es *EasyServer) executeCommand(
 			result, ok := ExtractResultFromLogs(taskID, line)
 			if ok && result != nil {
 
+				// 中间结果，重置空闲计时并上报进度
+				if result["_alive"] == true {
+					select {
+					case alive <- struct{}{}:
+					default:
+					}
+					progress, _ := result["_progress"].(*Progress)
+					es.reportProgress(progress)
+					continue
+				}
+
 				for k, v := range result {
 					launcherResult.Result[k] = v
 				}
 
 				// 收到最终结果
-				if _, hasUrl := result["url"]; hasUrl ||
-					result["records"] != nil ||
-					result["error"] != nil {
-
-					now := time.Now().Unix()
-					launcherResult.EndTime = &now
+				if isFinalResult(result) {
 
-					close(done)
+					doneOnce.Do(func() {
+						now := time.Now().Unix()
+						launcherResult.EndTime = &now
+						close(done)
+					})
 					return
 				}
 			}
 		}
 	}
 
-	go read(stdout)
-	go read(stderr)
+	var readers sync.WaitGroup
+	readersDone := make(chan struct{})
+	readers.Add(2)
+	go func() { defer readers.Done(); read(stdout) }()
+	go func() { defer readers.Done(); read(stderr) }()
+	go func() { readers.Wait(); close(readersDone) }()
 
-	timeout := time.After(10 * time.Minute)
-
-	// ---------- 主等待 ----------
-	select {
-
-	case <-done:
-		_ = cmd.Process.Kill()
-		<-waitDone
+	// finish 拿到结果后的处理：模型报错时检查是否因子进程超出限制
+	finish := func() error {
+		if _, failed := launcherResult.Result["error"]; failed {
+			if err := sb.violationError(waitErr); err != nil {
+				return err
+			}
+		}
 		return nil
+	}
+
+	// 总时长从启动开始计算，不因中间结果重置
+	total := time.NewTimer(timeout.Total)
+	defer total.Stop()
+	// 空闲超时：一段时间没有任何中间结果视为模型卡住
+	var idle *time.Timer
+	var idleC <-chan time.Time
+	if timeout.Idle > 0 {
+		idle = time.NewTimer(timeout.Idle)
+		defer idle.Stop()
+		idleC = idle.C
+	}
 
-	case <-es.CancelChan:
-		_ = cmd.Process.Kill()
-		<-waitDone
-		return errs.New("task cancelled")
+	// ---------- 主等待 ----------
+	for {
+		select {
+
+		case <-done:
+			// 已拿到结果，不需要等模型自行退出
+			_ = killGroup(pid)
+			<-exited
+			return finish()
+
+		case <-cancelChan:
+			cleanup := killProcessTree(pid, exited)
+			return &CallError{Kind: ErrorKindCancelled, Msg: "task cancelled", Cleanup: cleanup}
+
+		case <-total.C:
+			cleanup := killProcessTree(pid, exited)
+			return &CallError{Kind: ErrorKindTimeout, Msg: fmt.Sprintf("model timeout after %s", timeout.Total), Cleanup: cleanup}
+
+		case <-idleC:
+			cleanup := killProcessTree(pid, exited)
+			return &CallError{Kind: ErrorKindTimeout, Msg: fmt.Sprintf("model idle timeout, no progress for %s", timeout.Idle), Cleanup: cleanup}
+
+		case <-alive:
+			if idle != nil {
+				if !idle.Stop() {
+					select {
+					case <-idle.C:
+					default:
+					}
+				}
+				idle.Reset(timeout.Idle)
+			}
 
-	case <-timeout:
-		_ = cmd.Process.Kill()
-		<-waitDone
-		return errs.New("model timeout")
+		case <-exited:
+			// 主进程自行退出，清理它留下的子进程
+			cleanupGroup(pid, time.Time{})
+			// 读完剩余日志，结果可能在最后几行
+			select {
+			case <-readersDone:
+			case <-time.After(readDrainTimeout):
+			}
+			select {
+			case <-done:
+				return finish()
+			default:
+			}
+			if err := sb.violationError(waitErr); err != nil {
+				return err
+			}
+			return waitErr
+		}
+	}
+}
 
-	case err := <-waitDone:
-		return err
+// imageResultData 图片结果：模型可以返回 url 或 urls，统一成 url（第一张）+ urls
+func imageResultData(data ServerFunctionDataType, launcherResult LauncherResultType) (map[string]interface{}, error) {
+	urls := make([]interface{}, 0)
+	if list, ok := launcherResult.Result["urls"].([]interface{}); ok {
+		for _, item := range list {
+			if url, ok := item.(string); ok && url != "" {
+				urls = append(urls, url)
+			}
+		}
+	}
+	if url, ok := launcherResult.Result["url"].(string); ok && url != "" && len(urls) == 0 {
+		urls = append(urls, url)
+	}
+	if len(urls) == 0 {
+		if errMsg, ok := launcherResult.Result["error"]; ok {
+			return nil, errs.New(fmt.Sprintf("%v", errMsg))
+		}
+		return nil, errs.New("执行失败，请查看模型日志")
 	}
+	return map[string]interface{}{
+		"url":  urls[0],
+		"urls": urls,
+	}, nil
 }
 
+// readDrainTimeout 进程退出后等待读完日志的最长时间
+const readDrainTimeout = 2 * time.Second
+
 // SoundTts handles sound TTS function
 func (es *EasyServer) SoundTts(data ServerFunctionDataType) (*TaskResult, error) {
 	configCalculator := func(data ServerFunctionDataType) (map[string]interface{}, error) {
@@ -568,3 +701,88 @@ func (es *EasyServer) Asr(data ServerFunctionDataType) (*TaskResult, error) {
 
 	return es.CallFunc(data, configCalculator, resultDataCalculator)
 }
+
+// ImageGen handles image generation function
+func (es *EasyServer) ImageGen(data ServerFunctionDataType) (*TaskResult, error) {
+	configCalculator := func(data ServerFunctionDataType) (map[string]interface{}, error) {
+		return map[string]interface{}{
+			"id":   data.ID,
+			"mode": "local",
+			"modelConfig": map[string]interface{}{
+				"type":  string(FunctionImageGen),
+				"param": data.Param,
+				"text":  data.Text,
+			},
+		}, nil
+	}
+	return es.CallFunc(data, configCalculator, imageResultData)
+}
+
+// ImageEdit handles image editing function
+func (es *EasyServer) ImageEdit(data ServerFunctionDataType) (*TaskResult, error) {
+	configCalculator := func(data ServerFunctionDataType) (map[string]interface{}, error) {
+		if data.Image == "" {
+			return nil, errs.New("image is required")
+		}
+		return map[string]interface{}{
+			"id":   data.ID,
+			"mode": "local",
+			"modelConfig": map[string]interface{}{
+				"type":  string(FunctionImageEdit),
+				"param": data.Param,
+				"text":  data.Text,
+				"image": data.Image,
+				"mask":  data.Mask,
+			},
+		}, nil
+	}
+	return es.CallFunc(data, configCalculator, imageResultData)
+}
+
+// ImageUpscale handles image upscaling function
+func (es *EasyServer) ImageUpscale(data ServerFunctionDataType) (*TaskResult, error) {
+	configCalculator := func(data ServerFunctionDataType) (map[string]interface{}, error) {
+		if data.Image == "" {
+			return nil, errs.New("image is required")
+		}
+		return map[string]interface{}{
+			"id":   data.ID,
+			"mode": "local",
+			"modelConfig": map[string]interface{}{
+				"type":  string(FunctionImageUpscale),
+				"param": data.Param,
+				"image": data.Image,
+			},
+		}, nil
+	}
+	return es.CallFunc(data, configCalculator, imageResultData)
+}
+
+// functionCalls 功能名到调用方法，新增功能时在这里注册
+var functionCalls = map[ServerFunction]func(es *EasyServer, data ServerFunctionDataType) (*TaskResult, error){
+	FunctionSoundTts:     (*EasyServer).SoundTts,
+	FunctionSoundClone:   (*EasyServer).SoundClone,
+	FunctionVideoGen:     (*EasyServer).VideoGen,
+	FunctionAsr:          (*EasyServer).Asr,
+	FunctionImageGen:     (*EasyServer).ImageGen,
+	FunctionImageEdit:    (*EasyServer).ImageEdit,
+	FunctionImageUpscale: (*EasyServer).ImageUpscale,
+}
+
+// Supported 运行时是否实现了该功能
+func Supported(function ServerFunction) bool {
+	_, ok := functionCalls[function]
+	return ok
+}
+
+// Call 按功能名调用，功能必须在 config.json 的 functions 中声明
+func (es *EasyServer) Call(function ServerFunction, data ServerFunctionDataType) (*TaskResult, error) {
+	if len(es.ServerConfig.Functions) > 0 && !es.ServerConfig.HasFunction(function) {
+		return nil, errs.New(fmt.Sprintf("模型未声明功能 %s", function))
+	}
+	call, ok := functionCalls[function]
+	if !ok {
+		return nil, errs.New(fmt.Sprintf("不支持的功能 %s", function))
+	}
+	return call(es, data)
+}
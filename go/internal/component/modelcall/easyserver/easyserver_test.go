@@ -0,0 +1,288 @@
+package easyserver_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"xiacutai-server/internal/component/modelcall"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/component/modelcall/modeltest"
+	"xiacutai-server/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "easyserver-test-*")
+	if err != nil {
+		panic(err)
+	}
+	utils.JsonDir = dir
+	_ = os.Setenv("AIGCPANEL_KILL_GRACE_MS", "300")
+	code := m.Run()
+	easyserver.StopAllResidents()
+	modeltest.Cleanup()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newServer(t *testing.T, fixture string) *easyserver.EasyServer {
+	t.Helper()
+	dir := modeltest.ModelDir(t, fixture)
+	config, err := modelcall.LoadConfigFromJSON(filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	es := easyserver.NewEasyServer(*config)
+	es.ServerInfo = &easyserver.ServerInfo{LocalPath: dir, Name: config.Name, Version: config.Version, Setting: map[string]interface{}{}, Config: *config}
+	if err := es.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = es.Stop() })
+	return es
+}
+
+func ttsData(id string, fake map[string]interface{}) easyserver.ServerFunctionDataType {
+	param := map[string]interface{}{}
+	if fake != nil {
+		param["fake"] = fake
+	}
+	return easyserver.ServerFunctionDataType{ID: id, Text: "你好", Param: param, Result: map[string]interface{}{}}
+}
+
+func resultURL(t *testing.T, result *easyserver.TaskResult) string {
+	t.Helper()
+	data := result.Data.(map[string]interface{})["data"].(map[string]interface{})
+	url, _ := data["url"].(string)
+	if _, err := os.Stat(url); err != nil {
+		t.Fatalf("result file missing: %v", err)
+	}
+	return url
+}
+
+func TestSoundTts(t *testing.T) {
+	es := newServer(t, "basic")
+	result, err := es.SoundTts(ttsData("tts-1", map[string]interface{}{"noise": true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url := resultURL(t, result); !strings.HasSuffix(url, ".wav") {
+		t.Fatalf("unexpected url %s", url)
+	}
+}
+
+func TestAsrRecords(t *testing.T) {
+	es := newServer(t, "basic")
+	audio := filepath.Join(t.TempDir(), "a.wav")
+	_ = os.WriteFile(audio, []byte("RIFF"), 0644)
+	result, err := es.Asr(easyserver.ServerFunctionDataType{ID: "asr-1", Audio: audio, Param: map[string]interface{}{}, Result: map[string]interface{}{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, _ := result.Data.(map[string]interface{})["data"].(map[string]interface{})["records"].([]interface{})
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %v", records)
+	}
+}
+
+func TestImageFunctions(t *testing.T) {
+	es := newServer(t, "basic")
+	result, err := es.ImageGen(easyserver.ServerFunctionDataType{ID: "image-1", Text: "a cat", Param: map[string]interface{}{"count": 2}, Result: map[string]interface{}{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls, _ := result.Data.(map[string]interface{})["data"].(map[string]interface{})["urls"].([]interface{})
+	if len(urls) != 2 {
+		t.Fatalf("expected 2 images, got %v", urls)
+	}
+	image := resultURL(t, result)
+	if _, err := es.ImageUpscale(easyserver.ServerFunctionDataType{ID: "image-2", Image: image, Param: map[string]interface{}{}, Result: map[string]interface{}{}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := es.ImageEdit(easyserver.ServerFunctionDataType{ID: "image-3", Text: "dog", Param: map[string]interface{}{}, Result: map[string]interface{}{}}); err == nil {
+		t.Fatal("expected imageEdit without image to fail")
+	}
+}
+
+func TestProgress(t *testing.T) {
+	es := newServer(t, "basic")
+	var mu sync.Mutex
+	var got []easyserver.Progress
+	es.OnProgress = func(p easyserver.Progress) {
+		mu.Lock()
+		got = append(got, p)
+		mu.Unlock()
+	}
+	if _, err := es.SoundTts(ttsData("progress-1", map[string]interface{}{"delayMs": 300, "progress": 3})); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 3 || got[2].Percent != 100 || got[0].Stage != "inference" {
+		t.Fatalf("unexpected progress %+v", got)
+	}
+}
+
+func TestExtractProgressWithBrackets(t *testing.T) {
+	line := `Result[task-1][{"percent":40,"stage":"inference","outputs":{"segments":["a.wav","b.wav"]}}]`
+	out, ok := easyserver.ExtractResultFromLogs("task-1", line)
+	if !ok {
+		t.Fatal("line not recognized")
+	}
+	progress, _ := out["_progress"].(*easyserver.Progress)
+	if progress == nil || progress.Percent != 40 || len(progress.Outputs["segments"].([]interface{})) != 2 {
+		t.Fatalf("unexpected progress %+v", out)
+	}
+	if _, ok := easyserver.ExtractResultFromLogs("task-2", line); ok {
+		t.Fatal("other task id should not match")
+	}
+}
+
+func TestModelError(t *testing.T) {
+	es := newServer(t, "basic")
+	_, err := es.SoundTts(ttsData("error-1", map[string]interface{}{"error": "out of memory"}))
+	if err == nil || !strings.Contains(err.Error(), "out of memory") {
+		t.Fatalf("expected model error, got %v", err)
+	}
+}
+
+func TestProcessExit(t *testing.T) {
+	es := newServer(t, "basic")
+	_, err := es.SoundTts(ttsData("exit-1", map[string]interface{}{"exitCode": 3}))
+	if easyserver.ErrorKind(err) != easyserver.ErrorKindProcess {
+		t.Fatalf("expected process error, got %v", err)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	es := newServer(t, "basic")
+	es.TimeoutOverride = easyserver.CallTimeout{Total: 10 * time.Second, Idle: 300 * time.Millisecond}
+	start := time.Now()
+	_, err := es.SoundTts(ttsData("hang-1", map[string]interface{}{"hang": true}))
+	if easyserver.ErrorKind(err) != easyserver.ErrorKindTimeout {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("idle timeout took %s", time.Since(start))
+	}
+}
+
+func TestCancelKillsProcessTree(t *testing.T) {
+	es := newServer(t, "basic")
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		_ = es.Cancel()
+	}()
+	_, err := es.SoundTts(ttsData("cancel-1", map[string]interface{}{"hang": true, "spawnChild": true, "ignoreTerm": true}))
+	if easyserver.ErrorKind(err) != easyserver.ErrorKindCancelled {
+		t.Fatalf("expected cancelled, got %v", err)
+	}
+	if easyserver.ErrorCleanup(err) == "" {
+		t.Fatalf("expected forced cleanup note, got %v", err)
+	}
+}
+
+func TestResidentConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	dir := ""
+	errs := make([]error, 4)
+	for i := range errs {
+		es := newServer(t, "resident")
+		if dir == "" {
+			dir = es.ServerInfo.LocalPath
+		}
+		// 共用同一个模型目录，才会使用同一个常驻进程
+		es.ServerInfo.LocalPath = dir
+		wg.Add(1)
+		go func(i int, es *easyserver.EasyServer) {
+			defer wg.Done()
+			_, errs[i] = es.SoundTts(ttsData(fmt.Sprintf("resident-%d", i), map[string]interface{}{"delayMs": 200}))
+		}(i, es)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(easyserver.ResidentServers()); n != 1 {
+		t.Fatalf("expected 1 resident server, got %d", n)
+	}
+	easyserver.StopAllResidents()
+}
+
+func TestHTTPTransport(t *testing.T) {
+	es := newServer(t, "http")
+	result, err := es.SoundTts(ttsData("http-1", map[string]interface{}{"delayMs": 200, "progress": 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultURL(t, result)
+	if _, err := es.SoundTts(ttsData("http-2", map[string]interface{}{"error": "bad input"})); err == nil || !strings.Contains(err.Error(), "bad input") {
+		t.Fatalf("expected model error, got %v", err)
+	}
+	easyserver.StopAllResidents()
+}
+
+func registerModel(t *testing.T, fixture string) (string, string) {
+	t.Helper()
+	dir := modeltest.ModelDir(t, fixture)
+	config, err := modelcall.LoadConfigFromJSON(filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := config.Name + "|" + fixture
+	easyserver.Servers.Register(key, easyserver.ServerInfo{LocalPath: dir, Name: config.Name, Version: config.Version, Setting: map[string]interface{}{}, Config: *config})
+	t.Cleanup(func() { easyserver.Servers.Unregister(key) })
+	return key, dir
+}
+
+func TestManagerLifecycle(t *testing.T) {
+	for _, fixture := range []string{"basic", "http"} {
+		key, _ := registerModel(t, fixture)
+		if _, err := easyserver.Servers.Call(key, easyserver.FunctionSoundTts, ttsData("manager-0", nil)); err == nil {
+			t.Fatalf("%s: expected call before start to fail", fixture)
+		}
+		if err := easyserver.Servers.Start(key); err != nil {
+			t.Fatalf("%s: %v", fixture, err)
+		}
+		if ok, err := easyserver.Servers.Ping(key); !ok || err != nil {
+			t.Fatalf("%s: ping %v %v", fixture, ok, err)
+		}
+		result, err := easyserver.Servers.Call(key, easyserver.FunctionSoundTts, ttsData("manager-1", nil))
+		if err != nil {
+			t.Fatalf("%s: %v", fixture, err)
+		}
+		resultURL(t, result)
+		if _, err := easyserver.Servers.Call(key, easyserver.FunctionLive, ttsData("manager-2", nil)); err == nil {
+			t.Fatalf("%s: expected undeclared function to fail", fixture)
+		}
+		state, _ := easyserver.Servers.Status(key)
+		if state.Status != easyserver.ServerRunning || (fixture == "http") != (state.Resident != nil) {
+			t.Fatalf("%s: unexpected state %+v", fixture, state)
+		}
+		if err := easyserver.Servers.Stop(key); err != nil {
+			t.Fatal(err)
+		}
+		if ok, _ := easyserver.Servers.Ping(key); ok {
+			t.Fatalf("%s: expected ping to fail after stop", fixture)
+		}
+	}
+}
+
+func TestManagerMissingEntry(t *testing.T) {
+	key, dir := registerModel(t, "basic")
+	entries, _ := filepath.Glob(filepath.Join(dir, "fake_model*"))
+	for _, entry := range entries {
+		_ = os.Remove(entry)
+	}
+	if err := easyserver.Servers.Start(key); err == nil {
+		t.Fatal("expected start to fail without entry")
+	}
+	state, _ := easyserver.Servers.Status(key)
+	if state.Status != easyserver.ServerError || !strings.Contains(state.Error, "fake_model") {
+		t.Fatalf("unexpected state %+v", state)
+	}
+}
@@ -0,0 +1,204 @@
+package easyserver
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// 模型进程启动时保留原样、由 buildEnv 替换的占位符
+var envPlaceholders = map[string]bool{"CONFIG": true, "ROOT": true, "PORT": true}
+
+var reEnvRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// envPlatformAliases easyServer.platformEnvs 中各系统可用的键
+var envPlatformAliases = map[string][]string{
+	"windows": {"windows", "win", "win32"},
+	"darwin":  {"darwin", "osx", "mac", "macos"},
+	"linux":   {"linux"},
+}
+
+// modelEnv 模型进程的环境变量
+// Windows 上环境变量名不区分大小写，按原样保留第一次出现的写法（如 Path），其它系统区分大小写
+type modelEnv struct {
+	goos   string
+	keys   map[string]string // 规范化的名称 -> 实际名称
+	values map[string]string
+}
+
+func newModelEnv(goos string, environ []string) *modelEnv {
+	env := &modelEnv{goos: goos, keys: map[string]string{}, values: map[string]string{}}
+	for _, e := range environ {
+		parts := strings.SplitN(e, "=", 2)
+		// Windows 上会有 =C:=C:\ 这样的条目
+		if len(parts) == 2 && parts[0] != "" {
+			env.Set(parts[0], parts[1])
+		}
+	}
+	return env
+}
+
+func (env *modelEnv) canonical(key string) string {
+	if env.goos == "windows" {
+		return strings.ToUpper(key)
+	}
+	return key
+}
+
+func (env *modelEnv) Get(key string) string {
+	return env.values[env.keys[env.canonical(key)]]
+}
+
+func (env *modelEnv) Set(key, value string) {
+	c := env.canonical(key)
+	actual, ok := env.keys[c]
+	if !ok {
+		actual = key
+		env.keys[c] = key
+	}
+	env.values[actual] = value
+}
+
+// listSeparator PATH 等列表变量的分隔符
+func (env *modelEnv) listSeparator() string {
+	if env.goos == "windows" {
+		return ";"
+	}
+	return ":"
+}
+
+// Prepend 把目录加到列表变量最前面，已存在的目录不重复添加
+func (env *modelEnv) Prepend(key string, dirs ...string) {
+	sep := env.listSeparator()
+	list := make([]string, 0, len(dirs)+8)
+	seen := map[string]bool{}
+	for _, item := range append(dirs, strings.Split(env.Get(key), sep)...) {
+		if item == "" || seen[env.canonical(item)] {
+			continue
+		}
+		seen[env.canonical(item)] = true
+		list = append(list, item)
+	}
+	env.Set(key, strings.Join(list, sep))
+}
+
+// Apply 应用 config.json 中 KEY=VALUE 形式的配置
+// 值中的 ${NAME} 引用当前环境变量，${SEP} 为列表分隔符，如 PATH=${ROOT}/tools${SEP}${PATH}
+func (env *modelEnv) Apply(items []string) {
+	for _, item := range items {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			continue
+		}
+		value := reEnvRef.ReplaceAllStringFunc(parts[1], func(ref string) string {
+			name := ref[2 : len(ref)-1]
+			if envPlaceholders[name] {
+				return ref
+			}
+			if name == "SEP" {
+				return env.listSeparator()
+			}
+			return env.Get(name)
+		})
+		env.Set(parts[0], value)
+	}
+}
+
+func (env *modelEnv) Map() map[string]string {
+	out := make(map[string]string, len(env.values))
+	for k, v := range env.values {
+		out[k] = v
+	}
+	return out
+}
+
+// platformEnvs 当前系统在 easyServer.platformEnvs 中的配置
+func platformEnvs(goos string, cfg *EasyServerConfig) []string {
+	if cfg == nil || len(cfg.PlatformEnvs) == 0 {
+		return nil
+	}
+	aliases := envPlatformAliases[goos]
+	if aliases == nil {
+		aliases = []string{goos}
+	}
+	// 多个别名同时配置时按键名排序，结果稳定
+	keys := make([]string, 0, len(cfg.PlatformEnvs))
+	for k := range cfg.PlatformEnvs {
+		for _, alias := range aliases {
+			if strings.EqualFold(k, alias) {
+				keys = append(keys, k)
+				break
+			}
+		}
+	}
+	sort.Strings(keys)
+	var items []string
+	for _, k := range keys {
+		items = append(items, cfg.PlatformEnvs[k]...)
+	}
+	return items
+}
+
+// buildModelEnv 按系统生成模型进程的环境变量
+//   - PATH 前面加上虚拟环境（_aienv）的可执行目录、模型目录和 binary 目录
+//   - 虚拟环境在 Windows 上是 Scripts，其它系统是 bin
+//   - torch 等库目录在 Windows 上加入 PATH，Linux 加入 LD_LIBRARY_PATH，macOS 加入 DYLD_LIBRARY_PATH
+//   - 配置了 limits.envAllowlist 时只继承名单中的变量，limits.jail 时 home 和临时目录指向模型目录内
+//   - 最后依次应用 easyServer.envs 和 easyServer.platformEnvs.<系统>
+func buildModelEnv(goos string, environ []string, root string, cfg *EasyServerConfig) map[string]string {
+	if cfg != nil && cfg.Limits != nil && len(cfg.Limits.EnvAllowlist) > 0 {
+		environ = filterEnviron(environ, cfg.Limits.EnvAllowlist)
+	}
+	env := newModelEnv(goos, environ)
+	join := func(elem ...string) string {
+		if goos == "windows" {
+			return strings.Join(elem, `\`)
+		}
+		return strings.Join(elem, "/")
+	}
+
+	venv := join(root, "_aienv")
+	binary := join(root, "binary")
+	switch goos {
+	case "windows":
+		env.Prepend("PATH", join(venv, "Scripts"), venv, root, binary, join(venv, "Lib", "site-packages", "torch", "lib"))
+	default:
+		libs := []string{binary}
+		// python3.x 的版本号不固定
+		if matches, _ := filepath.Glob(filepath.Join(venv, "lib", "python*", "site-packages", "torch", "lib")); len(matches) > 0 {
+			sort.Strings(matches)
+			libs = append(libs, matches[len(matches)-1])
+		}
+		env.Prepend("PATH", join(venv, "bin"), root, binary)
+		if goos == "darwin" {
+			env.Prepend("DYLD_LIBRARY_PATH", libs...)
+		} else {
+			env.Prepend("LD_LIBRARY_PATH", libs...)
+		}
+	}
+	if info, err := os.Stat(venv); err == nil && info.IsDir() {
+		env.Set("VIRTUAL_ENV", venv)
+	}
+
+	if cfg != nil && cfg.Limits != nil && cfg.Limits.Jail {
+		applyJailEnv(env, root)
+	}
+
+	env.Set("PYTHONIOENCODING", "utf-8")
+	env.Set("AIGCPANEL_SERVER_PLACEHOLDER_CONFIG", "") // Will be set per execution
+	env.Set("AIGCPANEL_SERVER_PLACEHOLDER_ROOT", root)
+
+	if cfg != nil {
+		env.Apply(cfg.Envs)
+	}
+	env.Apply(platformEnvs(goos, cfg))
+	return env.Map()
+}
+
+// prepareEnvironment prepares environment variables
+func (es *EasyServer) prepareEnvironment() map[string]string {
+	return buildModelEnv(runtime.GOOS, os.Environ(), es.ServerInfo.LocalPath, es.ServerConfig.EasyServer)
+}
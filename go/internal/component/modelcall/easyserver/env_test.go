@@ -0,0 +1,113 @@
+package easyserver
+
+import "testing"
+
+func TestBuildModelEnv(t *testing.T) {
+	cfg := &EasyServerConfig{
+		Envs: []string{"FOO=base", "LIST=a${SEP}b", "MODEL_DATA=${ROOT}/data", "FROM_PATH=${PATH}"},
+		PlatformEnvs: map[string][]string{
+			"Win":   {"FOO=windows"},
+			"linux": {"FOO=linux"},
+			"mac":   {"FOO=darwin"},
+		},
+	}
+	cases := []struct {
+		goos    string
+		root    string
+		environ []string
+		want    map[string]string
+		absent  []string
+	}{
+		{
+			goos:    "linux",
+			root:    "/m",
+			environ: []string{"PATH=/usr/bin:/m", "path=lower", "LD_LIBRARY_PATH=/opt/lib"},
+			want: map[string]string{
+				"PATH":            "/m/_aienv/bin:/m:/m/binary:/usr/bin",
+				"path":            "lower", // 区分大小写，不合并
+				"LD_LIBRARY_PATH": "/m/binary:/opt/lib",
+				"FOO":             "linux",
+				"LIST":            "a:b",
+			},
+			absent: []string{"DYLD_LIBRARY_PATH"},
+		},
+		{
+			goos:    "darwin",
+			root:    "/m",
+			environ: []string{"PATH=/usr/bin"},
+			want: map[string]string{
+				"PATH":              "/m/_aienv/bin:/m:/m/binary:/usr/bin",
+				"DYLD_LIBRARY_PATH": "/m/binary",
+				"FOO":               "darwin",
+				"LIST":              "a:b",
+			},
+			absent: []string{"LD_LIBRARY_PATH"},
+		},
+		{
+			goos:    "windows",
+			root:    `C:\m`,
+			environ: []string{`Path=C:\Windows;c:\M`, `=C:=C:\`, `foo=inherited`},
+			want: map[string]string{
+				// 保留第一次出现的写法，目录按不区分大小写去重
+				"Path": `C:\m\_aienv\Scripts;C:\m\_aienv;C:\m;C:\m\binary;C:\m\_aienv\Lib\site-packages\torch\lib;C:\Windows`,
+				"foo":  "windows",
+				"LIST": "a;b",
+			},
+			absent: []string{"PATH", "FOO", "LD_LIBRARY_PATH", "DYLD_LIBRARY_PATH", "=C:"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.goos, func(t *testing.T) {
+			env := buildModelEnv(c.goos, c.environ, c.root, cfg)
+			for key, want := range c.want {
+				if env[key] != want {
+					t.Errorf("%s = %q, want %q", key, env[key], want)
+				}
+			}
+			for _, key := range c.absent {
+				if _, ok := env[key]; ok {
+					t.Errorf("%s should not be set, got %q", key, env[key])
+				}
+			}
+			// ${ROOT} 等占位符留给调用时替换，${PATH} 引用已加入模型目录后的值
+			if env["MODEL_DATA"] != "${ROOT}/data" {
+				t.Errorf("placeholder should be kept, got %q", env["MODEL_DATA"])
+			}
+			path := env["PATH"]
+			if c.goos == "windows" {
+				path = env["Path"]
+			}
+			if env["FROM_PATH"] != path {
+				t.Errorf("FROM_PATH = %q, want %q", env["FROM_PATH"], path)
+			}
+			if env["AIGCPANEL_SERVER_PLACEHOLDER_ROOT"] != c.root || env["PYTHONIOENCODING"] != "utf-8" {
+				t.Errorf("missing server envs: %v", env)
+			}
+		})
+	}
+}
+
+func TestPlatformEnvs(t *testing.T) {
+	cfg := &EasyServerConfig{PlatformEnvs: map[string][]string{
+		"win32":   {"A=1"},
+		"windows": {"B=2"},
+		"MacOS":   {"C=3"},
+		"freebsd": {"D=4"},
+	}}
+	// 多个别名按键名排序后依次应用
+	if got := platformEnvs("windows", cfg); len(got) != 2 || got[0] != "A=1" || got[1] != "B=2" {
+		t.Fatalf("windows: %v", got)
+	}
+	if got := platformEnvs("darwin", cfg); len(got) != 1 || got[0] != "C=3" {
+		t.Fatalf("darwin: %v", got)
+	}
+	if got := platformEnvs("freebsd", cfg); len(got) != 1 || got[0] != "D=4" {
+		t.Fatalf("freebsd: %v", got)
+	}
+	if got := platformEnvs("linux", cfg); len(got) != 0 {
+		t.Fatalf("linux: %v", got)
+	}
+	if got := platformEnvs("linux", nil); got != nil {
+		t.Fatalf("nil config: %v", got)
+	}
+}
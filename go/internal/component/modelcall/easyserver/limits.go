@@ -0,0 +1,208 @@
+package easyserver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"xiacutai-server/internal/utils"
+)
+
+// EasyServerLimits 模型进程的资源限制，对应 config.json 中的 easyServer.limits
+type EasyServerLimits struct {
+	MaxMemoryMB   int      `json:"maxMemoryMB"`   // 内存上限（MB），Linux 优先用 cgroup v2 的 memory.max，不可用时退回 RLIMIT_AS
+	MaxCPUs       float64  `json:"maxCpus"`       // CPU 核数上限，如 2.5，需要 cgroup v2，Linux 上不可用时调用失败
+	MaxCPUSeconds int      `json:"maxCpuSeconds"` // 累计 CPU 时间上限（秒），RLIMIT_CPU
+	Nice          int      `json:"nice"`          // 进程优先级，1-19 越大越低；Windows 上 >0 为低于正常，>=15 为空闲
+	EnvAllowlist  []string `json:"envAllowlist"`  // 只继承列出的服务环境变量，支持 CUDA_* 这样的前缀，为空时全部继承
+	Jail          bool     `json:"jail"`          // 限制在模型目录内运行
+}
+
+// Enabled 是否配置了需要在进程上生效的限制
+func (l *EasyServerLimits) Enabled() bool {
+	return l != nil && (l.MaxMemoryMB > 0 || l.MaxCPUs > 0 || l.MaxCPUSeconds > 0 || l.Nice != 0)
+}
+
+// ErrorKindLimit 模型超出资源限制或违反目录限制，重试大概率仍然失败
+const ErrorKindLimit = "limit"
+
+// baseEnvAllowlist 启用 envAllowlist 后仍然继承的系统变量，缺少这些变量很多程序无法运行
+var baseEnvAllowlist = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "LANG", "LANGUAGE", "LC_*", "TZ", "TERM",
+	"TMPDIR", "TEMP", "TMP",
+	"SYSTEMROOT", "SYSTEMDRIVE", "WINDIR", "COMSPEC", "PATHEXT", "USERPROFILE", "APPDATA", "LOCALAPPDATA",
+	"PROGRAMDATA", "PROGRAMFILES", "PROGRAMFILES(X86)", "NUMBER_OF_PROCESSORS", "PROCESSOR_ARCHITECTURE", "OS",
+}
+
+// filterEnviron 只保留允许名单中的环境变量，名称不区分大小写
+func filterEnviron(environ []string, allowlist []string) []string {
+	patterns := append(append([]string{}, baseEnvAllowlist...), allowlist...)
+	match := func(name string) bool {
+		name = strings.ToUpper(name)
+		for _, p := range patterns {
+			p = strings.ToUpper(p)
+			if strings.HasSuffix(p, "*") {
+				if strings.HasPrefix(name, strings.TrimSuffix(p, "*")) {
+					return true
+				}
+			} else if name == p {
+				return true
+			}
+		}
+		return false
+	}
+	out := make([]string, 0, len(environ))
+	for _, e := range environ {
+		if name, _, ok := strings.Cut(e, "="); ok && match(name) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// jailDirs 目录限制下模型使用的 home 和临时目录，都在模型目录内
+func jailDirs(root string) (home string, tmp string) {
+	return filepath.Join(root, "_jail", "home"), filepath.Join(root, "_jail", "tmp")
+}
+
+// applyJailEnv 把 home、临时目录和缓存目录指向模型目录内
+func applyJailEnv(env *modelEnv, root string) {
+	home, tmp := jailDirs(root)
+	_ = os.MkdirAll(home, 0755)
+	_ = os.MkdirAll(tmp, 0755)
+	for _, key := range []string{"HOME", "USERPROFILE"} {
+		env.Set(key, home)
+	}
+	for _, key := range []string{"TMPDIR", "TEMP", "TMP"} {
+		env.Set(key, tmp)
+	}
+	env.Set("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+}
+
+// pathWithin path 是否在 dir 内（含 dir 本身）
+func pathWithin(path, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
+
+// jailRoots 目录限制下允许模型访问的目录：模型目录和数据目录（输入文件、调用配置、结果都在数据目录中）
+func (es *EasyServer) jailRoots() []string {
+	roots := []string{}
+	for _, dir := range []string{es.ServerInfo.LocalPath, utils.DataDir} {
+		if dir == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = resolved
+		}
+		roots = append(roots, dir)
+	}
+	return roots
+}
+
+func (es *EasyServer) limits() *EasyServerLimits {
+	if es.ServerConfig.EasyServer == nil {
+		return nil
+	}
+	return es.ServerConfig.EasyServer.Limits
+}
+
+func (es *EasyServer) jailed() bool {
+	limits := es.limits()
+	return limits != nil && limits.Jail
+}
+
+// checkJailEntry 目录限制下，入口为路径时必须在模型目录内；只写命令名（如 python）时按 PATH 查找，虚拟环境优先
+func (es *EasyServer) checkJailEntry(command []string) error {
+	if !es.jailed() || len(command) == 0 {
+		return nil
+	}
+	entry := command[0]
+	if !strings.ContainsAny(entry, `/\`) {
+		return nil
+	}
+	if !filepath.IsAbs(entry) {
+		entry = filepath.Join(es.ServerInfo.LocalPath, entry)
+	}
+	if resolved, err := filepath.EvalSymlinks(entry); err == nil {
+		entry = resolved
+	}
+	if roots := es.jailRoots(); len(roots) > 0 && pathWithin(entry, roots[0]) {
+		return nil
+	}
+	return &CallError{Kind: ErrorKindLimit, Msg: fmt.Sprintf("模型入口 %s 不在模型目录内", command[0])}
+}
+
+// checkJailResult 目录限制下，模型返回的文件路径必须在模型目录或数据目录内
+func (es *EasyServer) checkJailResult(result map[string]interface{}) error {
+	if !es.jailed() {
+		return nil
+	}
+	roots := es.jailRoots()
+	var check func(v interface{}) error
+	check = func(v interface{}) error {
+		switch value := v.(type) {
+		case string:
+			if !filepath.IsAbs(value) {
+				return nil
+			}
+			resolved, err := filepath.EvalSymlinks(value)
+			if err != nil {
+				// 不是存在的文件，不当作路径处理
+				return nil
+			}
+			for _, root := range roots {
+				if pathWithin(resolved, root) {
+					return nil
+				}
+			}
+			return &CallError{Kind: ErrorKindLimit, Msg: fmt.Sprintf("模型结果文件不在允许的目录中: %s", value)}
+		case []interface{}:
+			for _, item := range value {
+				if err := check(item); err != nil {
+					return err
+				}
+			}
+		case map[string]interface{}:
+			for _, item := range value {
+				if err := check(item); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return check(result)
+}
+
+// sandbox 单个模型进程上生效的资源限制
+// prepare 在启动前调用，attach 在启动后立即调用，进程退出后用 violation 判断是否因超出限制被结束，
+// 最后（包括启动失败时）调用 release
+type sandbox struct {
+	name   string
+	limits *EasyServerLimits
+	pid    int
+	// 平台相关状态
+	cgroupDir    string
+	cgroupFile   *os.File // exec 前进入 cgroup 时使用的目录句柄，启动后关闭
+	cgroupErr    error    // prepare 中创建 cgroup 失败的原因
+	memoryRlimit bool
+}
+
+func (es *EasyServer) newSandbox() *sandbox {
+	return &sandbox{name: es.ServerConfig.Name, limits: es.limits()}
+}
+
+// violationError 进程退出后检查是否超出限制，超出时返回 limit 类错误
+func (sb *sandbox) violationError(waitErr error) error {
+	if msg := sb.violation(waitErr); msg != "" {
+		return &CallError{Kind: ErrorKindLimit, Msg: msg}
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+//go:build linux
+
+package easyserver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/utils"
+
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	defaultCgroupDir = "/sys/fs/cgroup/aigcpanel"
+	cgroupCPUPeriod  = 100000
+)
+
+// cgroupParent 模型进程 cgroup 的父目录，需要 cgroup v2 且服务有写权限（root 或委派给服务用户）
+func cgroupParent() string {
+	dir := utils.GetEnv("AIGCPANEL_CGROUP_DIR", defaultCgroupDir)
+	// 只支持 cgroup v2
+	if _, err := os.Stat("/sys/fs/cgroup/cgroup.controllers"); err != nil {
+		return ""
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return ""
+	}
+	_ = os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+memory +cpu"), 0644)
+	return dir
+}
+
+var (
+	cgroupExecOnce      sync.Once
+	cgroupExecSupported bool
+)
+
+// cloneIntoCgroupSupported 能否在 exec 前把子进程放入 cgroup（clone3 + CLONE_INTO_CGROUP，内核 5.7+）
+// 容器的 seccomp 配置可能让 clone3 返回 ENOSYS，第一次使用时用 true 命令在 dir 中实际启动一次判断
+func cloneIntoCgroupSupported(dir string) bool {
+	cgroupExecOnce.Do(func() {
+		bin, err := exec.LookPath("true")
+		if err != nil {
+			return
+		}
+		f, err := os.Open(dir)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		probe := exec.Command(bin)
+		probe.SysProcAttr = &syscall.SysProcAttr{UseCgroupFD: true, CgroupFD: int(f.Fd())}
+		if err := probe.Run(); err != nil {
+			log.Warn("Start into cgroup unsupported, model is moved into its cgroup after start", zap.Error(err))
+			return
+		}
+		cgroupExecSupported = true
+	})
+	return cgroupExecSupported
+}
+
+// prepare 内存/CPU 限制：启动前创建 cgroup，支持时子进程在 exec 前就进入 cgroup，模型从第一条指令起受限制；
+// 不支持时由 attach 在启动后加入
+func (sb *sandbox) prepare(cmd *exec.Cmd) {
+	l := sb.limits
+	if l == nil || (l.MaxMemoryMB <= 0 && l.MaxCPUs <= 0) {
+		return
+	}
+	dir, err := sb.createCgroup()
+	if err != nil {
+		sb.cgroupErr = err
+		return
+	}
+	sb.cgroupDir = dir
+	if !cloneIntoCgroupSupported(dir) {
+		return
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(f.Fd())
+	sb.cgroupFile = f
+}
+
+// attach rlimit 和 nice 只能在进程启动后设置，启动到设置之间（通常不到 1ms）已经 fork 的子进程不受 rlimit 限制
+func (sb *sandbox) attach(pid int) error {
+	sb.pid = pid
+	l := sb.limits
+	if !l.Enabled() {
+		return nil
+	}
+
+	if l.MaxMemoryMB > 0 || l.MaxCPUs > 0 {
+		if err := sb.attachCgroup(pid); err != nil {
+			// CPU 核数没有 rlimit 可以退回，不能不加限制地运行
+			if l.MaxCPUs > 0 {
+				return fmt.Errorf("maxCpus 需要 cgroup v2，当前不可用: %v", err)
+			}
+			log.Warn("Model cgroup unavailable, falling back to rlimit", zap.String("name", sb.name), zap.Error(err))
+			if l.MaxMemoryMB > 0 {
+				limit := uint64(l.MaxMemoryMB) * 1024 * 1024
+				if err := unix.Prlimit(pid, unix.RLIMIT_AS, &unix.Rlimit{Cur: limit, Max: limit}, nil); err != nil {
+					return fmt.Errorf("设置内存限制失败: %v", err)
+				}
+				sb.memoryRlimit = true
+			}
+		}
+	}
+	if l.MaxCPUSeconds > 0 {
+		// 超过软限制收到 SIGXCPU，留 5 秒后硬限制 SIGKILL
+		limit := uint64(l.MaxCPUSeconds)
+		if err := unix.Prlimit(pid, unix.RLIMIT_CPU, &unix.Rlimit{Cur: limit, Max: limit + 5}, nil); err != nil {
+			return fmt.Errorf("设置 CPU 时间限制失败: %v", err)
+		}
+	}
+	if l.Nice != 0 {
+		// 模型进程是进程组组长，按进程组设置，已经启动的子进程也生效
+		if err := unix.Setpriority(unix.PRIO_PGRP, pid, l.Nice); err != nil {
+			log.Warn("Set model process priority failed", zap.String("name", sb.name), zap.Int("nice", l.Nice), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// attachCgroup 进程已在 exec 前进入 cgroup 时只关闭句柄，否则把进程写入 prepare 创建的 cgroup
+func (sb *sandbox) attachCgroup(pid int) error {
+	if sb.cgroupFile != nil {
+		_ = sb.cgroupFile.Close()
+		sb.cgroupFile = nil
+		return nil
+	}
+	if sb.cgroupDir == "" {
+		if sb.cgroupErr != nil {
+			return sb.cgroupErr
+		}
+		return errors.New("cgroup v2 not available")
+	}
+	if err := os.WriteFile(filepath.Join(sb.cgroupDir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+		_ = os.Remove(sb.cgroupDir)
+		sb.cgroupDir = ""
+		return err
+	}
+	return nil
+}
+
+// createCgroup 创建模型进程的 cgroup 并写入内存/CPU 限制
+func (sb *sandbox) createCgroup() (string, error) {
+	parent := cgroupParent()
+	if parent == "" {
+		return "", errors.New("cgroup v2 not available")
+	}
+	dir := filepath.Join(parent, fmt.Sprintf("%s-%d", sanitizeCgroupName(sb.name), time.Now().UnixNano()))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", err
+	}
+	write := func(file, value string) error {
+		return os.WriteFile(filepath.Join(dir, file), []byte(value), 0644)
+	}
+	l := sb.limits
+	if l.MaxMemoryMB > 0 {
+		if err := write("memory.max", strconv.FormatInt(int64(l.MaxMemoryMB)*1024*1024, 10)); err != nil {
+			_ = os.Remove(dir)
+			return "", err
+		}
+		// 不允许用 swap 绕过内存限制
+		_ = write("memory.swap.max", "0")
+	}
+	if l.MaxCPUs > 0 {
+		quota := int64(l.MaxCPUs * cgroupCPUPeriod)
+		if err := write("cpu.max", fmt.Sprintf("%d %d", quota, cgroupCPUPeriod)); err != nil {
+			_ = os.Remove(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+func sanitizeCgroupName(name string) string {
+	if name == "" {
+		return "model"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == ' ' || r == '.' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// violation 进程退出后判断是否因超出限制被结束，返回说明
+func (sb *sandbox) violation(waitErr error) string {
+	l := sb.limits
+	if l == nil {
+		return ""
+	}
+	if sb.cgroupDir != "" && l.MaxMemoryMB > 0 {
+		if raw, err := os.ReadFile(filepath.Join(sb.cgroupDir, "memory.events")); err == nil {
+			for _, line := range strings.Split(string(raw), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) == 2 && fields[0] == "oom_kill" && fields[1] != "0" {
+					return fmt.Sprintf("模型内存超出限制 %d MB，进程被系统结束", l.MaxMemoryMB)
+				}
+			}
+		}
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(waitErr, &exitErr) {
+		return ""
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok {
+		return ""
+	}
+	if l.MaxCPUSeconds > 0 && status.Signaled() && (status.Signal() == syscall.SIGXCPU || status.Signal() == syscall.SIGKILL) {
+		if rusage, ok := exitErr.SysUsage().(*syscall.Rusage); ok {
+			used := int64(rusage.Utime.Sec) + int64(rusage.Stime.Sec)
+			if used+1 >= int64(l.MaxCPUSeconds) {
+				return fmt.Sprintf("模型 CPU 时间超出限制 %d 秒", l.MaxCPUSeconds)
+			}
+		} else if status.Signal() == syscall.SIGXCPU {
+			return fmt.Sprintf("模型 CPU 时间超出限制 %d 秒", l.MaxCPUSeconds)
+		}
+	}
+	// RLIMIT_AS 下申请内存失败通常表现为异常退出，无法准确判断，给出提示
+	if sb.memoryRlimit && (status.Signaled() || status.ExitStatus() != 0) {
+		return fmt.Sprintf("模型异常退出，可能超出内存限制 %d MB", l.MaxMemoryMB)
+	}
+	return ""
+}
+
+// release 删除进程的 cgroup，进程组已经全部退出后调用
+func (sb *sandbox) release() {
+	if sb.cgroupFile != nil {
+		_ = sb.cgroupFile.Close()
+		sb.cgroupFile = nil
+	}
+	if sb.cgroupDir == "" {
+		return
+	}
+	// 被结束的子进程可能还没有完全退出，cgroup 非空时无法删除
+	var err error
+	for i := 0; i < 20; i++ {
+		if err = os.Remove(sb.cgroupDir); err == nil || os.IsNotExist(err) {
+			sb.cgroupDir = ""
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		log.Warn("Remove model cgroup failed", zap.String("dir", sb.cgroupDir), zap.Error(err))
+	}
+	sb.cgroupDir = ""
+}
@@ -0,0 +1,71 @@
+//go:build linux
+
+package easyserver
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// exitError 运行 sh 脚本，返回 Wait 的错误
+func exitError(t *testing.T, script string) error {
+	t.Helper()
+	err := exec.Command("sh", "-c", script).Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected exit error, got %v", err)
+	}
+	return err
+}
+
+func TestSandboxViolation(t *testing.T) {
+	memoryEvents := t.TempDir()
+	if err := os.WriteFile(filepath.Join(memoryEvents, "memory.events"), []byte("low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	noOOM := t.TempDir()
+	if err := os.WriteFile(filepath.Join(noOOM, "memory.events"), []byte("oom 0\noom_kill 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		sandbox sandbox
+		waitErr error
+		want    string
+	}{
+		{"no limits", sandbox{}, exitError(t, "exit 1"), ""},
+		{"cgroup oom", sandbox{limits: &EasyServerLimits{MaxMemoryMB: 64}, cgroupDir: memoryEvents}, nil, "模型内存超出限制 64 MB"},
+		{"cgroup no oom", sandbox{limits: &EasyServerLimits{MaxMemoryMB: 64}, cgroupDir: noOOM}, exitError(t, "exit 1"), ""},
+		{"cpu seconds", sandbox{limits: &EasyServerLimits{MaxCPUSeconds: 1}}, exitError(t, "kill -XCPU $$"), "模型 CPU 时间超出限制 1 秒"},
+		{"killed below cpu limit", sandbox{limits: &EasyServerLimits{MaxCPUSeconds: 3600}}, exitError(t, "kill -KILL $$"), ""},
+		{"rlimit memory", sandbox{limits: &EasyServerLimits{MaxMemoryMB: 64}, memoryRlimit: true}, exitError(t, "exit 3"), "可能超出内存限制 64 MB"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.sandbox.violationError(c.waitErr)
+			if c.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected violation %v", err)
+				}
+				return
+			}
+			if !isLimitError(err) || !strings.Contains(err.Error(), c.want) {
+				t.Fatalf("expected limit error %q, got %v", c.want, err)
+			}
+		})
+	}
+}
+
+func TestSandboxMaxCpusWithoutCgroup(t *testing.T) {
+	// cgroup 不可用时 maxCpus 不能被忽略
+	sb := &sandbox{name: "demo", limits: &EasyServerLimits{MaxCPUs: 1}, cgroupErr: errors.New("cgroup v2 not available")}
+	err := sb.attach(os.Getpid())
+	if err == nil || !strings.Contains(err.Error(), "maxCpus") {
+		t.Fatalf("expected maxCpus error, got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !linux && !windows
+
+package easyserver
+
+import (
+	"os/exec"
+	"syscall"
+	"xiacutai-server/internal/component/log"
+
+	"go.uber.org/zap"
+)
+
+func (sb *sandbox) prepare(cmd *exec.Cmd) {}
+
+// attach 其它类 Unix 系统只支持 nice
+func (sb *sandbox) attach(pid int) error {
+	sb.pid = pid
+	l := sb.limits
+	if !l.Enabled() {
+		return nil
+	}
+	if l.MaxMemoryMB > 0 || l.MaxCPUs > 0 || l.MaxCPUSeconds > 0 {
+		log.Warn("Model memory/cpu limits are only supported on Linux, ignored", zap.String("name", sb.name))
+	}
+	if l.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PGRP, pid, l.Nice); err != nil {
+			log.Warn("Set model process priority failed", zap.String("name", sb.name), zap.Int("nice", l.Nice), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (sb *sandbox) violation(waitErr error) string {
+	return ""
+}
+
+func (sb *sandbox) release() {}
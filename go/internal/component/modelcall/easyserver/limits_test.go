@@ -0,0 +1,168 @@
+package easyserver
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"xiacutai-server/internal/utils"
+)
+
+func TestFilterEnviron(t *testing.T) {
+	environ := []string{
+		"PATH=/usr/bin", "Home=/home/u", "LC_ALL=C", "CUDA_VISIBLE_DEVICES=0", "cuda_home=/cuda",
+		"HF_ENDPOINT=https://hf", "HF_TOKEN=secret", "AIGCPANEL_WEBHOOK_SECRET=secret", "BROKEN",
+	}
+	got := filterEnviron(environ, []string{"CUDA_*", "hf_endpoint"})
+	sort.Strings(got)
+	want := []string{"CUDA_VISIBLE_DEVICES=0", "HF_ENDPOINT=https://hf", "Home=/home/u", "LC_ALL=C", "PATH=/usr/bin", "cuda_home=/cuda"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// buildModelEnv 只在配置了名单时过滤
+	cfg := &EasyServerConfig{Limits: &EasyServerLimits{EnvAllowlist: []string{"HF_ENDPOINT"}}}
+	env := buildModelEnv("linux", environ, "/m", cfg)
+	if _, ok := env["HF_TOKEN"]; ok {
+		t.Fatal("HF_TOKEN should be filtered")
+	}
+	if env["HF_ENDPOINT"] != "https://hf" {
+		t.Fatalf("HF_ENDPOINT missing: %v", env)
+	}
+	env = buildModelEnv("linux", environ, "/m", &EasyServerConfig{Limits: &EasyServerLimits{}})
+	if env["HF_TOKEN"] != "secret" {
+		t.Fatal("empty allowlist should inherit everything")
+	}
+}
+
+// jailServer 模型目录 root、数据目录 data 和两者之外的 outside 目录
+func jailServer(t *testing.T, jail bool) (es *EasyServer, root, data, outside string) {
+	t.Helper()
+	base := t.TempDir()
+	if resolved, err := filepath.EvalSymlinks(base); err == nil {
+		base = resolved
+	}
+	root, data, outside = filepath.Join(base, "model"), filepath.Join(base, "data"), filepath.Join(base, "outside")
+	for _, dir := range []string{root, data, outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldDataDir := utils.DataDir
+	utils.DataDir = data
+	t.Cleanup(func() { utils.DataDir = oldDataDir })
+	es = &EasyServer{
+		ServerInfo:   &ServerInfo{LocalPath: root},
+		ServerConfig: ServerConfig{EasyServer: &EasyServerConfig{Limits: &EasyServerLimits{Jail: jail}}},
+	}
+	return es, root, data, outside
+}
+
+func writeFile(t *testing.T, path string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func symlink(t *testing.T, target, link string) string {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlink unsupported: %v", err)
+	}
+	return link
+}
+
+func isLimitError(err error) bool {
+	var callErr *CallError
+	return errors.As(err, &callErr) && callErr.Kind == ErrorKindLimit
+}
+
+func TestCheckJailEntry(t *testing.T) {
+	es, root, data, outside := jailServer(t, true)
+	writeFile(t, filepath.Join(root, "run.sh"))
+	outsideEntry := writeFile(t, filepath.Join(outside, "run.sh"))
+	link := symlink(t, outsideEntry, filepath.Join(root, "link.sh"))
+
+	cases := []struct {
+		entry string
+		ok    bool
+	}{
+		{"python", true},
+		{"run.sh", true},
+		{filepath.Join("scripts", "missing.sh"), true},
+		{filepath.Join(root, "run.sh"), true},
+		{filepath.Join("..", "outside", "run.sh"), false},
+		{filepath.Join(root, "..", "outside", "run.sh"), false},
+		{outsideEntry, false},
+		{link, false},
+		// 数据目录允许读写，但入口必须在模型目录内
+		{writeFile(t, filepath.Join(data, "run.sh")), false},
+	}
+	for _, c := range cases {
+		err := es.checkJailEntry([]string{c.entry, "--flag"})
+		if c.ok && err != nil {
+			t.Errorf("%s: unexpected error %v", c.entry, err)
+		}
+		if !c.ok && !isLimitError(err) {
+			t.Errorf("%s: expected limit error, got %v", c.entry, err)
+		}
+	}
+
+	es.ServerConfig.EasyServer.Limits.Jail = false
+	if err := es.checkJailEntry([]string{outsideEntry}); err != nil {
+		t.Fatalf("entry is not checked without jail: %v", err)
+	}
+}
+
+func TestCheckJailResult(t *testing.T) {
+	es, root, data, outside := jailServer(t, true)
+	inRoot := writeFile(t, filepath.Join(root, "_output", "a.wav"))
+	inData := writeFile(t, filepath.Join(data, "storage", "b.wav"))
+	outsideFile := writeFile(t, filepath.Join(outside, "c.wav"))
+	link := symlink(t, outsideFile, filepath.Join(root, "_output", "link.wav"))
+	writeFile(t, filepath.Join(root, "_output", "sub", "d.wav"))
+
+	ok := map[string]interface{}{
+		"url":  inRoot,
+		"urls": []interface{}{inData, "relative/path.wav", filepath.Join(outside, "missing.wav")},
+		"meta": map[string]interface{}{"text": "你好", "duration": 1.5},
+	}
+	if err := es.checkJailResult(ok); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for name, result := range map[string]map[string]interface{}{
+		"outside": {"url": outsideFile},
+		"symlink": {"urls": []interface{}{inRoot, link}},
+		"dotdot":  {"records": []interface{}{map[string]interface{}{"url": filepath.Join(root, "_output", "sub", "..", "..", "..", "outside", "c.wav")}}},
+	} {
+		if err := es.checkJailResult(result); !isLimitError(err) {
+			t.Errorf("%s: expected limit error, got %v", name, err)
+		}
+	}
+
+	es.ServerConfig.EasyServer.Limits.Jail = false
+	if err := es.checkJailResult(map[string]interface{}{"url": outsideFile}); err != nil {
+		t.Fatalf("result is not checked without jail: %v", err)
+	}
+}
+
+func TestJailEnv(t *testing.T) {
+	root := t.TempDir()
+	env := buildModelEnv("linux", []string{"HOME=/home/u", "TMPDIR=/tmp"}, root, &EasyServerConfig{Limits: &EasyServerLimits{Jail: true}})
+	home, tmp := jailDirs(root)
+	if env["HOME"] != home || env["TMPDIR"] != tmp || env["TEMP"] != tmp || env["XDG_CACHE_HOME"] != filepath.Join(home, ".cache") {
+		t.Fatalf("unexpected jail env %v", env)
+	}
+	for _, dir := range []string{home, tmp} {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			t.Fatalf("jail dir %s not created: %v", dir, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+//go:build windows
+
+package easyserver
+
+import (
+	"os/exec"
+	"syscall"
+	"xiacutai-server/internal/component/log"
+
+	"go.uber.org/zap"
+	"golang.org/x/sys/windows"
+)
+
+// prepare Windows 上通过进程优先级类实现 nice，子进程默认继承
+func (sb *sandbox) prepare(cmd *exec.Cmd) {
+	l := sb.limits
+	if l == nil || l.Nice <= 0 {
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	if l.Nice >= 15 {
+		cmd.SysProcAttr.CreationFlags |= windows.IDLE_PRIORITY_CLASS
+	} else {
+		cmd.SysProcAttr.CreationFlags |= windows.BELOW_NORMAL_PRIORITY_CLASS
+	}
+}
+
+func (sb *sandbox) attach(pid int) error {
+	sb.pid = pid
+	l := sb.limits
+	if l != nil && (l.MaxMemoryMB > 0 || l.MaxCPUs > 0 || l.MaxCPUSeconds > 0) {
+		log.Warn("Model memory/cpu limits are only supported on Linux, ignored", zap.String("name", sb.name))
+	}
+	return nil
+}
+
+func (sb *sandbox) violation(waitErr error) string {
+	return ""
+}
+
+func (sb *sandbox) release() {}
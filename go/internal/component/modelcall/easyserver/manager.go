@@ -0,0 +1,345 @@
+package easyserver
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"xiacutai-server/internal/component/errs"
+)
+
+// ServerManager 模型运行时：按 key 管理已注册模型的启动、停止、探活、状态和功能调用
+// 一次性模型每次调用启动一个进程，Start 只检查入口；常驻模型（resident / http）Start 时拉起常驻进程，直到 Stop
+type ServerManager struct {
+	mu      sync.Mutex
+	servers map[string]*managedServer
+}
+
+type managedServer struct {
+	info      ServerInfo
+	status    ServerStatus
+	startTime int64
+	lastError string
+	calls     map[*EasyServer]struct{} // 进行中的调用，Stop 时取消
+}
+
+// ServerState 模型运行状态
+type ServerState struct {
+	Key       string          `json:"key"`
+	Name      string          `json:"name"`
+	Version   string          `json:"version"`
+	Status    ServerStatus    `json:"status"`
+	StartTime int64           `json:"startTime"`
+	Calls     int             `json:"calls"`           // 进行中的调用数
+	Error     string          `json:"error,omitempty"` // 启动失败或常驻进程退出的原因
+	Resident  *ResidentStatus `json:"resident,omitempty"`
+}
+
+// Servers 服务内共用的模型运行时
+var Servers = NewServerManager()
+
+func NewServerManager() *ServerManager {
+	return &ServerManager{servers: make(map[string]*managedServer)}
+}
+
+// Register 注册或更新模型，已注册的保留运行状态
+func (sm *ServerManager) Register(key string, info ServerInfo) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if m, ok := sm.servers[key]; ok {
+		m.info = info
+		return
+	}
+	sm.servers[key] = &managedServer{info: info, status: ServerStopped, calls: make(map[*EasyServer]struct{})}
+}
+
+// Unregister 停止并移除模型
+func (sm *ServerManager) Unregister(key string) {
+	_ = sm.Stop(key)
+	sm.mu.Lock()
+	delete(sm.servers, key)
+	sm.mu.Unlock()
+}
+
+func (sm *ServerManager) get(key string) (*managedServer, error) {
+	m, ok := sm.servers[key]
+	if !ok {
+		return nil, errs.New("模型未注册: " + key)
+	}
+	return m, nil
+}
+
+func (m *managedServer) newServer() *EasyServer {
+	info := m.info
+	es := NewEasyServer(info.Config)
+	es.ServerInfo = &info
+	_ = es.Start()
+	return es
+}
+
+func (m *managedServer) persistent() bool {
+	return m.info.Config.EasyServer.Persistent()
+}
+
+// Start 启动模型，已在运行时直接返回
+func (sm *ServerManager) Start(key string) error {
+	return sm.start(key, true)
+}
+
+// Preload 预先拉起常驻进程，与第一次调用时启动的进程一样空闲 idleShutdown 后退出
+// 启动中状态为 starting，失败为 error，就绪后回到 stopped（常驻进程信息见 resident）
+func (sm *ServerManager) Preload(key string) error {
+	return sm.start(key, false)
+}
+
+// start keepAlive 为 true 时是手动启动：常驻进程不做空闲退出，就绪后状态为 running
+func (sm *ServerManager) start(key string, keepAlive bool) error {
+	sm.mu.Lock()
+	m, err := sm.get(key)
+	if err != nil {
+		sm.mu.Unlock()
+		return err
+	}
+	if m.status == ServerRunning && sm.residentAlive(m) {
+		sm.mu.Unlock()
+		return nil
+	}
+	if m.status == ServerStarting {
+		sm.mu.Unlock()
+		return errs.New("模型正在启动")
+	}
+	m.status = ServerStarting
+	m.lastError = ""
+	es := m.newServer()
+	sm.mu.Unlock()
+
+	if es.ServerConfig.EasyServer == nil {
+		err = errs.New("模型配置缺少 easyServer")
+	} else if _, err = ResolveEntry(es.ServerInfo.LocalPath, es.ServerConfig.EasyServer.Entry); err == nil && m.persistent() {
+		// 等到可以接收任务（http 协议 /health 就绪）再返回
+		var rs *ResidentServer
+		if rs, err = StartResident(es, keepAlive); err == nil {
+			select {
+			case <-rs.ready:
+			case <-rs.exited:
+				if err = rs.exitError(); err == nil {
+					err = errs.New("常驻进程启动后退出")
+				}
+			}
+		}
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if err != nil {
+		m.status = ServerError
+		m.lastError = err.Error()
+		return err
+	}
+	if !keepAlive {
+		m.status = ServerStopped
+		return nil
+	}
+	m.status = ServerRunning
+	m.startTime = time.Now().Unix()
+	return nil
+}
+
+// Stop 停止模型：取消进行中的调用，结束常驻进程
+func (sm *ServerManager) Stop(key string) error {
+	sm.mu.Lock()
+	m, err := sm.get(key)
+	if err != nil {
+		sm.mu.Unlock()
+		return err
+	}
+	m.status = ServerStopping
+	calls := make([]*EasyServer, 0, len(m.calls))
+	for es := range m.calls {
+		calls = append(calls, es)
+	}
+	persistent, root := m.persistent(), m.info.LocalPath
+	sm.mu.Unlock()
+
+	for _, es := range calls {
+		_ = es.Cancel()
+	}
+	if persistent {
+		StopResident(root)
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	m.status = ServerStopped
+	m.startTime = 0
+	return nil
+}
+
+// Ping 模型是否可以接收调用：常驻进程存活，http 协议的模型 /health 返回 200
+func (sm *ServerManager) Ping(key string) (bool, error) {
+	sm.mu.Lock()
+	m, err := sm.get(key)
+	if err != nil {
+		sm.mu.Unlock()
+		return false, err
+	}
+	running, persistent, root := m.status == ServerRunning, m.persistent(), m.info.LocalPath
+	sm.mu.Unlock()
+
+	if !running {
+		return false, nil
+	}
+	if !persistent {
+		return true, nil
+	}
+	rs := residentByRoot(root)
+	if rs == nil || !rs.alive() {
+		return false, nil
+	}
+	if rs.transport == TransportHTTP {
+		code, err := rs.httpDo(http.MethodGet, "/health", nil, nil)
+		return code == http.StatusOK, err
+	}
+	return true, nil
+}
+
+// Status 模型运行状态，常驻进程意外退出时为 error
+func (sm *ServerManager) Status(key string) (ServerState, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	m, err := sm.get(key)
+	if err != nil {
+		return ServerState{}, err
+	}
+	return sm.state(key, m), nil
+}
+
+// List 所有已注册模型的运行状态
+func (sm *ServerManager) List() []ServerState {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	list := make([]ServerState, 0, len(sm.servers))
+	for key, m := range sm.servers {
+		list = append(list, sm.state(key, m))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
+
+func (sm *ServerManager) state(key string, m *managedServer) ServerState {
+	state := ServerState{
+		Key:       key,
+		Name:      m.info.Name,
+		Version:   m.info.Version,
+		Status:    m.status,
+		StartTime: m.startTime,
+		Calls:     len(m.calls),
+		Error:     m.lastError,
+	}
+	if !m.persistent() {
+		return state
+	}
+	if rs := residentByRoot(m.info.LocalPath); rs != nil && rs.alive() {
+		status := rs.Status()
+		state.Resident = &status
+	} else if m.status == ServerRunning {
+		state.Status = ServerError
+		state.Error = "常驻进程已退出"
+		if rs != nil && rs.exitError() != nil {
+			state.Error += ": " + rs.exitError().Error()
+		}
+	}
+	return state
+}
+
+// residentAlive 一次性模型总是返回 true
+func (sm *ServerManager) residentAlive(m *managedServer) bool {
+	if !m.persistent() {
+		return true
+	}
+	rs := residentByRoot(m.info.LocalPath)
+	return rs != nil && rs.alive()
+}
+
+// Acquire 创建一次调用使用的 EasyServer，Stop 模型时会被取消，调用结束后必须 Release
+func (sm *ServerManager) Acquire(key string) (*EasyServer, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	m, err := sm.get(key)
+	if err != nil {
+		return nil, err
+	}
+	es := m.newServer()
+	m.calls[es] = struct{}{}
+	return es, nil
+}
+
+func (sm *ServerManager) Release(key string, es *EasyServer) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if m, ok := sm.servers[key]; ok {
+		delete(m.calls, es)
+	}
+}
+
+// Call 调用已启动模型的一个功能
+func (sm *ServerManager) Call(key string, function ServerFunction, data ServerFunctionDataType) (*TaskResult, error) {
+	state, err := sm.Status(key)
+	if err != nil {
+		return nil, err
+	}
+	if state.Status != ServerRunning {
+		return nil, errs.New(fmt.Sprintf("模型未启动: %s", key))
+	}
+	es, err := sm.Acquire(key)
+	if err != nil {
+		return nil, err
+	}
+	defer sm.Release(key, es)
+	return es.Call(function, data)
+}
+
+func residentByRoot(root string) *ResidentServer {
+	residents.Lock()
+	defer residents.Unlock()
+	return residents.servers[root]
+}
+
+// ResolveEntry 查找模型入口：带路径的按模型目录解析，否则依次查找虚拟环境和系统 PATH
+func ResolveEntry(root, entry string) (string, error) {
+	entry = strings.ReplaceAll(entry, "${ROOT}", root)
+	if entry == "" {
+		return "", errs.New("模型入口为空")
+	}
+	if strings.ContainsAny(entry, `/\`) {
+		if !filepath.IsAbs(entry) {
+			entry = filepath.Join(root, entry)
+		}
+		candidates := []string{entry}
+		if runtime.GOOS == "windows" && filepath.Ext(entry) == "" {
+			candidates = append(candidates, entry+".exe")
+		}
+		for _, path := range candidates {
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+		return "", errs.New("模型入口不存在: " + entry)
+	}
+	for _, venvBin := range []string{filepath.Join(root, "_aienv", "bin"), filepath.Join(root, "_aienv", "Scripts")} {
+		if _, err := os.Stat(filepath.Join(venvBin, entry)); err == nil {
+			return filepath.Join(venvBin, entry), nil
+		}
+	}
+	path, err := exec.LookPath(entry)
+	if err != nil {
+		return "", errs.New("找不到模型入口命令: " + entry)
+	}
+	return path, nil
+}
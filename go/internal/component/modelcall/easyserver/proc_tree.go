@@ -0,0 +1,62 @@
+package easyserver
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const defaultKillGrace = 5 * time.Second
+
+// killGrace 结束模型进程时，发送终止信号后等待进程自行退出的时间
+func killGrace() time.Duration {
+	ms, err := strconv.Atoi(utils.GetEnv("AIGCPANEL_KILL_GRACE_MS", ""))
+	if err != nil || ms < 0 {
+		return defaultKillGrace
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// killProcessTree 结束模型进程及其启动的子进程
+// 先向整个进程组发送终止信号，grace 内没有退出的进程强制结束；exited 在主进程被 Wait 回收后关闭
+// 返回清理说明，没有需要强制结束的进程时为空
+func killProcessTree(pid int, exited <-chan struct{}) string {
+	grace := killGrace()
+	deadline := time.Now().Add(grace)
+	_ = terminateGroup(pid)
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-exited:
+	case <-timer.C:
+		count := groupSize(pid)
+		_ = killGroup(pid)
+		<-exited
+		log.Warn("Model process did not exit in time, killed", zap.Int("pid", pid), zap.Duration("grace", grace), zap.Int("count", count))
+		if count > 1 {
+			return fmt.Sprintf("模型进程 %s 内未退出，已强制结束（含 %d 个子进程）", grace, count-1)
+		}
+		return fmt.Sprintf("模型进程 %s 内未退出，已强制结束", grace)
+	}
+	return cleanupGroup(pid, deadline)
+}
+
+// cleanupGroup 主进程退出后，等到 deadline 仍残留在进程组中的子进程强制结束
+func cleanupGroup(pid int, deadline time.Time) string {
+	leftover := groupSize(pid)
+	for leftover > 0 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		leftover = groupSize(pid)
+	}
+	if leftover <= 0 {
+		return ""
+	}
+	_ = killGroup(pid)
+	log.Warn("Killed leftover model child processes", zap.Int("pid", pid), zap.Int("count", leftover))
+	return fmt.Sprintf("已清理 %d 个残留子进程", leftover)
+}
@@ -0,0 +1,68 @@
+//go:build !windows
+
+package easyserver
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// setProcessGroup 模型进程放到新的会话和进程组中（setsid），结束时可以连同子进程一起结束
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// terminateGroup 向整个进程组发送 SIGTERM
+func terminateGroup(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGTERM)
+}
+
+// killGroup 向整个进程组发送 SIGKILL
+func killGroup(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+// groupSize 进程组中仍在运行的进程数，不含僵尸进程
+func groupSize(pgid int) int {
+	if syscall.Kill(-pgid, 0) != nil {
+		return 0
+	}
+	if entries, err := os.ReadDir("/proc"); err == nil {
+		count := 0
+		for _, entry := range entries {
+			if _, err := strconv.Atoi(entry.Name()); err != nil {
+				continue
+			}
+			raw, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+			if err != nil {
+				continue
+			}
+			// pid (comm) state ppid pgrp ...，comm 中可能有空格，从最后一个 ')' 之后解析
+			stat := string(raw)
+			fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+			if len(fields) < 3 || fields[0] == "Z" {
+				continue
+			}
+			if fields[2] == strconv.Itoa(pgid) {
+				count++
+			}
+		}
+		return count
+	}
+	out, err := exec.Command("ps", "-A", "-o", "pgid=,stat=").Output()
+	if err != nil {
+		return 1
+	}
+	count := 0
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == strconv.Itoa(pgid) && !strings.HasPrefix(fields[1], "Z") {
+			count++
+		}
+	}
+	return count
+}
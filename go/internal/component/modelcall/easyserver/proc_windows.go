@@ -0,0 +1,30 @@
+//go:build windows
+
+package easyserver
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup 模型进程放到新的进程组中
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// terminateGroup 请求结束进程树（taskkill /T），控制台程序可能不响应
+func terminateGroup(pid int) error {
+	return exec.Command("taskkill", "/T", "/PID", strconv.Itoa(pid)).Run()
+}
+
+// killGroup 强制结束进程树（taskkill /T /F）
+func killGroup(pid int) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pid)).Run()
+}
+
+// groupSize Windows 没有进程组，主进程退出后无法再找到它的子进程，返回 0
+// 子进程需要在主进程退出前通过 killGroup 一起结束
+func groupSize(pgid int) int {
+	return 0
+}
@@ -0,0 +1,164 @@
+package easyserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// pidRecord 模型进程记录，服务异常退出后启动时据此清理残留进程
+type pidRecord struct {
+	Pid       int    `json:"pid"`
+	TaskID    string `json:"taskId"`
+	Entry     string `json:"entry"`
+	StartedAt int64  `json:"startedAt"`
+	OwnerPid  int    `json:"ownerPid"` // 启动该进程的服务进程
+	OwnerExe  string `json:"ownerExe"`
+}
+
+func pidFilePath(pid int) string {
+	return filepath.Join(utils.PidDir, fmt.Sprintf("%d.pid.json", pid))
+}
+
+func writePidFile(pid int, taskID string, entry string) {
+	if utils.PidDir == "" {
+		return
+	}
+	exe, _ := os.Executable()
+	data, _ := json.Marshal(pidRecord{
+		Pid:       pid,
+		TaskID:    taskID,
+		Entry:     entry,
+		StartedAt: time.Now().UnixMilli(),
+		OwnerPid:  os.Getpid(),
+		OwnerExe:  exe,
+	})
+	if err := os.WriteFile(pidFilePath(pid), data, 0644); err != nil {
+		log.Warn("Write pid file failed", zap.Int("pid", pid), zap.Error(err))
+	}
+}
+
+func removePidFile(pid int) {
+	if utils.PidDir == "" {
+		return
+	}
+	_ = os.Remove(pidFilePath(pid))
+}
+
+// CleanupOrphanProcesses 结束上次运行残留的模型进程，返回结束的进程数
+// 只在服务启动、尚未执行任何任务时调用
+func CleanupOrphanProcesses() int {
+	if utils.PidDir == "" {
+		return 0
+	}
+	files, err := filepath.Glob(filepath.Join(utils.PidDir, "*.pid.json"))
+	if err != nil {
+		return 0
+	}
+
+	killed := 0
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var record pidRecord
+		if err := json.Unmarshal(raw, &record); err != nil || record.Pid <= 0 {
+			_ = os.Remove(file)
+			continue
+		}
+		// 启动它的服务进程仍在运行（同一数据目录下的其它实例），不是残留进程
+		if record.OwnerPid > 0 && record.OwnerPid != os.Getpid() && processMatches(record.OwnerPid, record.OwnerExe) {
+			continue
+		}
+		_ = os.Remove(file)
+		// pid 可能已被系统复用，命令不匹配时不动
+		if !processMatches(record.Pid, record.Entry) {
+			continue
+		}
+		// 模型进程是进程组的首进程，连同子进程一起结束；旧版本启动的进程不是组长，只结束它本身
+		if err := killGroup(record.Pid); err != nil {
+			process, err := os.FindProcess(record.Pid)
+			if err != nil {
+				continue
+			}
+			if err := process.Kill(); err != nil {
+				log.Warn("Kill orphan process failed", zap.Int("pid", record.Pid), zap.Error(err))
+				continue
+			}
+		}
+		log.Info("Killed orphan model process",
+			zap.Int("pid", record.Pid),
+			zap.String("taskId", record.TaskID),
+			zap.String("entry", record.Entry))
+		killed++
+	}
+	return killed
+}
+
+// processMatches 判断 pid 对应的进程是否仍是当初启动的模型命令
+func processMatches(pid int, entry string) bool {
+	name := strings.ToLower(filepath.Base(entry))
+	name = strings.TrimSuffix(name, ".exe")
+	if name == "" {
+		return false
+	}
+
+	var command string
+	switch runtime.GOOS {
+	case "linux":
+		raw, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cmdline"))
+		if err != nil {
+			return false
+		}
+		command = strings.ReplaceAll(string(raw), "\x00", " ")
+	case "windows":
+		out, err := exec.Command("tasklist", "/FI", fmt.Sprintf("PID eq %d", pid), "/FO", "CSV", "/NH").Output()
+		if err != nil {
+			return false
+		}
+		command = string(out)
+	default:
+		out, err := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "command=").Output()
+		if err != nil {
+			return false
+		}
+		command = string(out)
+	}
+	return strings.Contains(strings.ToLower(command), name)
+}
+
+// staleConfigAge 超过该时间的调用配置文件视为残留，正常调用结束时会自行删除
+const staleConfigAge = time.Hour
+
+// CleanStaleConfigFiles 删除上次运行残留的调用配置文件，返回删除的文件数
+func CleanStaleConfigFiles() int {
+	if utils.JsonDir == "" {
+		return 0
+	}
+	files, err := filepath.Glob(filepath.Join(utils.JsonDir, "easyserver-config-*.json"))
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil || time.Since(info.ModTime()) < staleConfigAge {
+			continue
+		}
+		if err := os.Remove(file); err == nil {
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,89 @@
+package easyserver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Progress 模型上报的执行进度
+// 模型通过中间结果 Result[id][base64(JSON)] 上报，JSON 也可以不做 base64 编码
+type Progress struct {
+	Percent float64                `json:"percent"`           // 0-100
+	Stage   string                 `json:"stage,omitempty"`   // 当前阶段，如 loading / inference / encoding
+	Message string                 `json:"message,omitempty"` // 给用户看的说明
+	Outputs map[string]interface{} `json:"outputs,omitempty"` // 已产出的部分结果（如已生成的分段）
+}
+
+// ParseProgress 解析中间结果的内容，无法识别时返回 false
+// 兼容 {"percent"/"progress", "stage", "message"/"msg", "outputs"} 以及只有一个数字的写法
+func ParseProgress(payload string) (*Progress, bool) {
+	payload = strings.TrimSpace(payload)
+	if payload == "" {
+		return nil, false
+	}
+	raw := []byte(payload)
+	if decoded, err := base64.StdEncoding.DecodeString(payload); err == nil {
+		raw = decoded
+	}
+
+	if percent, err := strconv.ParseFloat(strings.TrimSpace(string(raw)), 64); err == nil {
+		return &Progress{Percent: clampPercent(percent)}, true
+	}
+
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, false
+	}
+	return progressFromMap(data)
+}
+
+// progressFromMap 从 JSON 对象中取出进度字段
+func progressFromMap(data map[string]interface{}) (*Progress, bool) {
+	if len(data) == 0 {
+		return nil, false
+	}
+	p := &Progress{}
+	found := false
+	for _, key := range []string{"percent", "progress"} {
+		if v, ok := data[key].(float64); ok {
+			p.Percent = clampPercent(v)
+			found = true
+			break
+		}
+	}
+	if v, ok := data["stage"].(string); ok {
+		p.Stage = v
+		found = true
+	}
+	for _, key := range []string{"message", "msg"} {
+		if v, ok := data[key].(string); ok {
+			p.Message = v
+			found = true
+			break
+		}
+	}
+	if v, ok := data["outputs"].(map[string]interface{}); ok {
+		p.Outputs = v
+		found = true
+	}
+	return p, found
+}
+
+func clampPercent(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 100 {
+		return 100
+	}
+	return v
+}
+
+// reportProgress 把进度交给调用方设置的回调
+func (es *EasyServer) reportProgress(p *Progress) {
+	if p != nil && es.OnProgress != nil {
+		es.OnProgress(*p)
+	}
+}
@@ -0,0 +1,528 @@
+package easyserver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"xiacutai-server/internal/component/errs"
+	"xiacutai-server/internal/component/log"
+
+	"go.uber.org/zap"
+)
+
+// DefaultResidentIdleShutdown 常驻进程未配置 idleShutdown 时的空闲退出时间
+const DefaultResidentIdleShutdown = 10 * time.Minute
+
+// residentTaskID 常驻进程自身的 ID，写入启动配置和 pid 记录
+const residentTaskID = "resident"
+
+var queueFileSafe = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// isFinalResult 是否是任务的最终结果
+func isFinalResult(result map[string]interface{}) bool {
+	_, hasUrl := result["url"]
+	return hasUrl || result["urls"] != nil || result["records"] != nil || result["error"] != nil
+}
+
+// residentWaiter 等待常驻进程返回某个任务的结果
+type residentWaiter struct {
+	result   map[string]interface{}
+	done     chan struct{}
+	alive    chan struct{}
+	once     sync.Once
+	progress func(*Progress)
+}
+
+// ResidentServer 常驻模型进程
+// stdout 协议：进程启动一次后监听 algorithms/task_queue 目录，任务以 *.queue.json 写入，
+// 所有任务的结果都从同一个进程的日志输出中按任务 ID 分发
+// http 协议：进程启动本地 HTTP 服务，任务通过接口提交、查询状态和获取结果，见 transport_http.go
+type ResidentServer struct {
+	Key       string // 模型目录
+	Name      string
+	Version   string
+	KeepAlive bool // 手动启动（/model/start）的常驻进程不做空闲退出
+	StartTime int64
+
+	mu           sync.Mutex
+	cmd          *exec.Cmd
+	queueDir     string
+	waiters      map[string]*residentWaiter
+	lastUsed     time.Time
+	idleShutdown time.Duration
+	exited       chan struct{}
+	exitErr      error
+
+	transport    string
+	httpBase     string        // http 协议的服务地址
+	pollInterval time.Duration // http 协议查询任务状态的间隔
+	ready        chan struct{} // 进程可以接收任务时关闭
+}
+
+// ResidentStatus 常驻进程状态
+type ResidentStatus struct {
+	Key       string `json:"key"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Pid       int    `json:"pid"`
+	StartTime int64  `json:"startTime"`
+	Running   int    `json:"running"` // 正在执行的任务数
+	KeepAlive bool   `json:"keepAlive"`
+	IdleSince int64  `json:"idleSince"`
+}
+
+var residents = struct {
+	sync.Mutex
+	servers map[string]*ResidentServer
+}{
+	servers: make(map[string]*ResidentServer),
+}
+
+// StartResident 启动模型的常驻进程，已在运行时直接返回
+// keepAlive 为 true 时进程不会因空闲退出
+func StartResident(es *EasyServer, keepAlive bool) (*ResidentServer, error) {
+	if es.ServerInfo == nil || es.ServerConfig.EasyServer == nil {
+		return nil, errs.New("server not initialized")
+	}
+	key := es.ServerInfo.LocalPath
+
+	residents.Lock()
+	defer residents.Unlock()
+	if rs, ok := residents.servers[key]; ok && rs.alive() {
+		if keepAlive {
+			rs.mu.Lock()
+			rs.KeepAlive = true
+			rs.mu.Unlock()
+		}
+		return rs, nil
+	}
+
+	idleShutdown := time.Duration(es.ServerConfig.EasyServer.IdleShutdown) * time.Second
+	if idleShutdown <= 0 {
+		idleShutdown = DefaultResidentIdleShutdown
+	}
+	rs := &ResidentServer{
+		Key:          key,
+		Name:         es.ServerInfo.Name,
+		Version:      es.ServerInfo.Version,
+		KeepAlive:    keepAlive,
+		queueDir:     es.queueDir(),
+		waiters:      make(map[string]*residentWaiter),
+		lastUsed:     time.Now(),
+		idleShutdown: idleShutdown,
+		exited:       make(chan struct{}),
+		transport:    es.ServerConfig.EasyServer.Transport,
+		ready:        make(chan struct{}),
+	}
+	if err := rs.start(es); err != nil {
+		return nil, err
+	}
+	residents.servers[key] = rs
+	return rs, nil
+}
+
+// StopResident 停止模型目录对应的常驻进程
+func StopResident(key string) bool {
+	residents.Lock()
+	rs, ok := residents.servers[key]
+	residents.Unlock()
+	if !ok {
+		return false
+	}
+	rs.Stop()
+	return true
+}
+
+// StopAllResidents 停止所有常驻进程，服务退出时调用
+func StopAllResidents() {
+	residents.Lock()
+	servers := make([]*ResidentServer, 0, len(residents.servers))
+	for _, rs := range residents.servers {
+		servers = append(servers, rs)
+	}
+	residents.Unlock()
+	// 并行结束，每个进程最多等待一个 AIGCPANEL_KILL_GRACE_MS
+	var wg sync.WaitGroup
+	for _, rs := range servers {
+		wg.Add(1)
+		go func(rs *ResidentServer) {
+			defer wg.Done()
+			rs.Stop()
+		}(rs)
+	}
+	wg.Wait()
+}
+
+// ResidentServers 当前运行中的常驻进程
+func ResidentServers() []ResidentStatus {
+	residents.Lock()
+	defer residents.Unlock()
+	list := make([]ResidentStatus, 0, len(residents.servers))
+	for _, rs := range residents.servers {
+		list = append(list, rs.Status())
+	}
+	return list
+}
+
+func (rs *ResidentServer) Status() ResidentStatus {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	status := ResidentStatus{
+		Key:       rs.Key,
+		Name:      rs.Name,
+		Version:   rs.Version,
+		StartTime: rs.StartTime,
+		Running:   len(rs.waiters),
+		KeepAlive: rs.KeepAlive,
+	}
+	if rs.cmd != nil && rs.cmd.Process != nil {
+		status.Pid = rs.cmd.Process.Pid
+	}
+	if len(rs.waiters) == 0 {
+		status.IdleSince = rs.lastUsed.UnixMilli()
+	}
+	return status
+}
+
+func (rs *ResidentServer) alive() bool {
+	select {
+	case <-rs.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+func (rs *ResidentServer) start(es *EasyServer) error {
+	if err := os.MkdirAll(rs.queueDir, 0755); err != nil {
+		return err
+	}
+	// 启动配置：stdout 协议为 watch 模式，模型进程从 queueDir 读取任务；http 协议为 http 模式，模型监听 port
+	launchConfig := map[string]interface{}{
+		"id":       residentTaskID,
+		"mode":     "watch",
+		"queueDir": rs.queueDir,
+		"setting":  es.ServerInfo.Setting,
+	}
+	port := 0
+	if rs.transport == TransportHTTP {
+		var err error
+		if port, err = rs.prepareHTTP(es.ServerConfig.EasyServer.HTTP); err != nil {
+			return err
+		}
+		launchConfig["mode"] = "http"
+		launchConfig["port"] = port
+	}
+	configPath, err := es.prepareConfigJson(launchConfig)
+	if err != nil {
+		return errs.New(fmt.Sprintf("failed to prepare config JSON: %v", err))
+	}
+
+	command := replacePort(es.buildCommand(configPath), port)
+	if err := es.checkJailEntry(command); err != nil {
+		_ = os.Remove(configPath)
+		return err
+	}
+	envMap := es.prepareEnvironment()
+	if port > 0 {
+		envMap["AIGCPANEL_SERVER_PORT"] = strconv.Itoa(port)
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = es.ServerInfo.LocalPath
+	cmd.Env = replacePort(es.buildEnv(envMap, configPath), port)
+	setProcessGroup(cmd)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		_ = os.Remove(configPath)
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		_ = os.Remove(configPath)
+		return err
+	}
+	sb := es.newSandbox()
+	sb.prepare(cmd)
+	if err := cmd.Start(); err != nil {
+		sb.release()
+		_ = os.Remove(configPath)
+		return err
+	}
+	rs.cmd = cmd
+	rs.StartTime = time.Now().Unix()
+	pid := cmd.Process.Pid
+	writePidFile(pid, residentTaskID, command[0])
+	if err := sb.attach(pid); err != nil {
+		_ = killGroup(pid)
+		_ = cmd.Wait()
+		removePidFile(pid)
+		_ = os.Remove(configPath)
+		return &CallError{Kind: ErrorKindLimit, Msg: err.Error()}
+	}
+	log.Info("Resident model started", zap.String("name", rs.Name), zap.String("version", rs.Version), zap.Int("pid", pid))
+
+	go rs.read(stdout)
+	go rs.read(stderr)
+	go func() {
+		err := cmd.Wait()
+		cleanupGroup(pid, time.Time{})
+		if violation := sb.violationError(err); violation != nil {
+			err = violation
+		}
+		sb.release()
+		removePidFile(pid)
+		_ = os.Remove(configPath)
+		rs.onExit(err)
+	}()
+	go rs.watchIdle()
+	if rs.transport == TransportHTTP {
+		go rs.waitHTTPReady(es.ServerConfig.EasyServer.HTTP)
+	} else {
+		close(rs.ready)
+	}
+	return nil
+}
+
+// replacePort 替换 ${PORT} 占位符
+func replacePort(items []string, port int) []string {
+	if port <= 0 {
+		return items
+	}
+	for i := range items {
+		items[i] = strings.ReplaceAll(items[i], "${PORT}", strconv.Itoa(port))
+	}
+	return items
+}
+
+func (rs *ResidentServer) read(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	buf := make([]byte, 0, 1024*1024)
+	scanner.Buffer(buf, 8*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fmt.Println(line)
+		// http 协议的结果走接口，日志只做输出
+		if rs.transport != TransportHTTP {
+			rs.dispatch(line)
+		}
+	}
+}
+
+// dispatch 按日志中的任务 ID 把结果交给对应的等待者
+func (rs *ResidentServer) dispatch(line string) {
+	m := reRunResult.FindStringSubmatch(line)
+	if m == nil {
+		m = reMidResult.FindStringSubmatch(line)
+	}
+	if len(m) != 3 {
+		return
+	}
+	taskID := m[1]
+
+	result, ok := ExtractResultFromLogs(taskID, line)
+	if !ok || result == nil {
+		return
+	}
+
+	rs.mu.Lock()
+	w, ok := rs.waiters[taskID]
+	if !ok {
+		rs.mu.Unlock()
+		return
+	}
+	if result["_alive"] == true {
+		rs.mu.Unlock()
+		select {
+		case w.alive <- struct{}{}:
+		default:
+		}
+		progress, _ := result["_progress"].(*Progress)
+		w.progress(progress)
+		return
+	}
+	for k, v := range result {
+		w.result[k] = v
+	}
+	if isFinalResult(result) {
+		w.once.Do(func() { close(w.done) })
+	}
+	rs.mu.Unlock()
+}
+
+// call 把任务交给常驻进程并等待结果
+func (rs *ResidentServer) call(es *EasyServer, configData map[string]interface{}, taskID string, timeout CallTimeout, launcherResult *LauncherResultType) error {
+	if rs.transport == TransportHTTP {
+		return rs.callHTTP(es, configData, taskID, timeout, launcherResult)
+	}
+	return rs.callQueue(es, configData, taskID, timeout, launcherResult)
+}
+
+// callQueue 把任务写入队列目录，从日志中等待结果
+func (rs *ResidentServer) callQueue(es *EasyServer, configData map[string]interface{}, taskID string, timeout CallTimeout, launcherResult *LauncherResultType) error {
+	w, release, err := rs.addWaiter(taskID, launcherResult, es.reportProgress)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	queueFile, err := es.enqueueTask(configData)
+	if err != nil {
+		return err
+	}
+	// 任务还没被模型取走时撤回，已取走的任务结果会被忽略
+	withdraw := func() { _ = os.Remove(queueFile) }
+
+	total := time.NewTimer(timeout.Total)
+	defer total.Stop()
+	var idle *time.Timer
+	var idleC <-chan time.Time
+	if timeout.Idle > 0 {
+		idle = time.NewTimer(timeout.Idle)
+		defer idle.Stop()
+		idleC = idle.C
+	}
+
+	for {
+		select {
+		case <-w.done:
+			now := time.Now().Unix()
+			launcherResult.EndTime = &now
+			return nil
+
+		case <-es.CancelChan:
+			withdraw()
+			return &CallError{Kind: ErrorKindCancelled, Msg: "task cancelled"}
+
+		case <-total.C:
+			withdraw()
+			return &CallError{Kind: ErrorKindTimeout, Msg: fmt.Sprintf("model timeout after %s", timeout.Total)}
+
+		case <-idleC:
+			withdraw()
+			return &CallError{Kind: ErrorKindTimeout, Msg: fmt.Sprintf("model idle timeout, no progress for %s", timeout.Idle)}
+
+		case <-w.alive:
+			if idle != nil {
+				if !idle.Stop() {
+					select {
+					case <-idle.C:
+					default:
+					}
+				}
+				idle.Reset(timeout.Idle)
+			}
+
+		case <-rs.exited:
+			withdraw()
+			return rs.exitError()
+		}
+	}
+}
+
+// addWaiter 登记正在执行的任务，返回的 release 在任务结束时调用
+func (rs *ResidentServer) addWaiter(taskID string, launcherResult *LauncherResultType, progress func(*Progress)) (*residentWaiter, func(), error) {
+	w := &residentWaiter{
+		result:   launcherResult.Result,
+		done:     make(chan struct{}),
+		alive:    make(chan struct{}, 1),
+		progress: progress,
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if _, ok := rs.waiters[taskID]; ok {
+		return nil, nil, errs.New(fmt.Sprintf("task %s already running", taskID))
+	}
+	rs.waiters[taskID] = w
+	rs.lastUsed = time.Now()
+	return w, func() {
+		rs.mu.Lock()
+		delete(rs.waiters, taskID)
+		rs.lastUsed = time.Now()
+		rs.mu.Unlock()
+	}, nil
+}
+
+// Stop 结束常驻进程，正在等待的任务会收到进程退出错误
+func (rs *ResidentServer) Stop() {
+	rs.mu.Lock()
+	cmd := rs.cmd
+	rs.mu.Unlock()
+	if cmd != nil && cmd.Process != nil && rs.alive() {
+		if cleanup := killProcessTree(cmd.Process.Pid, rs.exited); cleanup != "" {
+			log.Warn("Resident model stopped by force", zap.String("name", rs.Name), zap.String("cleanup", cleanup))
+		}
+	}
+}
+
+func (rs *ResidentServer) onExit(err error) {
+	// 等待中的任务都会失败，先清掉没被取走的队列文件，避免下次启动的进程重复执行
+	files, _ := filepath.Glob(filepath.Join(rs.queueDir, "*.queue.json"))
+	for _, file := range files {
+		_ = os.Remove(file)
+	}
+
+	rs.mu.Lock()
+	rs.exitErr = err
+	close(rs.exited)
+	rs.mu.Unlock()
+
+	residents.Lock()
+	if residents.servers[rs.Key] == rs {
+		delete(residents.servers, rs.Key)
+	}
+	residents.Unlock()
+
+	log.Info("Resident model exited", zap.String("name", rs.Name), zap.String("version", rs.Version), zap.Error(err))
+}
+
+// exitError 常驻进程退出后等待中任务的错误，超出资源限制时保留 limit 类别
+func (rs *ResidentServer) exitError() error {
+	kind := ErrorKind(rs.exitErr)
+	if kind != ErrorKindLimit {
+		kind = ErrorKindProcess
+	}
+	return &CallError{Kind: kind, Msg: fmt.Sprintf("resident model exited: %v", rs.exitErr)}
+}
+
+// watchIdle 空闲超过 idleShutdown 且没有任务时退出
+func (rs *ResidentServer) watchIdle() {
+	interval := rs.idleShutdown / 4
+	if interval > 30*time.Second {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rs.exited:
+			return
+		case <-ticker.C:
+		}
+		rs.mu.Lock()
+		idle := !rs.KeepAlive && len(rs.waiters) == 0 && time.Since(rs.lastUsed) >= rs.idleShutdown
+		rs.mu.Unlock()
+		if idle {
+			log.Info("Resident model idle, shutting down", zap.String("name", rs.Name), zap.Duration("idle", rs.idleShutdown))
+			rs.Stop()
+			return
+		}
+	}
+}
+
+// callResident 通过常驻进程执行任务，进程未启动时先启动
+func (es *EasyServer) callResident(configData map[string]interface{}, taskID string, timeout CallTimeout, launcherResult *LauncherResultType) error {
+	rs, err := StartResident(es, false)
+	if err != nil {
+		return err
+	}
+	return rs.call(es, configData, taskID, timeout, launcherResult)
+}
@@ -0,0 +1,75 @@
+package easyserver
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCallTimeout 未配置时单次调用的最长执行时间
+const DefaultCallTimeout = 10 * time.Minute
+
+// CallTimeout 单次调用的超时设置
+type CallTimeout struct {
+	Total time.Duration // 最长执行时间，0 表示未设置
+	Idle  time.Duration // 没有收到中间结果的最长时间，收到中间结果时重新计时，0 表示未设置
+}
+
+// merge 用 other 中设置了的字段覆盖当前值
+func (t CallTimeout) merge(other CallTimeout) CallTimeout {
+	if other.Total > 0 {
+		t.Total = other.Total
+	}
+	if other.Idle > 0 {
+		t.Idle = other.Idle
+	}
+	return t
+}
+
+func secondsTimeout(total, idle int) CallTimeout {
+	return CallTimeout{
+		Total: time.Duration(total) * time.Second,
+		Idle:  time.Duration(idle) * time.Second,
+	}
+}
+
+// resolveTimeout 计算功能 fn 的超时设置
+// 优先级：任务覆盖 > 模型设置（setting.timeout / setting.idleTimeout）> easyServer.functions.<fn> > easyServer > 默认值
+func (es *EasyServer) resolveTimeout(fn string) CallTimeout {
+	timeout := CallTimeout{Total: DefaultCallTimeout}
+
+	if cfg := es.ServerConfig.EasyServer; cfg != nil {
+		timeout = timeout.merge(secondsTimeout(cfg.Timeout, cfg.IdleTimeout))
+		if fnCfg, ok := cfg.Functions[fn]; ok {
+			timeout = timeout.merge(secondsTimeout(fnCfg.Timeout, fnCfg.IdleTimeout))
+		}
+	}
+	if es.ServerInfo != nil {
+		setting := es.ServerInfo.Setting
+		timeout = timeout.merge(secondsTimeout(settingInt(setting["timeout"]), settingInt(setting["idleTimeout"])))
+	}
+	return timeout.merge(es.TimeoutOverride)
+}
+
+// settingInt 模型设置里的数字可能是 number 也可能是字符串
+func settingInt(v interface{}) int {
+	switch val := v.(type) {
+	case int:
+		return val
+	case int64:
+		return int(val)
+	case float64:
+		return int(val)
+	case string:
+		n, _ := strconv.Atoi(strings.TrimSpace(val))
+		return n
+	}
+	return 0
+}
+
+// callFunctionName 从调用配置中取出功能名
+func callFunctionName(configData map[string]interface{}) string {
+	modelConfig, _ := configData["modelConfig"].(map[string]interface{})
+	fn, _ := modelConfig["type"].(string)
+	return fn
+}
@@ -0,0 +1,262 @@
+package easyserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+	"xiacutai-server/internal/component/errs"
+	"xiacutai-server/internal/component/log"
+
+	"go.uber.org/zap"
+)
+
+// 模型结果回传方式
+const (
+	TransportStdout = "stdout" // 模型在日志中输出 XiacutAIRunResult[id][base64]
+	TransportHTTP   = "http"   // 模型启动本地 HTTP 服务
+)
+
+const (
+	defaultHTTPHost           = "127.0.0.1"
+	defaultHTTPStartupTimeout = 120 * time.Second
+	defaultHTTPPollInterval   = time.Second
+	httpRequestTimeout        = 10 * time.Second
+)
+
+// EasyServerHTTPConfig 模型 HTTP 服务配置
+//
+// 模型进程需要提供以下接口：
+//   - GET    /health             服务就绪后返回 200
+//   - POST   /tasks              提交任务，请求体与 stdout 协议的调用配置相同（id/mode/modelConfig/setting）
+//   - GET    /tasks/{id}         查询任务状态，返回 httpTaskStatus
+//   - GET    /tasks/{id}/result  获取结果，返回与 XiacutAIRunResult 中相同的 JSON
+//   - DELETE /tasks/{id}         取消任务
+type EasyServerHTTPConfig struct {
+	Host           string `json:"host"`           // 默认 127.0.0.1
+	Port           int    `json:"port"`           // 0 时自动分配空闲端口，通过 ${PORT} 占位符和 AIGCPANEL_SERVER_PORT 环境变量传给模型
+	StartupTimeout int    `json:"startupTimeout"` // 等待服务就绪的时间（秒），默认 120
+	PollInterval   int    `json:"pollInterval"`   // 查询任务状态的间隔（毫秒），默认 1000
+}
+
+// 模型 HTTP 服务返回的任务状态
+const (
+	httpTaskQueue   = "queue"
+	httpTaskRunning = "running"
+	httpTaskSuccess = "success"
+	httpTaskFail    = "fail"
+)
+
+type httpTaskStatus struct {
+	Status   string                 `json:"status"`
+	Progress map[string]interface{} `json:"progress,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+var httpTransportClient = &http.Client{Timeout: httpRequestTimeout}
+
+// prepareHTTP 确定监听端口和服务地址
+func (rs *ResidentServer) prepareHTTP(cfg *EasyServerHTTPConfig) (int, error) {
+	host, port := defaultHTTPHost, 0
+	rs.pollInterval = defaultHTTPPollInterval
+	if cfg != nil {
+		if cfg.Host != "" {
+			host = cfg.Host
+		}
+		port = cfg.Port
+		if cfg.PollInterval > 0 {
+			rs.pollInterval = time.Duration(cfg.PollInterval) * time.Millisecond
+		}
+	}
+	if port <= 0 {
+		var err error
+		if port, err = freePort(host); err != nil {
+			return 0, errs.New(fmt.Sprintf("failed to allocate port: %v", err))
+		}
+	}
+	rs.httpBase = fmt.Sprintf("http://%s", net.JoinHostPort(host, fmt.Sprint(port)))
+	return port, nil
+}
+
+func freePort(host string) (int, error) {
+	l, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// waitHTTPReady 等待模型 HTTP 服务就绪，超时则结束进程
+func (rs *ResidentServer) waitHTTPReady(cfg *EasyServerHTTPConfig) {
+	startup := defaultHTTPStartupTimeout
+	if cfg != nil && cfg.StartupTimeout > 0 {
+		startup = time.Duration(cfg.StartupTimeout) * time.Second
+	}
+	deadline := time.Now().Add(startup)
+	for time.Now().Before(deadline) {
+		if !rs.alive() {
+			return
+		}
+		if code, _ := rs.httpDo(http.MethodGet, "/health", nil, nil); code == http.StatusOK {
+			close(rs.ready)
+			log.Info("Model HTTP server ready", zap.String("name", rs.Name), zap.String("addr", rs.httpBase))
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	log.Error("Model HTTP server not ready, stopping", zap.String("name", rs.Name), zap.Duration("timeout", startup))
+	rs.Stop()
+}
+
+// httpDo 调用模型 HTTP 接口，out 不为空时解析 JSON 响应
+func (rs *ResidentServer) httpDo(method, path string, body interface{}, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, rs.httpBase+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := httpTransportClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 8*1024*1024))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, errs.New(fmt.Sprintf("%s %s: status %d %s", method, path, resp.StatusCode, bytes.TrimSpace(raw)))
+	}
+	if out != nil && len(raw) > 0 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// callHTTP 提交任务并轮询状态，成功后获取结果
+// 模型返回失败时把错误写入结果的 error 字段，与 stdout 协议一致
+func (rs *ResidentServer) callHTTP(es *EasyServer, configData map[string]interface{}, taskID string, timeout CallTimeout, launcherResult *LauncherResultType) error {
+	_, release, err := rs.addWaiter(taskID, launcherResult, es.reportProgress)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	total := time.NewTimer(timeout.Total)
+	defer total.Stop()
+
+	// 等待服务就绪，计入总时长
+	select {
+	case <-rs.ready:
+	case <-rs.exited:
+		return rs.exitError()
+	case <-es.CancelChan:
+		return &CallError{Kind: ErrorKindCancelled, Msg: "task cancelled"}
+	case <-total.C:
+		return &CallError{Kind: ErrorKindTimeout, Msg: fmt.Sprintf("model timeout after %s", timeout.Total)}
+	}
+
+	if _, err := rs.httpDo(http.MethodPost, "/tasks", configData, nil); err != nil {
+		return &CallError{Kind: ErrorKindProcess, Msg: fmt.Sprintf("submit task failed: %v", err)}
+	}
+	taskPath := "/tasks/" + url.PathEscape(taskID)
+	cancel := func() { _, _ = rs.httpDo(http.MethodDelete, taskPath, nil, nil) }
+
+	var idleC <-chan time.Time
+	var idle *time.Timer
+	if timeout.Idle > 0 {
+		idle = time.NewTimer(timeout.Idle)
+		defer idle.Stop()
+		idleC = idle.C
+	}
+	ticker := time.NewTicker(rs.pollInterval)
+	defer ticker.Stop()
+
+	lastState := ""
+	for {
+		select {
+		case <-es.CancelChan:
+			cancel()
+			return &CallError{Kind: ErrorKindCancelled, Msg: "task cancelled"}
+
+		case <-total.C:
+			cancel()
+			return &CallError{Kind: ErrorKindTimeout, Msg: fmt.Sprintf("model timeout after %s", timeout.Total)}
+
+		case <-idleC:
+			cancel()
+			return &CallError{Kind: ErrorKindTimeout, Msg: fmt.Sprintf("model idle timeout, no progress for %s", timeout.Idle)}
+
+		case <-rs.exited:
+			return rs.exitError()
+
+		case <-ticker.C:
+		}
+
+		var status httpTaskStatus
+		if _, err := rs.httpDo(http.MethodGet, taskPath, nil, &status); err != nil {
+			// 查询失败不立即判失败，进程退出或超时会结束等待
+			log.Warn("Query model task failed", zap.String("id", taskID), zap.Error(err))
+			continue
+		}
+
+		switch status.Status {
+		case httpTaskSuccess:
+			result := map[string]interface{}{}
+			if _, err := rs.httpDo(http.MethodGet, taskPath+"/result", nil, &result); err != nil {
+				return &CallError{Kind: ErrorKindProcess, Msg: fmt.Sprintf("fetch result failed: %v", err)}
+			}
+			for k, v := range result {
+				launcherResult.Result[k] = v
+			}
+			now := time.Now().Unix()
+			launcherResult.EndTime = &now
+			return nil
+
+		case httpTaskFail:
+			msg := status.Error
+			if msg == "" {
+				msg = "model task failed"
+			}
+			launcherResult.Result["error"] = msg
+			now := time.Now().Unix()
+			launcherResult.EndTime = &now
+			return nil
+		}
+
+		// 状态或进度有变化视为模型仍在工作，重置空闲计时并上报进度
+		state, _ := json.Marshal(status)
+		if string(state) != lastState {
+			if idle != nil {
+				if !idle.Stop() {
+					select {
+					case <-idle.C:
+					default:
+					}
+				}
+				idle.Reset(timeout.Idle)
+			}
+			if progress, ok := progressFromMap(status.Progress); ok {
+				es.reportProgress(progress)
+			}
+		}
+		lastState = string(state)
+	}
+}
@@ -5,6 +5,7 @@ package easyserver
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"regexp"
 	"strings"
 	"time"
@@ -25,36 +26,104 @@ const (
 type ServerFunction string
 
 const (
-	FunctionVideoGen   ServerFunction = "videoGen"   // 视频生成功能
-	FunctionSoundTts   ServerFunction = "soundTts"   // 语音合成功能
-	FunctionSoundClone ServerFunction = "soundClone" // 语音克隆功能
-	FunctionAsr        ServerFunction = "asr"        // 语音识别功能
+	FunctionVideoGen     ServerFunction = "videoGen"     // 视频生成功能
+	FunctionSoundTts     ServerFunction = "soundTts"     // 语音合成功能
+	FunctionSoundClone   ServerFunction = "soundClone"   // 语音克隆功能
+	FunctionAsr          ServerFunction = "asr"          // 语音识别功能
+	FunctionLive         ServerFunction = "live"         // 直播功能
+	FunctionImageGen     ServerFunction = "imageGen"     // 图像生成功能
+	FunctionImageEdit    ServerFunction = "imageEdit"    // 图像编辑功能
+	FunctionImageUpscale ServerFunction = "imageUpscale" // 图像放大功能
+)
+
+// ServerType 表示服务器的类型
+type ServerType string
+
+const (
+	ServerLocal    ServerType = "local"    // 本地服务器
+	ServerLocalDir ServerType = "localDir" // 本地目录服务器
+	ServerCloud    ServerType = "cloud"    // 云端服务器
 )
 
 // ServerConfig 表示服务器的配置信息
 type ServerConfig struct {
-	Name          string           `json:"name"`          // 服务器名称
-	Version       string           `json:"version"`       // 服务器版本
-	Title         string           `json:"title"`         // 服务器标题
-	Description   string           `json:"description"`   // 服务器描述
-	PlatformName  string           `json:"platformName"`  // 平台名称
-	PlatformArch  string           `json:"platformArch"`  // 平台架构
-	ServerRequire string           `json:"serverRequire"` // 服务器要求
-	Entry         string           `json:"entry"`         // 入口点
-	Functions     []ServerFunction `json:"functions"`     // 支持的功能列表
-	EasyServer    *struct {
-		Entry     string   `json:"entry"`     // EasyServer 入口点
-		EntryArgs []string `json:"entryArgs"` // EasyServer 入口参数
-		Envs      []string `json:"envs"`      // 环境变量
-		Content   string   `json:"content"`   // 内容
-	} `json:"easyServer,omitempty"` // EasyServer 特定配置
-	Settings []struct {
-		Name        string `json:"name"`        // 设置名称
-		Type        string `json:"type"`        // 设置类型
-		Title       string `json:"title"`       // 设置标题
-		Default     string `json:"default"`     // 默认值
-		Placeholder string `json:"placeholder"` // 占位符
-	} `json:"settings"` // 设置列表
+	Name              string            `json:"name"`                 // 服务器名称
+	Version           string            `json:"version"`              // 服务器版本
+	Title             string            `json:"title"`                // 服务器标题
+	Description       string            `json:"description"`          // 服务器描述
+	DeviceDescription string            `json:"deviceDescription"`    // 设备描述
+	PlatformName      string            `json:"platformName"`         // 平台名称
+	PlatformArch      string            `json:"platformArch"`         // 平台架构
+	ServerRequire     string            `json:"serverRequire"`        // 服务器要求
+	Entry             string            `json:"entry"`                // 入口点
+	Functions         []ServerFunction  `json:"functions"`            // 支持的功能列表
+	EasyServer        *EasyServerConfig `json:"easyServer,omitempty"` // EasyServer 特定配置
+	Settings          []ServerSetting   `json:"settings"`             // 设置列表
+}
+
+// HasFunction 是否声明了该功能
+func (c *ServerConfig) HasFunction(function ServerFunction) bool {
+	for _, f := range c.Functions {
+		if f == function {
+			return true
+		}
+	}
+	return false
+}
+
+// ServerSetting 表示 config.json 中 settings 的一项
+type ServerSetting struct {
+	Name         string                `json:"name"`                   // 设置名称
+	Type         string                `json:"type"`                   // 设置类型
+	Title        string                `json:"title"`                  // 设置标题
+	DefaultValue interface{}           `json:"defaultValue,omitempty"` // 默认值
+	Default      interface{}           `json:"default,omitempty"`      // 旧版本的默认值写法
+	Placeholder  string                `json:"placeholder"`            // 占位符
+	Options      []ServerSettingOption `json:"options,omitempty"`      // 选项列表
+}
+
+// DefaultOrNil 默认值，优先 defaultValue
+func (s ServerSetting) DefaultOrNil() interface{} {
+	if s.DefaultValue != nil {
+		return s.DefaultValue
+	}
+	return s.Default
+}
+
+// ServerSettingOption 表示设置项的一个选项
+type ServerSettingOption struct {
+	Value interface{} `json:"value"` // 选项值
+	Label string      `json:"label"` // 选项标签
+}
+
+// EasyServerConfig 表示 config.json 中的 easyServer 配置
+type EasyServerConfig struct {
+	Entry        string                              `json:"entry"`            // EasyServer 入口点
+	EntryArgs    []string                            `json:"entryArgs"`        // EasyServer 入口参数
+	Envs         []string                            `json:"envs"`             // 环境变量
+	PlatformEnvs map[string][]string                 `json:"platformEnvs"`     // 按系统的环境变量（windows/linux/darwin），在 envs 之后应用
+	Content      string                              `json:"content"`          // 内容
+	Timeout      int                                 `json:"timeout"`          // 单次调用最长执行时间（秒），0 使用默认值
+	IdleTimeout  int                                 `json:"idleTimeout"`      // 没有收到中间结果的最长时间（秒），0 不限制
+	Functions    map[string]EasyServerFunctionConfig `json:"functions"`        // 按功能的配置
+	Resident     bool                                `json:"resident"`         // 常驻模式：进程只启动一次，通过 algorithms/task_queue 目录接收任务
+	IdleShutdown int                                 `json:"idleShutdown"`     // 常驻进程空闲多久后退出（秒），0 使用默认值
+	AutoStart    bool                                `json:"autoStart"`        // 常驻模型随服务启动，默认第一次调用时启动
+	Transport    string                              `json:"transport"`        // 结果回传方式：stdout（默认，解析日志）或 http
+	HTTP         *EasyServerHTTPConfig               `json:"http,omitempty"`   // transport=http 时模型 HTTP 服务的配置
+	Limits       *EasyServerLimits                   `json:"limits,omitempty"` // 资源限制和目录限制
+}
+
+// Persistent 模型进程是否常驻（常驻模式或 HTTP 协议）
+func (c *EasyServerConfig) Persistent() bool {
+	return c != nil && (c.Resident || c.Transport == TransportHTTP)
+}
+
+// EasyServerFunctionConfig 表示 easyServer.functions 中单个功能的配置
+type EasyServerFunctionConfig struct {
+	Content     string `json:"content"`     // 功能说明
+	Timeout     int    `json:"timeout"`     // 覆盖 easyServer.timeout
+	IdleTimeout int    `json:"idleTimeout"` // 覆盖 easyServer.idleTimeout
 }
 
 // ServerInfo 表示服务器的运行时信息
@@ -78,6 +147,8 @@ type ServerFunctionDataType struct {
 	Audio       string                 `json:"audio,omitempty"`       // 音频文件路径
 	PromptAudio string                 `json:"promptAudio,omitempty"` // 提示音频
 	PromptText  string                 `json:"promptText,omitempty"`  // 提示文本
+	Image       string                 `json:"image,omitempty"`       // 输入图片路径
+	Mask        string                 `json:"mask,omitempty"`        // 图片编辑的蒙版路径
 }
 
 // LauncherResultType 表示启动器的结果类型
@@ -101,10 +172,51 @@ type TaskResultData struct {
 	Data  interface{} `json:"data"`
 }
 
+// 模型调用失败的类别
+const (
+	ErrorKindTimeout   = "timeout"   // 模型执行超时
+	ErrorKindCancelled = "cancelled" // 任务被取消
+	ErrorKindProcess   = "process"   // 模型进程启动失败或异常退出
+)
+
+// CallError 模型调用错误，Kind 标明失败类别，调度器据此决定是否重试
+type CallError struct {
+	Kind    string
+	Msg     string
+	Cleanup string // 结束进程树时的清理说明，如强制结束了残留子进程
+}
+
+func (e *CallError) Error() string {
+	if e.Cleanup != "" {
+		return e.Msg + "; " + e.Cleanup
+	}
+	return e.Msg
+}
+
+// ErrorKind 返回错误的类别，非 CallError 返回空字符串
+func ErrorKind(err error) string {
+	var callErr *CallError
+	if errors.As(err, &callErr) {
+		return callErr.Kind
+	}
+	return ""
+}
+
+// ErrorCleanup 返回错误中记录的进程清理说明
+func ErrorCleanup(err error) string {
+	var callErr *CallError
+	if errors.As(err, &callErr) {
+		return callErr.Cleanup
+	}
+	return ""
+}
+
 // ExtractResultFromLogs extracts result from logs
 // This function mimics the behavior of extractResultFromLogs in the Electron project
 var reRunResult = regexp.MustCompile(`XiacutAIRunResult\[(.*?)\]\[(.*?)\]`)
-var reMidResult = regexp.MustCompile(`Result\[(.*?)\]\[(.*?)\]`)
+
+// 中间结果可以是未编码的 JSON，内容里可能有 "]"（如 outputs 中的数组），取到行内最后一个 "]"
+var reMidResult = regexp.MustCompile(`Result\[([^\]]*)\]\[(.*)\]`)
 
 func ExtractResultFromLogs(taskID string, line string) (map[string]interface{}, bool) {
 
@@ -133,8 +245,12 @@ func ExtractResultFromLogs(taskID string, line string) (map[string]interface{},
 			return nil, false
 		}
 
-		// 只是表示进程活着
-		return map[string]interface{}{"_alive": true}, true
+		// 表示进程活着，能解析出进度时一并带上
+		out := map[string]interface{}{"_alive": true}
+		if progress, ok := ParseProgress(m[2]); ok {
+			out["_progress"] = progress
+		}
+		return out, true
 	}
 
 	// ===== 3. 简单JSON =====
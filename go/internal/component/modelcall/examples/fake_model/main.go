@@ -0,0 +1,390 @@
+// fake_model 一个不依赖 AI 模型的假模型，按 EasyServer 协议输出结果，用于测试调度、工作流和模型接入
+//
+// 用法（config.json 中 easyServer.entry 指向编译出的程序）：
+//
+//	fake_model ${CONFIG}
+//
+// 行为由脚本控制，优先级从低到高：环境变量 FAKE_MODEL_SCRIPT（JSON）→ 调用配置 modelConfig.param.fake：
+//
+//	{"delayMs": 500, "progress": 5, "error": "out of memory", "retry": true, "hang": true,
+//	 "exitCode": 2, "spawnChild": true, "ignoreTerm": true, "noise": true}
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// script 假模型的行为
+type script struct {
+	DelayMs    int    `json:"delayMs"`    // 出结果前的耗时
+	Progress   int    `json:"progress"`   // 耗时期间输出的进度条数
+	Error      string `json:"error"`      // 返回 {"error": ...}
+	Retry      bool   `json:"retry"`      // 返回 {"type": "retry", "error": ...}，模拟服务未就绪
+	Hang       bool   `json:"hang"`       // 不输出结果也不退出
+	ExitCode   int    `json:"exitCode"`   // 不输出结果，以该退出码退出
+	SpawnChild bool   `json:"spawnChild"` // 启动一个一直运行的子进程，用于验证进程树清理
+	IgnoreTerm bool   `json:"ignoreTerm"` // 忽略 SIGTERM
+	Noise      bool   `json:"noise"`      // 输出与协议无关的日志和其它任务 ID 的结果
+}
+
+// taskConfig 调用配置，与 EasyServer.prepareConfigJson 写出的内容相同
+type taskConfig struct {
+	ID          string                 `json:"id"`
+	Mode        string                 `json:"mode"`
+	QueueDir    string                 `json:"queueDir"`
+	Port        int                    `json:"port"`
+	ModelConfig map[string]interface{} `json:"modelConfig"`
+	Setting     map[string]interface{} `json:"setting"`
+}
+
+var stdoutMu sync.Mutex
+
+func emit(line string) {
+	stdoutMu.Lock()
+	defer stdoutMu.Unlock()
+	fmt.Println(line)
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "--child" {
+		// spawnChild 启动的子进程
+		signal.Ignore(syscall.SIGTERM)
+		select {}
+	}
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: fake_model <config.json>")
+		os.Exit(1)
+	}
+	cfg, err := readConfig(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch cfg.Mode {
+	case "watch":
+		runWatch(cfg)
+	case "http":
+		runHTTP(cfg)
+	default:
+		os.Exit(runTask(cfg, true))
+	}
+}
+
+func readConfig(path string) (*taskConfig, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %v", err)
+	}
+	cfg := &taskConfig{}
+	if err := json.Unmarshal(raw, cfg); err != nil {
+		return nil, fmt.Errorf("parse config: %v", err)
+	}
+	if cfg.ModelConfig == nil {
+		cfg.ModelConfig = map[string]interface{}{}
+	}
+	return cfg, nil
+}
+
+func loadScript(cfg *taskConfig) script {
+	s := script{}
+	if raw := os.Getenv("FAKE_MODEL_SCRIPT"); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &s)
+	}
+	if param, ok := cfg.ModelConfig["param"].(map[string]interface{}); ok {
+		if fake, ok := param["fake"]; ok {
+			raw, _ := json.Marshal(fake)
+			_ = json.Unmarshal(raw, &s)
+		}
+	}
+	return s
+}
+
+// runTask 执行一个任务并输出结果，返回进程退出码；single 为 false 时（常驻模式）不执行退出类脚本
+func runTask(cfg *taskConfig, single bool) int {
+	s := loadScript(cfg)
+	if single && s.IgnoreTerm {
+		signal.Ignore(syscall.SIGTERM)
+	}
+	if single && s.SpawnChild {
+		if self, err := os.Executable(); err == nil {
+			_ = exec.Command(self, "--child").Start()
+		}
+	}
+	if s.Noise {
+		emit("loading model weights ...")
+		emit(fmt.Sprintf("Result[other-%s][%s]", cfg.ID, encode(map[string]interface{}{"percent": 99})))
+		emit(fmt.Sprintf("XiacutAIRunResult[other-%s][%s]", cfg.ID, encode(map[string]interface{}{"error": "not mine"})))
+	}
+
+	steps := s.Progress
+	if steps <= 0 {
+		steps = 1
+	}
+	for i := 1; i <= steps; i++ {
+		time.Sleep(time.Duration(s.DelayMs/steps) * time.Millisecond)
+		if s.Progress > 0 {
+			emit(fmt.Sprintf("Result[%s][%s]", cfg.ID, encode(map[string]interface{}{
+				"percent": float64(i) * 100 / float64(steps),
+				"stage":   "inference",
+				"message": fmt.Sprintf("step %d/%d", i, steps),
+			})))
+		}
+	}
+
+	if s.Hang {
+		select {}
+	}
+	if single && s.ExitCode != 0 {
+		fmt.Fprintln(os.Stderr, "fake model crashed")
+		return s.ExitCode
+	}
+	emit(fmt.Sprintf("XiacutAIRunResult[%s][%s]", cfg.ID, encode(resultFor(cfg, s))))
+	return 0
+}
+
+// resultFor 按功能生成结果
+func resultFor(cfg *taskConfig, s script) map[string]interface{} {
+	if s.Retry {
+		return map[string]interface{}{"type": "retry", "error": "model not ready"}
+	}
+	if s.Error != "" {
+		return map[string]interface{}{"error": s.Error}
+	}
+	fn, _ := cfg.ModelConfig["type"].(string)
+	for _, key := range []string{"promptAudio", "audio", "video", "image", "mask"} {
+		if path, _ := cfg.ModelConfig[key].(string); path != "" {
+			if _, err := os.Stat(path); err != nil {
+				return map[string]interface{}{"error": fmt.Sprintf("%s not found: %s", key, path)}
+			}
+		}
+	}
+	switch fn {
+	case "soundTts", "soundClone":
+		path, err := writeOutput(cfg.ID, ".wav", silenceWav(time.Second))
+		if err != nil {
+			return map[string]interface{}{"error": err.Error()}
+		}
+		return map[string]interface{}{"url": path}
+	case "videoGen":
+		path, err := writeOutput(cfg.ID, ".mp4", []byte("fake video"))
+		if err != nil {
+			return map[string]interface{}{"error": err.Error()}
+		}
+		return map[string]interface{}{"url": path}
+	case "imageGen", "imageEdit", "imageUpscale":
+		// param.count 大于 1 时返回多张
+		param, _ := cfg.ModelConfig["param"].(map[string]interface{})
+		count, _ := param["count"].(float64)
+		if count < 1 {
+			count = 1
+		}
+		urls := make([]interface{}, 0, int(count))
+		for i := 0; i < int(count); i++ {
+			path, err := writeOutput(fmt.Sprintf("%s-%d", cfg.ID, i), ".png", fakePng(64, 64))
+			if err != nil {
+				return map[string]interface{}{"error": err.Error()}
+			}
+			urls = append(urls, path)
+		}
+		if len(urls) == 1 {
+			return map[string]interface{}{"url": urls[0]}
+		}
+		return map[string]interface{}{"urls": urls}
+	case "asr", "soundAsr":
+		text, _ := cfg.ModelConfig["text"].(string)
+		if text == "" {
+			text = "这是一段测试文本"
+		}
+		return map[string]interface{}{"records": []interface{}{
+			map[string]interface{}{"segments": []interface{}{
+				map[string]interface{}{"start": 0, "end": 800, "text": text},
+				map[string]interface{}{"start": 1000, "end": 1800, "text": text},
+			}},
+		}}
+	}
+	return map[string]interface{}{"error": fmt.Sprintf("unsupported function: %s", fn)}
+}
+
+// fakePng 生成一张纯色 PNG
+func fakePng(w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func encode(v interface{}) string {
+	raw, _ := json.Marshal(v)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+// writeOutput 结果文件写在模型目录的 _output 下
+func writeOutput(id, ext string, data []byte) (string, error) {
+	dir, err := filepath.Abs("_output")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, sanitize(id)+ext)
+	return path, os.WriteFile(path, data, 0644)
+}
+
+func sanitize(id string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, id)
+}
+
+// silenceWav 16kHz 单声道 16bit 静音
+func silenceWav(d time.Duration) []byte {
+	const rate = 16000
+	samples := int(d.Seconds() * rate)
+	dataSize := samples * 2
+	buf := make([]byte, 44+dataSize)
+	copy(buf[0:], "RIFF")
+	binary.LittleEndian.PutUint32(buf[4:], uint32(36+dataSize))
+	copy(buf[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(buf[16:], 16)
+	binary.LittleEndian.PutUint16(buf[20:], 1)
+	binary.LittleEndian.PutUint16(buf[22:], 1)
+	binary.LittleEndian.PutUint32(buf[24:], rate)
+	binary.LittleEndian.PutUint32(buf[28:], rate*2)
+	binary.LittleEndian.PutUint16(buf[32:], 2)
+	binary.LittleEndian.PutUint16(buf[34:], 16)
+	copy(buf[36:], "data")
+	binary.LittleEndian.PutUint32(buf[40:], uint32(dataSize))
+	return buf
+}
+
+// runWatch 常驻模式：轮询队列目录，取走 *.queue.json 后并发执行
+func runWatch(cfg *taskConfig) {
+	emit("fake model watching " + cfg.QueueDir)
+	for {
+		files, _ := filepath.Glob(filepath.Join(cfg.QueueDir, "*.queue.json"))
+		sort.Strings(files)
+		for _, file := range files {
+			task, err := readConfig(file)
+			_ = os.Remove(file)
+			if err != nil {
+				emit("skip invalid task file: " + err.Error())
+				continue
+			}
+			go runTask(task, false)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// httpTask HTTP 模式下的任务状态
+type httpTask struct {
+	Status   string                 `json:"status"`
+	Progress map[string]interface{} `json:"progress,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	result   map[string]interface{}
+}
+
+// runHTTP HTTP 模式：实现 /health 和 /tasks 接口
+func runHTTP(cfg *taskConfig) {
+	var mu sync.Mutex
+	tasks := map[string]*httpTask{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/tasks", func(w http.ResponseWriter, r *http.Request) {
+		task := &taskConfig{}
+		if err := json.NewDecoder(r.Body).Decode(task); err != nil || task.ID == "" {
+			http.Error(w, "invalid task", http.StatusBadRequest)
+			return
+		}
+		if task.ModelConfig == nil {
+			task.ModelConfig = map[string]interface{}{}
+		}
+		state := &httpTask{Status: "running"}
+		mu.Lock()
+		tasks[task.ID] = state
+		mu.Unlock()
+		go func() {
+			s := loadScript(task)
+			steps := s.Progress
+			if steps <= 0 {
+				steps = 1
+			}
+			for i := 1; i <= steps; i++ {
+				time.Sleep(time.Duration(s.DelayMs/steps) * time.Millisecond)
+				mu.Lock()
+				state.Progress = map[string]interface{}{"percent": float64(i) * 100 / float64(steps), "stage": "inference"}
+				mu.Unlock()
+			}
+			if s.Hang {
+				return
+			}
+			result := resultFor(task, s)
+			mu.Lock()
+			defer mu.Unlock()
+			if state.Status != "running" {
+				return
+			}
+			if msg, ok := result["error"].(string); ok && result["type"] == nil {
+				state.Status, state.Error = "fail", msg
+				return
+			}
+			state.Status, state.result = "success", result
+		}()
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/tasks/", func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/tasks/")
+		id, suffix, _ := strings.Cut(path, "/")
+		mu.Lock()
+		defer mu.Unlock()
+		state, ok := tasks[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		switch {
+		case r.Method == http.MethodDelete:
+			state.Status = "fail"
+			state.Error = "cancelled"
+		case suffix == "result":
+			_ = json.NewEncoder(w).Encode(state.result)
+		default:
+			_ = json.NewEncoder(w).Encode(state)
+		}
+	})
+
+	addr := net.JoinHostPort("127.0.0.1", fmt.Sprint(cfg.Port))
+	emit("fake model listening on " + addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -1,12 +1,11 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"xiacutai-server/internal/component/modelcall"
 	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/utils"
 
-	"io/ioutil"
 	"log"
 	"os"
 	"strconv"
@@ -59,17 +58,27 @@ func callModelFunction(modelPath, configPath, functionName string, params []stri
 	// 加载配置
 	config, err := modelcall.LoadConfigFromJSON(configPath)
 	if err != nil {
-		return nil, errs.New("加载配置失败: %v", err)
+		return nil, fmt.Errorf("加载配置失败: %v", err)
 	}
 
-	// 创建 EasyServer 实例
-	server := createCallerEasyServer(modelPath, config)
+	// 调用配置写到临时目录
+	if utils.JsonDir == "" {
+		utils.JsonDir = os.TempDir()
+	}
 
-	// 启动服务器
-	if err := server.Start(); err != nil {
-		return nil, errs.New("启动服务器失败: %v", err)
+	// 注册到模型运行时并启动
+	key := config.Name + "|" + config.Version
+	easyserver.Servers.Register(key, easyserver.ServerInfo{
+		LocalPath: modelPath,
+		Name:      config.Name,
+		Version:   config.Version,
+		Setting:   map[string]interface{}{},
+		Config:    *config,
+	})
+	if err := easyserver.Servers.Start(key); err != nil {
+		return nil, fmt.Errorf("启动服务器失败: %v", err)
 	}
-	defer server.Stop()
+	defer easyserver.Servers.Stop(key)
 
 	// 解析参数
 	paramMap := make(map[string]interface{})
@@ -90,8 +99,9 @@ func callModelFunction(modelPath, configPath, functionName string, params []stri
 
 	// 构建功能数据
 	functionData := &easyserver.ServerFunctionDataType{
-		ID:    fmt.Sprintf("call-%d", time.Now().Unix()),
-		Param: paramMap,
+		ID:     fmt.Sprintf("call-%d", time.Now().Unix()),
+		Param:  paramMap,
+		Result: map[string]interface{}{},
 	}
 
 	// 根据功能类型设置特定参数
@@ -124,152 +134,14 @@ func callModelFunction(modelPath, configPath, functionName string, params []stri
 	}
 
 	// 调用功能
-	var result *easyserver.TaskResult
-
-	switch functionName {
-	case "soundTts":
-		result, err = server.SoundTts(*functionData)
-	case "soundClone":
-		result, err = server.SoundClone(*functionData)
-	case "videoGen":
-		result, err = server.VideoGen(*functionData)
-	case "asr":
-		result, err = server.Asr(*functionData)
-	default:
-		return nil, errs.New("不支持的功能: %s", functionName)
-	}
-
+	result, err := easyserver.Servers.Call(key, easyserver.ServerFunction(functionName), *functionData)
 	if err != nil {
-		return nil, errs.New("调用功能失败: %v", err)
+		return nil, fmt.Errorf("调用功能失败: %v", err)
 	}
 
 	return result, nil
 }
 
-// loadConfigFromJSON 从 JSON 文件加载配置
-func loadConfigFromJSON(configPath string) (*easyserver.ServerConfig, error) {
-	// 读取配置文件
-	data, err := ioutil.ReadFile(configPath)
-	if err != nil {
-		return nil, errs.New("读取配置文件失败: %v", err)
-	}
-
-	// 定义一个中间结构体来解析 JSON
-	var configJSON struct {
-		Name          string `json:"name"`
-		Version       string `json:"version"`
-		Title         string `json:"title"`
-		Description   string `json:"description"`
-		ServerRequire string `json:"serverRequire"`
-		PlatformName  string `json:"platformName"`
-		PlatformArch  string `json:"platformArch"`
-		Entry         string `json:"entry"`
-		EasyServer    struct {
-			Entry     string   `json:"entry"`
-			EntryArgs []string `json:"entryArgs"`
-			Envs      []string `json:"envs"`
-			Content   string   `json:"content"`
-		} `json:"easyServer"`
-		Launcher struct {
-			Entry     string   `json:"entry"`
-			EntryArgs []string `json:"entryArgs"`
-			Envs      []string `json:"envs"`
-		} `json:"launcher"`
-		Functions []string `json:"functions"`
-		Settings  []struct {
-			Name        string `json:"name"`
-			Type        string `json:"type"`
-			Title       string `json:"title"`
-			Default     string `json:"default"`
-			Placeholder string `json:"placeholder"`
-		} `json:"settings"`
-	}
-
-	// 解析 JSON
-	if err := json.Unmarshal(data, &configJSON); err != nil {
-		return nil, errs.New("解析配置文件失败: %v", err)
-	}
-
-	// 确定使用哪个 entry
-	entry := configJSON.Entry
-	var entryArgs []string
-	var envs []string
-	var content string
-
-	if configJSON.EasyServer.Entry != "" {
-		entry = configJSON.EasyServer.Entry
-		entryArgs = configJSON.EasyServer.EntryArgs
-		envs = configJSON.EasyServer.Envs
-		content = configJSON.EasyServer.Content
-	} else if configJSON.Launcher.Entry != "" {
-		entry = configJSON.Launcher.Entry
-		entryArgs = configJSON.Launcher.EntryArgs
-		envs = configJSON.Launcher.Envs
-	}
-
-	// 转换 functions
-	functions := make([]easyserver.ServerFunction, len(configJSON.Functions))
-	for i, f := range configJSON.Functions {
-		functions[i] = easyserver.ServerFunction(f)
-	}
-
-	// 转换 settings
-	settings := make([]struct {
-		Name        string `json:"name"`
-		Type        string `json:"type"`
-		Title       string `json:"title"`
-		Default     string `json:"default"`
-		Placeholder string `json:"placeholder"`
-	}, len(configJSON.Settings))
-	for i, s := range configJSON.Settings {
-		settings[i] = s
-	}
-
-	// 构建 ServerConfig
-	config := &easyserver.ServerConfig{
-		Name:          configJSON.Name,
-		Version:       configJSON.Version,
-		Title:         configJSON.Title,
-		Description:   configJSON.Description,
-		PlatformName:  configJSON.PlatformName,
-		PlatformArch:  configJSON.PlatformArch,
-		ServerRequire: configJSON.ServerRequire,
-		Entry:         entry,
-		Functions:     functions,
-		Settings:      settings,
-	}
-
-	// 设置 EasyServer 配置
-	if entry != configJSON.Entry || len(entryArgs) > 0 || len(envs) > 0 || content != "" {
-		config.EasyServer = &struct {
-			Entry     string   `json:"entry"`
-			EntryArgs []string `json:"entryArgs"`
-			Envs      []string `json:"envs"`
-			Content   string   `json:"content"`
-		}{
-			Entry:     entry,
-			EntryArgs: entryArgs,
-			Envs:      envs,
-			Content:   content,
-		}
-	}
-
-	return config, nil
-}
-
-// createCallerEasyServer 创建用于调用的 EasyServer 实例
-func createCallerEasyServer(modelPath string, config *easyserver.ServerConfig) *easyserver.EasyServer {
-	return &easyserver.EasyServer{
-		ServerConfig: *config,
-		ServerInfo: &easyserver.ServerInfo{
-			LocalPath: modelPath,
-			Name:      config.Name,
-			Version:   config.Version,
-			Config:    *config,
-		},
-	}
-}
-
 // parseCallParam 解析调用参数
 func parseCallParam(param string) []string {
 	parts := strings.SplitN(param, "=", 2)
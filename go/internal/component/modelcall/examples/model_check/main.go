@@ -0,0 +1,59 @@
+// model_check 检查模型包是否符合 EasyServer 协议，供模型作者接入前自查
+//
+//	go run ./internal/component/modelcall/examples/model_check [-run] [-function soundTts,asr] [-timeout 600] [-audio a.wav] [-video v.mp4] <模型目录>
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"xiacutai-server/internal/component/modelcall"
+)
+
+func main() {
+	run := flag.Bool("run", false, "实际调用模型并检查结果输出")
+	functions := flag.String("function", "", "要调用的功能，逗号分隔，默认全部")
+	timeout := flag.Int("timeout", 600, "单次调用超时（秒）")
+	audio := flag.String("audio", "", "调用时使用的音频，默认生成一段静音")
+	video := flag.String("video", "", "videoGen 使用的视频")
+	img := flag.String("image", "", "imageEdit / imageUpscale 使用的图片，默认生成一张纯色图片")
+	asJSON := flag.Bool("json", false, "以 JSON 输出检查结果")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "使用方法: model_check [选项] <模型目录>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := modelcall.CheckOptions{
+		Run:         *run,
+		Timeout:     time.Duration(*timeout) * time.Second,
+		SampleAudio: *audio,
+		SampleVideo: *video,
+		SampleImage: *img,
+	}
+	if *functions != "" {
+		opts.Functions = strings.Split(*functions, ",")
+	}
+	report := modelcall.CheckModel(flag.Arg(0), opts)
+
+	if *asJSON {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		marks := map[string]string{modelcall.CheckOK: "✔", modelcall.CheckWarn: "!", modelcall.CheckError: "✘"}
+		fmt.Println(report.Dir)
+		for _, item := range report.Items {
+			fmt.Printf("  %s %-22s %s\n", marks[item.Level], item.Name, item.Msg)
+		}
+	}
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,78 @@
+// Package modeltest 测试用的假模型：编译 examples/fake_model 并生成模型目录
+package modeltest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+var build struct {
+	once sync.Once
+	dir  string
+	bin  string
+	err  error
+	out  []byte
+}
+
+// modelcallDir modelcall 包的源码目录
+func modelcallDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(filepath.Dir(file))
+}
+
+// Binary 编译假模型，同一个测试进程只编译一次，测试结束后由 Cleanup 删除
+func Binary(t testing.TB) string {
+	t.Helper()
+	build.once.Do(func() {
+		build.dir, build.err = os.MkdirTemp("", "fake-model-*")
+		if build.err != nil {
+			return
+		}
+		build.bin = filepath.Join(build.dir, "fake_model")
+		if runtime.GOOS == "windows" {
+			build.bin += ".exe"
+		}
+		goBin := filepath.Join(runtime.GOROOT(), "bin", "go")
+		cmd := exec.Command(goBin, "build", "-o", build.bin, "./examples/fake_model")
+		cmd.Dir = modelcallDir()
+		build.out, build.err = cmd.CombinedOutput()
+	})
+	if build.err != nil {
+		t.Fatalf("build fake model: %v\n%s", build.err, build.out)
+	}
+	return build.bin
+}
+
+// Cleanup 删除编译假模型的临时目录，在使用本包的测试包的 TestMain 中 m.Run 之后调用
+func Cleanup() {
+	if build.dir != "" {
+		_ = os.RemoveAll(build.dir)
+	}
+}
+
+// ModelDir 用 fixtures/<fixture>/config.json 和假模型程序生成一个模型目录
+// fixture 可选 basic、resident、http
+func ModelDir(t testing.TB, fixture string) string {
+	t.Helper()
+	bin := Binary(t)
+	dir := t.TempDir()
+	config, err := os.ReadFile(filepath.Join(modelcallDir(), "examples", "fake_model", "fixtures", fixture, "config.json"))
+	if err != nil {
+		t.Fatalf("read fixture %s: %v", fixture, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), config, 0644); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, filepath.Base(bin)), raw, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
@@ -0,0 +1,182 @@
+package sqllite
+
+import (
+	"fmt"
+	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/domain"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ModelRegistryRepo local_model_registry 表的读写
+// 所有方法先把 key 规范成 name|version（见 domain.NormalizeModelKey），写操作按单条记录在事务中完成，不会改变其它记录的 id
+type ModelRegistryRepo struct {
+	db *gorm.DB
+}
+
+// ModelRegistry 使用全局连接的模型注册表
+func ModelRegistry() *ModelRegistryRepo {
+	return &ModelRegistryRepo{db: GetSession()}
+}
+
+// List 按注册顺序返回全部模型
+func (r *ModelRegistryRepo) List() ([]domain.LocalModelRegistryModel, error) {
+	rows := make([]domain.LocalModelRegistryModel, 0)
+	if err := r.db.Order("id ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// Get 按 key 查询，不存在时返回 gorm.ErrRecordNotFound
+func (r *ModelRegistryRepo) Get(key string) (domain.LocalModelRegistryModel, error) {
+	return getModelRow(r.db, key)
+}
+
+func getModelRow(db *gorm.DB, key string) (domain.LocalModelRegistryModel, error) {
+	// 用 Find 而不是 First，查不到时不打 gorm 的 record not found 日志
+	var row domain.LocalModelRegistryModel
+	result := db.Where("key = ?", domain.NormalizeModelKey(key)).Limit(1).Find(&row)
+	if result.Error != nil {
+		return row, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return row, gorm.ErrRecordNotFound
+	}
+	return row, nil
+}
+
+// Create 新增模型，key 已存在时返回 gorm.ErrDuplicatedKey
+func (r *ModelRegistryRepo) Create(row domain.LocalModelRegistryModel) (domain.LocalModelRegistryModel, error) {
+	row.ID = 0
+	row.Key = domain.NormalizeModelKey(row.Key)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := getModelRow(tx, row.Key); err == nil {
+			return gorm.ErrDuplicatedKey
+		} else if err != gorm.ErrRecordNotFound {
+			return err
+		}
+		return tx.Create(&row).Error
+	})
+	return row, err
+}
+
+// Update 在事务中读取一条记录交给 fn 修改后写回；fn 返回错误时不写入
+// 记录不存在时返回 gorm.ErrRecordNotFound
+func (r *ModelRegistryRepo) Update(key string, fn func(row *domain.LocalModelRegistryModel) error) (domain.LocalModelRegistryModel, error) {
+	var row domain.LocalModelRegistryModel
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if row, err = getModelRow(tx, key); err != nil {
+			return err
+		}
+		id, rowKey := row.ID, row.Key
+		if err := fn(&row); err != nil {
+			return err
+		}
+		// id 和 key 不允许在更新中修改
+		row.ID, row.Key = id, rowKey
+		return tx.Select("*").Omit("id").Save(&row).Error
+	})
+	return row, err
+}
+
+// Upsert key 不存在时新增，存在时交给 fn 合并后写回
+func (r *ModelRegistryRepo) Upsert(row domain.LocalModelRegistryModel, merge func(old *domain.LocalModelRegistryModel) error) (domain.LocalModelRegistryModel, error) {
+	row.ID = 0
+	row.Key = domain.NormalizeModelKey(row.Key)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		old, err := getModelRow(tx, row.Key)
+		if err == gorm.ErrRecordNotFound {
+			return tx.Create(&row).Error
+		}
+		if err != nil {
+			return err
+		}
+		if err := merge(&old); err != nil {
+			return err
+		}
+		old.Key = row.Key
+		row = old
+		return tx.Select("*").Omit("id").Save(&row).Error
+	})
+	return row, err
+}
+
+// UpdateFields 只更新指定的列，记录不存在时返回 gorm.ErrRecordNotFound
+func (r *ModelRegistryRepo) UpdateFields(key string, fields map[string]any) error {
+	result := r.db.Model(&domain.LocalModelRegistryModel{}).
+		Where("key = ?", domain.NormalizeModelKey(key)).
+		Updates(fields)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Delete 删除一条记录并返回删除前的内容，记录不存在时返回 gorm.ErrRecordNotFound
+func (r *ModelRegistryRepo) Delete(key string) (domain.LocalModelRegistryModel, error) {
+	var row domain.LocalModelRegistryModel
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if row, err = getModelRow(tx, key); err != nil {
+			return err
+		}
+		return tx.Delete(&domain.LocalModelRegistryModel{}, row.ID).Error
+	})
+	return row, err
+}
+
+// migrateModelRegistry 旧数据迁移：key 统一成 name|version，规范化后重复的 key 只保留最后写入的一条，type 为空的补成 localDir
+func migrateModelRegistry(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		rows := make([]domain.LocalModelRegistryModel, 0)
+		if err := tx.Order("id DESC").Find(&rows).Error; err != nil {
+			return err
+		}
+
+		kept := map[string]bool{}
+		renames := make([]domain.LocalModelRegistryModel, 0)
+		for _, row := range rows {
+			key := domain.NormalizeModelKey(row.Key)
+			if row.Name != "" && row.Version != "" {
+				key = domain.ModelKey(row.Name, row.Version)
+			}
+			if kept[key] {
+				log.Warn("移除重复的模型记录", zap.Int64("id", row.ID), zap.String("key", row.Key), zap.String("normalized", key))
+				if err := tx.Delete(&domain.LocalModelRegistryModel{}, row.ID).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			kept[key] = true
+			if key != row.Key || row.Type == "" {
+				row.Key = key
+				renames = append(renames, row)
+			}
+		}
+
+		// 重复记录删除后再改 key；先统一改成临时 key，避免 a→b、b→c 这样的链式改名撞上唯一索引
+		for _, row := range renames {
+			if err := tx.Model(&domain.LocalModelRegistryModel{}).Where("id = ?", row.ID).
+				Update("key", fmt.Sprintf("__migrating__%d", row.ID)).Error; err != nil {
+				return err
+			}
+		}
+		for _, row := range renames {
+			updates := map[string]any{"key": row.Key}
+			if row.Type == "" {
+				updates["type"] = "localDir"
+			}
+			log.Info("迁移模型记录", zap.Int64("id", row.ID), zap.String("key", row.Key))
+			if err := tx.Model(&domain.LocalModelRegistryModel{}).Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
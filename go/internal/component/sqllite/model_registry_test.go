@@ -0,0 +1,175 @@
+package sqllite
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"xiacutai-server/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+func newTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestModelRegistryRepo(t *testing.T) {
+	repo := &ModelRegistryRepo{db: newTestStore(t).db}
+
+	// key 规范成 name|version
+	created, err := repo.Create(domain.LocalModelRegistryModel{Key: " demo@1.0 ", Name: "demo", Version: "1.0", Title: "演示"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Key != "demo|1.0" || created.ID == 0 {
+		t.Fatalf("unexpected created row %+v", created)
+	}
+	if _, err := repo.Create(domain.LocalModelRegistryModel{Key: "demo:1.0"}); !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Fatalf("expected duplicated key, got %v", err)
+	}
+	for _, alias := range []string{"demo|1.0", "demo@1.0", "demo:1.0", " demo | 1.0 "} {
+		if row, err := repo.Get(alias); err != nil || row.ID != created.ID {
+			t.Fatalf("lookup %q: %+v %v", alias, row, err)
+		}
+	}
+	if _, err := repo.Get("demo|2.0"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	// Update 不能修改 id 和 key，fn 返回错误时不写入
+	updated, err := repo.Update("demo@1.0", func(row *domain.LocalModelRegistryModel) error {
+		row.ID, row.Key, row.Title = 99, "other|1.0", "新标题"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ID != created.ID || updated.Key != "demo|1.0" || updated.Title != "新标题" {
+		t.Fatalf("unexpected updated row %+v", updated)
+	}
+	if _, err := repo.Update("demo|1.0", func(row *domain.LocalModelRegistryModel) error {
+		row.Title = "不写入"
+		return errors.New("abort")
+	}); err == nil {
+		t.Fatal("expected fn error")
+	}
+	if row, _ := repo.Get("demo|1.0"); row.Title != "新标题" {
+		t.Fatalf("aborted update was written: %+v", row)
+	}
+	if _, err := repo.Update("missing|1.0", func(*domain.LocalModelRegistryModel) error { return nil }); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	// Upsert：不存在时新增，存在时合并，id 不变
+	merged := 0
+	merge := func(old *domain.LocalModelRegistryModel) error {
+		merged++
+		old.Status = "5"
+		return nil
+	}
+	other, err := repo.Upsert(domain.LocalModelRegistryModel{Key: "other@2", Name: "other", Version: "2", Status: "1"}, merge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Key != "other|2" || other.Status != "1" || merged != 0 {
+		t.Fatalf("upsert should create: %+v merged %d", other, merged)
+	}
+	again, err := repo.Upsert(domain.LocalModelRegistryModel{Key: "other:2", Status: "1"}, merge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != other.ID || again.Key != "other|2" || again.Status != "5" || again.Name != "other" || merged != 1 {
+		t.Fatalf("upsert should merge: %+v merged %d", again, merged)
+	}
+
+	// UpdateFields 只改指定列
+	if err := repo.UpdateFields("other@2", map[string]any{"autoStart": true}); err != nil {
+		t.Fatal(err)
+	}
+	if row, _ := repo.Get("other|2"); !row.AutoStart || row.Status != "5" {
+		t.Fatalf("unexpected row after UpdateFields %+v", row)
+	}
+	if err := repo.UpdateFields("missing|1.0", map[string]any{"status": "5"}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	// Delete 返回删除前的记录，不影响其它记录
+	deleted, err := repo.Delete("demo@1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted.ID != created.ID || deleted.Title != "新标题" {
+		t.Fatalf("unexpected deleted row %+v", deleted)
+	}
+	if _, err := repo.Delete("demo|1.0"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if rows, _ := repo.List(); len(rows) != 1 || rows[0].ID != other.ID {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+}
+
+func TestMigrateModelRegistry(t *testing.T) {
+	db := newTestStore(t).db
+	rows := []domain.LocalModelRegistryModel{
+		// 重复记录：规范化后都是 dup|1，保留后写入的一条
+		{Key: "dup@1", Title: "旧", Type: "localDir"},
+		{Key: "dup|1", Title: "新", Type: "localDir"},
+		// 链式改名：按 name/version 得到的新 key 正好是另一条记录的旧 key
+		{Key: "b|2", Name: "c", Version: "3", Type: "localDir"},
+		{Key: "a|1", Name: "b", Version: "2", Type: "localDir"},
+		// 互换 key
+		{Key: "x|1", Name: "y", Version: "1", Type: "localDir"},
+		{Key: "y|1", Name: "x", Version: "1", Type: "localDir"},
+		// 补 type，已有的 type 不变
+		{Key: "old:1.0", Name: "old", Version: "1.0"},
+		{Key: "cloud|1", Name: "cloud", Version: "1", Type: "cloud"},
+	}
+	for i := range rows {
+		if err := db.Create(&rows[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := migrateModelRegistry(db); err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]domain.LocalModelRegistryModel{}
+	all := make([]domain.LocalModelRegistryModel, 0)
+	if err := db.Find(&all).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range all {
+		got[row.Key] = row
+	}
+	if len(all) != len(rows)-1 {
+		t.Fatalf("expected %d rows, got %+v", len(rows)-1, all)
+	}
+	if row := got["dup|1"]; row.ID != rows[1].ID || row.Title != "新" {
+		t.Fatalf("should keep the last duplicate, got %+v", row)
+	}
+	for key, id := range map[string]int64{"c|3": rows[2].ID, "b|2": rows[3].ID, "y|1": rows[4].ID, "x|1": rows[5].ID, "old|1.0": rows[6].ID} {
+		if got[key].ID != id {
+			t.Errorf("%s: expected id %d, got %+v", key, id, got[key])
+		}
+	}
+	if got["old|1.0"].Type != "localDir" || got["cloud|1"].Type != "cloud" {
+		t.Fatalf("unexpected types: %+v %+v", got["old|1.0"], got["cloud|1"])
+	}
+
+	// 再次迁移不做任何修改
+	if err := migrateModelRegistry(db); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	db.Model(&domain.LocalModelRegistryModel{}).Count(&count)
+	if count != int64(len(all)) {
+		t.Fatalf("second migration changed rows: %d", count)
+	}
+}
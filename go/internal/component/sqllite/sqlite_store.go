@@ -2,6 +2,7 @@ package sqllite
 
 import (
 	"errors"
+	"strings"
 	"time"
 	"xiacutai-server/internal/component/log"
 	"xiacutai-server/internal/domain"
@@ -34,6 +35,11 @@ func GetSession() *gorm.DB {
 }
 func NewSQLiteStore(dsn string) (*SQLiteStore, error) {
 
+	// 多个任务并发写库时，等待锁释放而不是直接返回 SQLITE_BUSY
+	if !strings.Contains(dsn, "?") {
+		dsn += "?_pragma=busy_timeout(5000)"
+	}
+
 	// ⭐ 必须用 DriverName=sqlite
 	db, err := gorm.Open(sqlite.Dialector{
 		DriverName: "sqlite",
@@ -44,6 +50,13 @@ func NewSQLiteStore(dsn string) (*SQLiteStore, error) {
 		return nil, err
 	}
 
+	// sqlite 单写者，串行化连接避免并发任务互相抢锁
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
 	store := &SQLiteStore{db: db}
 
 	if err := store.migrate(); err != nil {
@@ -58,20 +71,37 @@ func (s *SQLiteStore) Close() error {
 }
 
 func (s *SQLiteStore) migrate() error {
-	return s.db.AutoMigrate(
+	if err := s.db.AutoMigrate(
 		&domain.DataTaskModel{},
 		&domain.DataStorageModel{}, // ⭐ 新表
 		&domain.DataVideoTemplateModel{},
 		&domain.LocalModelRegistryModel{},
-	)
+		&domain.DataWebhookDeliveryModel{},
+		&domain.DataResultCacheModel{},
+	); err != nil {
+		return err
+	}
+	return migrateModelRegistry(s.db)
 }
 
 type TaskFilters struct {
-	Biz    string
-	Status []string
-	Type   *int
-	Page   int `form:"page"`
-	Size   int `form:"size"`
+	Biz        string
+	Status     []string
+	Type       *int
+	QueueOrder bool  // true 时按出队顺序（优先级、排队顺序）排序，否则按 id 倒序
+	RunnableAt int64 // 大于 0 时只返回 nextRunAt 已到期的任务（毫秒）
+	Page       int   `form:"page"`
+	Size       int   `form:"size"`
+}
+
+// TaskQueueOrder 任务出队顺序：优先级高的先执行，同优先级先进先出
+const TaskQueueOrder = "priority DESC, queueSort ASC, id ASC"
+
+func (f TaskFilters) OrderBy() string {
+	if f.QueueOrder {
+		return TaskQueueOrder
+	}
+	return "id DESC"
 }
 
 func (s *SQLiteStore) CreateTask(task domain.DataTaskModel) (domain.DataTaskModel, error) {
@@ -86,6 +116,9 @@ func (s *SQLiteStore) CreateTask(task domain.DataTaskModel) (domain.DataTaskMode
 	if task.Type == 0 {
 		task.Type = 1
 	}
+	if task.QueueSort == 0 {
+		task.QueueSort = task.CreatedAt
+	}
 
 	if err := s.db.Create(&task).Error; err != nil {
 		return domain.DataTaskModel{}, err
@@ -126,8 +159,12 @@ func (s *SQLiteStore) ListTasks(filters TaskFilters) ([]domain.DataTaskModel, er
 		query = query.Where("type = ?", *filters.Type)
 	}
 
+	if filters.RunnableAt > 0 {
+		query = query.Where("nextRunAt <= ?", filters.RunnableAt)
+	}
+
 	var models []domain.DataTaskModel
-	if err := query.Order("id DESC").Find(&models).Error; err != nil {
+	if err := query.Order(filters.OrderBy()).Find(&models).Error; err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,20 @@
+package domain
+
+// DataResultCacheModel 模型生成结果缓存，相同模型、功能、文本、参考声音和参数的请求直接复用
+type DataResultCacheModel struct {
+	ID         int64  `gorm:"column:id;primaryKey;autoIncrement"`
+	CreatedAt  int64  `gorm:"column:createdAt;not null"`
+	UpdatedAt  int64  `gorm:"column:updatedAt;not null"`
+	CacheKey   string `gorm:"column:cacheKey;uniqueIndex:idx_data_result_cache_key"`
+	ModelKey   string `gorm:"column:modelKey"` // name|version
+	Function   string `gorm:"column:function"`
+	Result     string `gorm:"column:result"` // 结果数据，文件路径指向缓存目录
+	Files      string `gorm:"column:files"`  // 缓存的文件列表 JSON
+	Size       int64  `gorm:"column:size;default:0"`
+	HitCount   int    `gorm:"column:hitCount;default:0"`
+	LastUsedAt int64  `gorm:"column:lastUsedAt;index:idx_data_result_cache_used"`
+}
+
+func (DataResultCacheModel) TableName() string {
+	return "data_result_cache"
+}
@@ -15,6 +15,9 @@ const (
 	FunctionSoundTts     string = "soundTts"     // 语音合成功能
 	FunctionSoundClone   string = "soundClone"   // 语音克隆功能
 	FunctionSoundAsr     string = "soundAsr"     // 语音识别功能
+	FunctionImageGen     string = "imageGen"     // 图像生成功能
+	FunctionImageEdit    string = "imageEdit"    // 图像编辑功能
+	FunctionImageUpscale string = "imageUpscale" // 图像放大功能
 )
 
 type DataTaskModel struct {
@@ -35,6 +38,18 @@ type DataTaskModel struct {
 	JobResult     string `gorm:"column:jobResult"`
 	ModelConfig   string `gorm:"column:modelConfig"`
 	Result        string `gorm:"column:result"`
+	CallbackURL   string `gorm:"column:callbackUrl"`                                       // 任务结束（success/fail/wait）时回调的地址
+	Priority      int    `gorm:"column:priority;default:0;index:idx_data_task_priority"`   // 优先级，越大越先执行
+	QueueSort     int64  `gorm:"column:queueSort;default:0"`                               // 同优先级内的排队顺序，越小越先执行
+	Attempt       int    `gorm:"column:attempt;default:0"`                                 // 已执行次数
+	MaxAttempts   int    `gorm:"column:maxAttempts;default:0"`                             // 最多执行次数，0 表示未设置
+	LastError     string `gorm:"column:lastError"`                                         // 最近一次执行失败的原因
+	NextRunAt     int64  `gorm:"column:nextRunAt;default:0;index:idx_data_task_next_run"`  // 重试时最早可执行时间（毫秒），0 表示立即
+	LeaseOwner    string `gorm:"column:leaseOwner"`                                        // 持有任务的执行者
+	LeaseExpireAt int64  `gorm:"column:leaseExpireAt;default:0;index:idx_data_task_lease"` // 租约到期时间（毫秒），过期后可被其它执行者接管
+	HeartbeatAt   int64  `gorm:"column:heartbeatAt;default:0"`                             // 最近一次心跳时间（毫秒）
+	Progress      string `gorm:"column:progress"`                                          // 模型最近一次上报的进度 JSON：percent/stage/message/outputs
+	QueuePosition int    `gorm:"-"`                                                        // 排队位置（从 1 开始），仅 queue 状态有值
 }
 
 func (DataTaskModel) TableName() string {
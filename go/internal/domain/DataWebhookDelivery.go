@@ -0,0 +1,27 @@
+package domain
+
+const (
+	WebhookDeliveryPending = "pending"
+	WebhookDeliverySuccess = "success"
+	WebhookDeliveryFail    = "fail"
+)
+
+// DataWebhookDeliveryModel 任务回调投递记录
+type DataWebhookDeliveryModel struct {
+	ID           int64  `gorm:"column:id;primaryKey;autoIncrement"`
+	CreatedAt    int64  `gorm:"column:createdAt;not null"`
+	UpdatedAt    int64  `gorm:"column:updatedAt;not null"`
+	TaskID       int64  `gorm:"column:taskId;index:idx_data_webhook_delivery_task"`
+	Event        string `gorm:"column:event"` // success / fail / wait
+	URL          string `gorm:"column:url"`
+	Payload      string `gorm:"column:payload"`
+	Status       string `gorm:"column:status;index:idx_data_webhook_delivery_status"`
+	Attempt      int    `gorm:"column:attempt;default:0"`
+	NextRetryAt  int64  `gorm:"column:nextRetryAt;default:0"`
+	ResponseCode int    `gorm:"column:responseCode;default:0"`
+	LastError    string `gorm:"column:lastError"`
+}
+
+func (DataWebhookDeliveryModel) TableName() string {
+	return "data_webhook_delivery"
+}
@@ -1,5 +1,7 @@
 package domain
 
+import "strings"
+
 type LocalModelRegistryModel struct {
 	ID        int64  `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
 	Key       string `gorm:"column:key;type:text;uniqueIndex" json:"key"`
@@ -20,3 +22,29 @@ type LocalModelRegistryModel struct {
 func (LocalModelRegistryModel) TableName() string {
 	return "local_model_registry"
 }
+
+// ModelKey 注册表统一使用的模型 key：name|version
+func ModelKey(name, version string) string {
+	return strings.TrimSpace(name) + "|" + strings.TrimSpace(version)
+}
+
+// NormalizeModelKey 把不同来源（sys_config、旧数据、接口参数）的 key 统一成 name|version：
+// 去掉首尾空白，name@version、name:version 视为 name|version；没有版本号时原样返回 name
+func NormalizeModelKey(key string) string {
+	name, version, ok := ParseModelKey(key)
+	if !ok {
+		return name
+	}
+	return ModelKey(name, version)
+}
+
+// ParseModelKey 拆分 key，ok 为 false 表示 key 中没有版本号
+func ParseModelKey(key string) (name, version string, ok bool) {
+	key = strings.TrimSpace(key)
+	for _, sep := range []string{"|", "@", ":"} {
+		if i := strings.Index(key, sep); i > 0 && i < len(key)-1 {
+			return strings.TrimSpace(key[:i]), strings.TrimSpace(key[i+1:]), true
+		}
+	}
+	return key, "", false
+}
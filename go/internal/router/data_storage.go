@@ -0,0 +1,15 @@
+package router
+
+import "xiacutai-server/internal/api"
+
+func init() {
+	group := router.Group("/data/storage")
+	{
+		group.POST("/get", api.DataStorageGet)
+		group.POST("/list", api.DataStorageList)
+		group.POST("/sound/add", api.DataStorageSoundCreate)
+		group.POST("/update", api.DataStorageUpdate)
+		group.POST("/delete", api.DataStorageDelete)
+		group.POST("/clear", api.DataStorageClear)
+	}
+}
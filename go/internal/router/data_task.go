@@ -0,0 +1,31 @@
+package router
+
+import "xiacutai-server/internal/api"
+
+func init() {
+	group := router.Group("/data/task")
+	// 任务管理
+	{
+		group.POST("/add", api.DataTaskCreate)
+		group.POST("/list", api.DataTaskList)
+		group.POST("/update", api.DataTaskUpdate)
+		group.POST("/delete", api.DataTaskDelete)
+		group.POST("/cancel", api.DataTaskCancel)
+		group.POST("/continue", api.DataTaskContinue)
+		group.POST("/sound-replace/confirm", api.DataTaskSoundReplaceConfirm)
+	}
+	// 队列调整
+	{
+		group.POST("/priority", api.DataTaskPriority)
+		group.POST("/top", api.DataTaskMoveTop)
+		group.POST("/reorder", api.DataTaskReorder)
+	}
+	// 任务事件推送（SSE）
+	{
+		group.GET("/events", api.DataTaskEvents)
+	}
+	// 任务回调
+	{
+		group.POST("/webhook/list", api.DataTaskWebhookList)
+	}
+}
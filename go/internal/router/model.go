@@ -0,0 +1,28 @@
+package router
+
+import "xiacutai-server/internal/api"
+
+func init() {
+	group := router.Group("/model")
+	// 模型管理
+	{
+		group.POST("/add", api.ModelAdd)
+		group.POST("/list", api.ModelList)
+		group.POST("/setting", api.ModelSetting)
+		group.POST("/delete", api.ModelDelete)
+	}
+	// 设置与参数的表单定义
+	{
+		group.POST("/schema", api.ModelSchema)
+	}
+	// 云端模型
+	{
+		group.POST("/cloudAdd", api.ModelCloudAdd)
+	}
+	// 模型运行时
+	{
+		group.POST("/start", api.ModelStart)
+		group.POST("/stop", api.ModelStop)
+		group.POST("/status", api.ModelStatus)
+	}
+}
@@ -0,0 +1,368 @@
+package router
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"xiacutai-server/internal/api"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/component/modelcall/modeltest"
+	"xiacutai-server/internal/component/sqllite"
+	"xiacutai-server/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "router-test-*")
+	if err != nil {
+		panic(err)
+	}
+	_ = os.Setenv("DataDir", dir)
+	utils.InitDirs()
+	sqllite.Init()
+	code := m.Run()
+	easyserver.StopAllResidents()
+	modeltest.Cleanup()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+type response struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Data json.RawMessage `json:"data"`
+	} `json:"data"`
+}
+
+// post 调用接口，want 为期望的业务 code（0 成功）
+func post(t *testing.T, path string, body any, want int, out any) response {
+	t.Helper()
+	raw, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: http status %d: %s", path, w.Code, w.Body.String())
+	}
+	var resp response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s: invalid response %s", path, w.Body.String())
+	}
+	if (want == 0) != (resp.Code == 0) {
+		t.Fatalf("%s: expected code %d, got %d %s", path, want, resp.Code, resp.Message)
+	}
+	if out != nil && len(resp.Data.Data) > 0 {
+		if err := json.Unmarshal(resp.Data.Data, out); err != nil {
+			t.Fatalf("%s: decode data: %v", path, err)
+		}
+	}
+	return resp
+}
+
+func TestRoutesMounted(t *testing.T) {
+	mounted := map[string]bool{}
+	for _, r := range router.Routes() {
+		mounted[r.Method+" "+r.Path] = true
+	}
+	for _, route := range []string{
+		"POST /model/add", "POST /model/list", "POST /model/setting", "POST /model/delete",
+		"POST /model/schema", "POST /model/cloudAdd",
+		"POST /model/start", "POST /model/stop", "POST /model/status",
+		"POST /data/task/add", "POST /data/task/list", "POST /data/task/update", "POST /data/task/delete",
+		"POST /data/task/cancel", "POST /data/task/continue", "POST /data/task/sound-replace/confirm",
+		"POST /data/task/priority", "POST /data/task/top", "POST /data/task/reorder",
+		"GET /data/task/events", "POST /data/task/webhook/list",
+		"POST /data/storage/get", "POST /data/storage/list", "POST /data/storage/sound/add",
+		"POST /data/storage/update", "POST /data/storage/delete", "POST /data/storage/clear",
+		"POST /init", "POST /init/install", "POST /config",
+	} {
+		if !mounted[route] {
+			t.Errorf("route not mounted: %s", route)
+		}
+	}
+}
+
+// addModel 注册一个假模型，返回 key
+func addModel(t *testing.T) string {
+	t.Helper()
+	dir := modeltest.ModelDir(t, "basic")
+	post(t, "/model/add", map[string]any{"configPath": filepath.Join(dir, "config.json")}, 0, nil)
+	key := "fake-model|1.0.0"
+	t.Cleanup(func() {
+		post(t, "/model/delete", map[string]any{"name": "fake-model", "version": "1.0.0"}, 0, nil)
+	})
+	return key
+}
+
+func TestModelRoutes(t *testing.T) {
+	key := addModel(t)
+
+	var list []map[string]any
+	post(t, "/model/list", map[string]any{"functionName": "soundTts"}, 0, &list)
+	if len(list) != 1 || list[0]["key"] != key {
+		t.Fatalf("unexpected model list %v", list)
+	}
+	post(t, "/model/add", map[string]any{"configPath": "/not/exist/config.json"}, 1, nil)
+	post(t, "/model/setting", map[string]any{"name": "fake-model", "version": "1.0.0", "setting": map[string]any{}}, 0, nil)
+	post(t, "/model/setting", map[string]any{"name": "fake-model", "version": "1.0.0", "setting": map[string]any{"unknown": 1}}, 1, nil)
+
+	var schema map[string]any
+	post(t, "/model/schema", map[string]any{"key": key}, 0, &schema)
+	if _, ok := schema["functions"].(map[string]any)["soundTts"]; !ok {
+		t.Fatalf("schema missing soundTts: %v", schema)
+	}
+
+	var state easyserver.ServerState
+	post(t, "/model/status", map[string]any{"key": key}, 0, &state)
+	if state.Status != easyserver.ServerStopped {
+		t.Fatalf("expected stopped, got %s", state.Status)
+	}
+	post(t, "/model/start", map[string]any{"name": "fake-model", "version": "1.0.0"}, 0, &state)
+	if state.Status != easyserver.ServerRunning {
+		t.Fatalf("expected running, got %s %s", state.Status, state.Error)
+	}
+	var states []easyserver.ServerState
+	post(t, "/model/status", map[string]any{}, 0, &states)
+	if len(states) != 1 || states[0].Status != easyserver.ServerRunning {
+		t.Fatalf("unexpected status list %v", states)
+	}
+	post(t, "/model/stop", map[string]any{"key": key}, 0, &state)
+	if state.Status != easyserver.ServerStopped {
+		t.Fatalf("expected stopped, got %s", state.Status)
+	}
+	post(t, "/model/start", map[string]any{}, 1, nil)
+	post(t, "/model/start", map[string]any{"key": "missing|1"}, 1, nil)
+}
+
+func TestCloudModelRoutes(t *testing.T) {
+	var info map[string]any
+	post(t, "/model/cloudAdd", map[string]any{
+		"name": "cloud-tts", "version": "1", "functions": []string{"soundTts"},
+		"endpoint": "http://127.0.0.1:1/v1", "apiKey": "sk-secret", "model": "tts-1",
+	}, 0, &info)
+	defer post(t, "/model/delete", map[string]any{"name": "cloud-tts", "version": "1"}, 0, nil)
+	if info["config"].(map[string]any)["cloud"].(map[string]any)["apiKey"] != "******" {
+		t.Fatalf("api key not masked: %v", info["config"])
+	}
+	post(t, "/model/cloudAdd", map[string]any{"name": "cloud-tts", "version": "1", "endpoint": "ftp://x", "model": "m"}, 1, nil)
+
+	var state easyserver.ServerState
+	post(t, "/model/status", map[string]any{"key": "cloud-tts|1"}, 0, &state)
+	if state.Status != easyserver.ServerRunning {
+		t.Fatalf("expected cloud model running, got %s", state.Status)
+	}
+	post(t, "/model/start", map[string]any{"key": "cloud-tts|1"}, 1, nil)
+}
+
+type task struct {
+	ID       int64  `json:"ID"`
+	Title    string `json:"Title"`
+	Status   string `json:"Status"`
+	Priority int    `json:"Priority"`
+}
+
+func TestTaskRoutes(t *testing.T) {
+	key := addModel(t)
+
+	create := func(text string) task {
+		var created map[string]any
+		post(t, "/data/task/add", map[string]any{"type": "soundTts", "serverKey": key, "text": text}, 0, &created)
+		raw, _ := json.Marshal(created)
+		var out task
+		_ = json.Unmarshal(raw, &out)
+		if out.ID == 0 {
+			t.Fatalf("task not created: %v", created)
+		}
+		return out
+	}
+	first, second := create("第一条"), create("第二条")
+	post(t, "/data/task/add", map[string]any{"type": "unknown"}, 1, nil)
+
+	var list []map[string]any
+	post(t, "/data/task/list", map[string]any{"biz": "SoundGenerate", "page": 1, "size": 10}, 0, &list)
+	if len(list) < 2 {
+		t.Fatalf("expected tasks in list, got %v", list)
+	}
+
+	post(t, "/data/task/update", map[string]any{"id": first.ID, "title": "改名"}, 0, nil)
+	post(t, "/data/task/priority", map[string]any{"id": first.ID, "priority": 5}, 0, nil)
+	post(t, "/data/task/top", map[string]any{"id": second.ID}, 0, nil)
+	post(t, "/data/task/reorder", map[string]any{"ids": []int64{first.ID, second.ID}}, 0, nil)
+	post(t, "/data/task/webhook/list", map[string]any{"taskId": first.ID}, 0, nil)
+	post(t, "/data/task/sound-replace/confirm", map[string]any{"id": first.ID}, 1, nil)
+
+	post(t, "/data/task/cancel", map[string]any{"id": first.ID}, 0, nil)
+	post(t, "/data/task/cancel", map[string]any{"id": first.ID}, 1, nil)
+	post(t, "/data/task/continue", map[string]any{"id": first.ID}, 0, nil)
+
+	post(t, "/data/task/delete", map[string]any{"id": first.ID}, 0, nil)
+	post(t, "/data/task/delete", map[string]any{"id": second.ID}, 0, nil)
+	post(t, "/data/task/delete", map[string]any{"id": 0}, 1, nil)
+}
+
+func TestTaskEventsRoute(t *testing.T) {
+	// SSE 需要真实连接（ResponseRecorder 不支持 CloseNotify）
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/data/task/events?biz=SoundGenerate", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected events response %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestStorageRoutes(t *testing.T) {
+	audio := filepath.Join(t.TempDir(), "prompt.wav")
+	if err := os.WriteFile(audio, []byte("RIFF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var created struct {
+		ID int64 `json:"ID"`
+	}
+	post(t, "/data/storage/sound/add", map[string]any{"title": "参考声音", "filePath": audio, "promptText": "你好"}, 0, &created)
+	if created.ID == 0 {
+		t.Fatal("storage not created")
+	}
+	var got map[string]any
+	post(t, "/data/storage/get", map[string]any{"id": created.ID}, 0, &got)
+	var list []map[string]any
+	post(t, "/data/storage/list", map[string]any{"biz": "SoundPrompt"}, 0, &list)
+	if len(list) != 1 {
+		t.Fatalf("expected 1 storage, got %v", list)
+	}
+	post(t, "/data/storage/update", map[string]any{"id": created.ID, "title": "改名"}, 0, nil)
+	post(t, "/data/storage/delete", map[string]any{"id": created.ID}, 0, nil)
+	post(t, "/data/storage/get", map[string]any{"id": created.ID}, 1, nil)
+
+	post(t, "/data/storage/sound/add", map[string]any{"title": "参考声音", "filePath": audio, "promptText": "你好"}, 0, nil)
+	post(t, "/data/storage/clear", map[string]any{"biz": "SoundPrompt"}, 0, nil)
+	post(t, "/data/storage/list", map[string]any{"biz": "SoundPrompt"}, 0, &list)
+	if len(list) != 0 {
+		t.Fatalf("expected storage cleared, got %v", list)
+	}
+	post(t, "/data/storage/clear", map[string]any{}, 1, nil)
+}
+
+func TestSysConfigRoute(t *testing.T) {
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0,"message":"ok","data":{"sys_configs":[{"code":"version","content":"1.2.0"}]}}`))
+	}))
+	defer remote.Close()
+	t.Setenv("AIGCPANEL_SYS_CONFIG_URL", remote.URL)
+
+	raw, _ := json.Marshal(map[string]any{})
+	req := httptest.NewRequest(http.MethodPost, "/config", bytes.NewReader(raw))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp struct {
+		Code int `json:"code"`
+		Data struct {
+			VersionInfo map[string]any `json:"version_info"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Code != 0 {
+		t.Fatalf("unexpected response %s", w.Body.String())
+	}
+	if resp.Data.VersionInfo["version"] != "1.2.0" {
+		t.Fatalf("unexpected version info %v", resp.Data.VersionInfo)
+	}
+}
+
+// modelZip 生成模型压缩包，files 为压缩包内路径 -> 内容
+func modelZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "model.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	return path
+}
+
+func TestSysInstallRoute(t *testing.T) {
+	post(t, "/init/install", map[string]any{}, 1, nil)
+	post(t, "/init/install", map[string]any{"path": filepath.Join(t.TempDir(), "missing.zip")}, 1, nil)
+	post(t, "/init/install", map[string]any{"path": modelZip(t, map[string]string{"a.txt": "a"})}, 1, nil)
+
+	config := `{"name":"offline","version":"1.0"}`
+	sum := sha256.Sum256([]byte(config))
+	manifest := func(files map[string]string) string {
+		raw, _ := json.Marshal(map[string]any{"name": "offline", "version": "1.0", "files": files})
+		return string(raw)
+	}
+
+	cases := []struct {
+		name     string
+		files    map[string]string
+		checksum string
+		want     string
+	}{
+		{
+			name:     "zip checksum",
+			files:    map[string]string{"manifest.json": manifest(nil), "offline-win-x86-v1.0/config.json": config},
+			checksum: strings.Repeat("0", 64),
+			want:     "sha256",
+		},
+		{
+			name:  "file checksum",
+			files: map[string]string{"manifest.json": manifest(map[string]string{"offline-win-x86-v1.0/config.json": strings.Repeat("0", 64)}), "offline-win-x86-v1.0/config.json": config},
+			want:  "文件校验失败",
+		},
+		{
+			name:  "missing file",
+			files: map[string]string{"manifest.json": manifest(map[string]string{"offline-win-x86-v1.0/run.bat": hex.EncodeToString(sum[:])}), "offline-win-x86-v1.0/config.json": config},
+			want:  "不存在",
+		},
+		{
+			name:  "config mismatch",
+			files: map[string]string{"manifest.json": manifest(nil), "offline-win-x86-v1.0/config.json": `{"name":"other","version":"1.0"}`},
+			want:  "不一致",
+		},
+		{
+			name:  "missing dir",
+			files: map[string]string{"manifest.json": manifest(nil), "other/config.json": config},
+			want:  "缺少模型目录",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			key, err := api.InstallModelPackage(modelZip(t, c.files), c.checksum)
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Fatalf("expected %q error, got %v", c.want, err)
+			}
+			if key != "offline|1.0" {
+				t.Fatalf("unexpected key %q", key)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ func init() {
 	group := router.Group("/")
 	{
 		group.POST("/init", api.SysInit)
-
+		group.POST("/init/install", api.SysInstall)
+		group.POST("/config", api.SysConfig)
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"time"
 	"xiacutai-server/internal/component/sqllite"
 	"xiacutai-server/internal/domain"
+
+	"gorm.io/gorm"
 )
 
 type taskService struct{}
@@ -22,11 +24,15 @@ func (s *taskService) CreateTask(task domain.DataTaskModel) (domain.DataTaskMode
 	if task.Type == 0 {
 		task.Type = 1
 	}
+	if task.QueueSort == 0 {
+		task.QueueSort = task.CreatedAt
+	}
 	session := sqllite.GetSession()
 	if err := session.Save(&task).Error; err != nil {
 		return domain.DataTaskModel{}, err
 	}
 
+	publishTaskCreated(task)
 	return task, nil
 }
 
@@ -63,6 +69,10 @@ func (s *taskService) ListTasks(filters sqllite.TaskFilters) ([]domain.DataTaskM
 		query = query.Where("type = ?", *filters.Type)
 	}
 
+	if filters.RunnableAt > 0 {
+		query = query.Where("nextRunAt <= ?", filters.RunnableAt)
+	}
+
 	if filters.Page > 0 {
 		limit := filters.Size
 		offset := (filters.Page - 1) * filters.Size
@@ -70,7 +80,7 @@ func (s *taskService) ListTasks(filters sqllite.TaskFilters) ([]domain.DataTaskM
 	}
 
 	var models []domain.DataTaskModel
-	if err := query.Order("id DESC").Find(&models).Error; err != nil {
+	if err := query.Order(filters.OrderBy()).Find(&models).Error; err != nil {
 		return nil, err
 	}
 	return models, nil
@@ -82,17 +92,65 @@ func (s *taskService) UpdateTask(id int64, updates map[string]any) (domain.DataT
 	}
 	updates["updatedAt"] = time.Now().UnixMilli()
 
-	session := sqllite.GetSession()
-	if err := session.Model(&domain.DataTaskModel{}).
-		Where("id = ?", id).
-		Updates(updates).Error; err != nil {
+	if _, err := updateTaskRow(id, nil, updates); err != nil {
 		return domain.DataTaskModel{}, err
 	}
 
-	return s.GetTask(id)
+	task, err := s.GetTask(id)
+	if err != nil {
+		return task, err
+	}
+	if _, ok := updates["status"]; ok {
+		publishTaskStatus(task)
+	}
+	return task, nil
+}
+
+// updateTaskRow 更新一条任务，cond 为附加的更新条件（如租约持有者），返回更新的行数
+// 状态变为 success/fail/wait 时在同一个事务中写入回调投递记录，状态写入后进程崩溃也不会丢失回调
+func updateTaskRow(id int64, cond func(db *gorm.DB) *gorm.DB, updates map[string]any) (int64, error) {
+	var affected int64
+	queued := false
+	err := sqllite.GetSession().Transaction(func(tx *gorm.DB) error {
+		status, _ := updates["status"].(string)
+		var before []string
+		if isWebhookEvent(status) {
+			if err := tx.Model(&domain.DataTaskModel{}).Where("id = ?", id).Pluck("status", &before).Error; err != nil {
+				return err
+			}
+		}
+
+		query := tx.Model(&domain.DataTaskModel{}).Where("id = ?", id)
+		if cond != nil {
+			query = cond(query)
+		}
+		result := query.Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		affected = result.RowsAffected
+		// 状态未变化（例如重复取消）时不重复回调
+		if affected == 0 || len(before) == 0 || before[0] == status {
+			return nil
+		}
+		var err error
+		queued, err = Webhook.enqueue(tx, id, status)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if queued {
+		Webhook.notify()
+	}
+	return affected, nil
 }
 
 func (s *taskService) DeleteTask(id int64) error {
 	session := sqllite.GetSession()
-	return session.Delete(&domain.DataTaskModel{}, id).Error
+	if err := session.Delete(&domain.DataTaskModel{}, id).Error; err != nil {
+		return err
+	}
+	forgetTaskEvents(id)
+	return nil
 }
@@ -5,14 +5,14 @@ import (
 	"go.uber.org/zap"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"xiacutai-server/internal/component/errs"
 	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/component/modelcall/easyserver"
 	"xiacutai-server/internal/component/sqllite"
 	"xiacutai-server/internal/domain"
 	"xiacutai-server/internal/utils"
-
-	"gorm.io/gorm"
 )
 
 type model struct{}
@@ -23,22 +23,20 @@ var Model = new(model)
 // 公共解析
 ////////////////////////////////////////////////////////////
 
-func parseConfigToInfo(cfg map[string]any, parent string) domain.LocalModelConfigInfo {
-
-	toString := func(v any) string {
-		if x, ok := v.(string); ok {
-			return x
-		}
-		return ""
+// parseConfigToInfo 按 easyserver.ServerConfig 解析 config.json，settings / config 保留原始内容
+func parseConfigToInfo(cfg map[string]any, parent string) (domain.LocalModelConfigInfo, error) {
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return domain.LocalModelConfigInfo{}, err
+	}
+	config, err := easyserver.ParseServerConfig(raw)
+	if err != nil {
+		return domain.LocalModelConfigInfo{}, err
 	}
 
-	functions := make([]string, 0)
-	if arr, ok := cfg["functions"].([]any); ok {
-		for _, item := range arr {
-			if str, ok := item.(string); ok {
-				functions = append(functions, str)
-			}
-		}
+	functions := make([]string, 0, len(config.Functions))
+	for _, f := range config.Functions {
+		functions = append(functions, string(f))
 	}
 
 	settings := make([]any, 0)
@@ -53,22 +51,22 @@ func parseConfigToInfo(cfg map[string]any, parent string) domain.LocalModelConfi
 
 	return domain.LocalModelConfigInfo{
 		Type:              "LOCAL_DIR",
-		Name:              toString(cfg["name"]),
-		Version:           toString(cfg["version"]),
-		ServerRequire:     firstNonEmpty(toString(cfg["serverRequire"]), "*"),
-		Title:             toString(cfg["title"]),
-		Description:       toString(cfg["description"]),
-		DeviceDescription: toString(cfg["deviceDescription"]),
+		Name:              config.Name,
+		Version:           config.Version,
+		ServerRequire:     firstNonEmpty(config.ServerRequire, "*"),
+		Title:             config.Title,
+		Description:       config.Description,
+		DeviceDescription: config.DeviceDescription,
 		Path:              parent,
-		PlatformName:      toString(cfg["platformName"]),
-		PlatformArch:      toString(cfg["platformArch"]),
-		Entry:             toString(cfg["entry"]),
+		PlatformName:      config.PlatformName,
+		PlatformArch:      config.PlatformArch,
+		Entry:             config.Entry,
 		Functions:         functions,
 		Settings:          settings,
 		Setting:           setting,
 		Config:            cfg,
 		Status:            "3",
-	}
+	}, nil
 }
 
 func firstNonEmpty(v string, fallback string) string {
@@ -107,7 +105,11 @@ func (s *model) ModelAdd(configPath string) (domain.LocalModelConfigInfo, error)
 	}
 
 	parent := filepath.Dir(configPath)
-	info := parseConfigToInfo(cfg, parent)
+	info, err := parseConfigToInfo(cfg, parent)
+	if err != nil {
+		log.Error("模型配置解析失败", zap.Error(err))
+		return domain.LocalModelConfigInfo{}, err
+	}
 
 	log.Info("准备注册模型",
 		zap.String("name", info.Name),
@@ -198,10 +200,8 @@ func convertDBToInfo(row domain.LocalModelRegistryModel) (domain.LocalModelConfi
 }
 
 func (s *model) ModelList(functionName string) ([]domain.LocalModelConfigInfo, error) {
-	rows := make([]domain.LocalModelRegistryModel, 0)
-	if err := sqllite.GetSession().
-		Order("id ASC").
-		Find(&rows).Error; err != nil {
+	rows, err := sqllite.ModelRegistry().List()
+	if err != nil {
 		return nil, err
 	}
 
@@ -217,6 +217,8 @@ func (s *model) ModelList(functionName string) ([]domain.LocalModelConfigInfo, e
 			continue
 		}
 
+		maskCloudSecret(&info)
+
 		if functionName != "" {
 			if len(info.Functions) > 0 && utils.Contains(info.Functions, functionName) {
 				list = append(list, info)
@@ -236,121 +238,119 @@ func (s *model) ModelList(functionName string) ([]domain.LocalModelConfigInfo, e
 }
 func (s *model) ModelUpdateSetting(name, version string, newSetting map[string]any) error {
 
-	key := name + "|" + version
+	key := domain.ModelKey(name, version)
 
 	log.Info("更新模型设置",
 		zap.String("key", key),
 		zap.Any("setting", newSetting),
 	)
 
-	reg, err := loadRegistry()
-	if err != nil {
-		return err
-	}
-
-	for i, r := range reg.Records {
-
-		if r.Key != key {
-			continue
+	_, err := sqllite.ModelRegistry().Update(key, func(row *domain.LocalModelRegistryModel) error {
+		r, err := convertDBToRecord(*row)
+		if err != nil {
+			return err
 		}
 
 		// ---------- 校验参数合法性 ----------
-		validKeys := map[string]bool{}
-		for _, s := range r.Settings {
-			if m, ok := s.(map[string]any); ok {
-				if n, ok := m["name"].(string); ok {
-					validKeys[n] = true
-				}
-			}
-		}
-
-		for k := range newSetting {
-			if !validKeys[k] {
-				log.Warn("非法设置字段",
-					zap.String("key", key),
-					zap.String("field", k),
-				)
-				return errs.New("存在未定义的设置字段: " + k)
-			}
+		fields := parseSettingFields(r.Settings)
+		if name := undefinedField(fields, newSetting); name != "" {
+			log.Warn("非法设置字段",
+				zap.String("key", key),
+				zap.String("field", name),
+			)
+			return errs.New("存在未定义的设置字段: " + name)
 		}
 
-		// ---------- 合并设置 ----------
+		// ---------- 合并设置，按 settings 定义校验类型、范围并补默认值 ----------
+		// 旧版本遗留的未定义字段原样保留
 		if r.Setting == nil {
 			r.Setting = map[string]any{}
 		}
-
-		for k, v := range newSetting {
+		declared := map[string]any{}
+		for _, field := range fields {
+			if v, ok := r.Setting[field.Name]; ok {
+				declared[field.Name] = v
+			}
+			if v, ok := newSetting[field.Name]; ok {
+				declared[field.Name] = v
+			}
+		}
+		normalized, err := validateFields(fields, declared)
+		if err != nil {
+			log.Warn("模型设置校验失败",
+				zap.String("key", key),
+				zap.Error(err),
+			)
+			return err
+		}
+		for k, v := range normalized {
 			r.Setting[k] = v
 		}
 
-		reg.Records[i].Setting = r.Setting
-
-		log.Info("模型设置更新成功", zap.String("key", key))
-		return saveRegistry(reg)
+		settingRaw, err := json.Marshal(r.Setting)
+		if err != nil {
+			return err
+		}
+		row.Setting = string(settingRaw)
+		return nil
+	})
+	if sqllite.IsRecordNotFound(err) {
+		return errs.New("模型不存在")
+	}
+	if err != nil {
+		return err
 	}
 
-	return errs.New("模型不存在")
+	log.Info("模型设置更新成功", zap.String("key", key))
+	return nil
 }
-func (s *model) ModelUpdateStatus(key string, status int) error {
-	db := sqllite.GetSession()
-	return db.Model(&domain.LocalModelRegistryModel{}).
-		Where("key = ?", key).
-		Updates(map[string]any{
-			"status": status,
-		}).Error
 
+// ModelUpdateStatus 更新安装状态，key 可以是 sys_config 中的写法（见 domain.NormalizeModelKey）
+func (s *model) ModelUpdateStatus(key string, status int) error {
+	err := sqllite.ModelRegistry().UpdateFields(key, map[string]any{
+		"status": strconv.Itoa(status),
+	})
+	if sqllite.IsRecordNotFound(err) {
+		return errs.New("模型不存在")
+	}
+	return err
 }
 
 func (s *model) ModelDelete(name string, version string) error {
 
-	key := name + "|" + version
+	key := domain.ModelKey(name, version)
 
 	log.Info("请求删除模型", zap.String("key", key))
 
-	reg, err := loadRegistry()
-	if err != nil {
-		return err
-	}
-
-	index := -1
-
-	for i, r := range reg.Records {
-		if r.Key == key {
-			index = i
-			break
-		}
-	}
-
-	if index == -1 {
+	removed, err := sqllite.ModelRegistry().Delete(key)
+	if sqllite.IsRecordNotFound(err) {
 		log.Warn("删除失败，模型不存在", zap.String("key", key))
 		return errs.New("模型不存在")
 	}
+	if err != nil {
+		return err
+	}
 
-	removed := reg.Records[index]
-
-	// 从数组移除
-	reg.Records = append(reg.Records[:index], reg.Records[index+1:]...)
+	easyserver.Servers.Unregister(removed.Key)
 
 	log.Info("模型已从注册表移除",
 		zap.String("key", removed.Key),
 		zap.String("path", removed.LocalPath),
 	)
 
-	return saveRegistry(reg)
+	return nil
 }
 func (s *model) Get(modelKey string) (*domain.LocalModelConfigInfo, error) {
-	row := &domain.LocalModelRegistryModel{}
-	if err := sqllite.GetSession().
-		Where("key = ?", modelKey).
-		First(row).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	row, err := sqllite.ModelRegistry().Get(modelKey)
+	if err != nil {
+		if sqllite.IsRecordNotFound(err) {
 			log.Warn("模型不存在", zap.String("key", modelKey))
 			return &domain.LocalModelConfigInfo{}, errs.New("模型不存在")
 		}
 		return &domain.LocalModelConfigInfo{}, err
 	}
 
-	record, err := convertDBToRecord(*row)
+	record, err := convertDBToRecord(row)
 	if err != nil {
 		return &domain.LocalModelConfigInfo{}, err
 	}
@@ -359,8 +359,25 @@ func (s *model) Get(modelKey string) (*domain.LocalModelConfigInfo, error) {
 		return &domain.LocalModelConfigInfo{}, errs.New("模型配置损坏")
 	}
 
-	modelConfigInfo := parseConfigToInfo(record.Config, record.LocalPath)
+	modelConfigInfo, err := parseConfigToInfo(record.Config, record.LocalPath)
+	if err != nil {
+		return &domain.LocalModelConfigInfo{}, errs.New("模型配置损坏")
+	}
+	modelConfigInfo.Key = record.Key
+	if record.Type == string(easyserver.ServerCloud) {
+		modelConfigInfo.Type = record.Type
+	}
 	modelConfigInfo.Status = firstNonEmpty(record.Status, "3")
+	// 注册表里保存的是用户修改过的设置，优先于 config.json 自带的 setting
+	for k, v := range record.Setting {
+		modelConfigInfo.Setting[k] = v
+	}
+	// 未设置的项使用 settings 中声明的默认值
+	for _, field := range parseSettingFields(modelConfigInfo.Settings) {
+		if _, ok := modelConfigInfo.Setting[field.Name]; !ok && field.Default != nil {
+			modelConfigInfo.Setting[field.Name] = field.Default
+		}
+	}
 
 	return &modelConfigInfo, nil
 }
@@ -384,13 +401,17 @@ func resolveModelConfig(r ModelRecord) (map[string]any, bool) {
 
 	return cfg, true
 }
+
+// GetByDB 注册表原始记录，不存在时返回空记录（Key 为空）
 func (s *model) GetByDB(modelKey string) (*domain.LocalModelRegistryModel, error) {
-	LocalModelRegistryModel := &domain.LocalModelRegistryModel{}
-	if err := sqllite.GetSession().Where("key=?", modelKey).Order("id ASC").Find(&LocalModelRegistryModel).Error; err != nil {
+	row, err := sqllite.ModelRegistry().Get(modelKey)
+	if sqllite.IsRecordNotFound(err) {
+		return &domain.LocalModelRegistryModel{}, nil
+	}
+	if err != nil {
 		return nil, err
 	}
-
-	return LocalModelRegistryModel, nil
+	return &row, nil
 }
 
 type ModelRecord struct {
@@ -408,51 +429,10 @@ type ModelRecord struct {
 	Config    map[string]any `json:"config"`
 }
 
-type ModelRegistry struct {
-	Records []ModelRecord `json:"records"`
-}
-
 ////////////////////////////////////////////////////////////
-// Registry 操作
+// Registry 记录转换
 ////////////////////////////////////////////////////////////
 
-func loadRegistry() (*ModelRegistry, error) {
-	rows := make([]domain.LocalModelRegistryModel, 0)
-	if err := sqllite.GetSession().Order("id ASC").Find(&rows).Error; err != nil {
-		return nil, err
-	}
-
-	records := make([]ModelRecord, 0, len(rows))
-	for _, row := range rows {
-		r, err := convertDBToRecord(row)
-		if err != nil {
-			return nil, err
-		}
-		records = append(records, r)
-	}
-
-	return &ModelRegistry{Records: records}, nil
-}
-
-func saveRegistry(reg *ModelRegistry) error {
-	return sqllite.GetSession().Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("1 = 1").Delete(&domain.LocalModelRegistryModel{}).Error; err != nil {
-			return err
-		}
-
-		for _, r := range reg.Records {
-			row, err := convertRecordToDB(r)
-			if err != nil {
-				return err
-			}
-			if err := tx.Create(&row).Error; err != nil {
-				return err
-			}
-		}
-		return nil
-	})
-}
-
 func convertRecordToDB(r ModelRecord) (domain.LocalModelRegistryModel, error) {
 	functionsRaw, err := json.Marshal(r.Functions)
 	if err != nil {
@@ -527,79 +507,27 @@ func convertDBToRecord(row domain.LocalModelRegistryModel) (ModelRecord, error)
 }
 
 ////////////////////////////////////////////////////////////
-// 清理失效模型
+// 注册模型
 ////////////////////////////////////////////////////////////
 
-func cleanInvalid(reg *ModelRegistry) {
-
-	valid := make([]ModelRecord, 0)
-
-	for _, r := range reg.Records {
-		if _, err := os.Stat(r.LocalPath); err == nil {
-			valid = append(valid, r)
-		} else {
-			log.Warn("移除失效模型",
-				zap.String("key", r.Key),
-				zap.String("path", r.LocalPath),
-			)
-		}
-	}
-
-	reg.Records = valid
+// autoStartOf config.json 中 easyServer.autoStart 为 true 时随服务启动，默认第一次调用时启动
+func autoStartOf(cfg map[string]any) bool {
+	easyServer, _ := cfg["easyServer"].(map[string]any)
+	autoStart, _ := easyServer["autoStart"].(bool)
+	return autoStart
 }
 
-////////////////////////////////////////////////////////////
-// 注册模型
-////////////////////////////////////////////////////////////
-
 func registerModel(info domain.LocalModelConfigInfo) error {
-	key := info.Name + "|" + info.Version
+	key := domain.ModelKey(info.Name, info.Version)
 	path := filepath.Clean(info.Path)
 
-	db := sqllite.GetSession()
-
-	// 只查这一条，不再 load 全表
-	var row domain.LocalModelRegistryModel
-	err := db.Where("key = ?", key).First(&row).Error
-
-	if err == nil {
-		// ---- 保留你原来的判断逻辑 ----
-		if filepath.Clean(row.LocalPath) == path {
-			log.Warn("重复注册模型", zap.String("key", key))
-			return errs.New("模型已存在")
-		}
-
-		log.Warn("模型路径更新",
-			zap.String("old", row.LocalPath),
-			zap.String("new", path),
-		)
-
-		// 你原来只更新 LocalPath + Config（语义保持一致）
-		configRaw, jerr := json.Marshal(info.Config)
-		if jerr != nil {
-			return jerr
-		}
-
-		return db.Model(&domain.LocalModelRegistryModel{}).
-			Where("key = ?", key).
-			Updates(map[string]any{
-				"local_path": path,
-				"config":     string(configRaw),
-			}).Error
-	}
-
-	if err != nil && err != gorm.ErrRecordNotFound {
-		return err
-	}
-
-	// 不存在：新增（只插这一条）
 	rec := ModelRecord{
 		Key:       key,
 		Name:      info.Name,
 		Title:     info.Title,
 		Version:   info.Version,
 		Type:      "localDir",
-		AutoStart: true,
+		AutoStart: autoStartOf(info.Config),
 		Functions: info.Functions,
 		LocalPath: path,
 		Settings:  info.Settings,
@@ -607,11 +535,25 @@ func registerModel(info domain.LocalModelConfigInfo) error {
 		Config:    info.Config,
 		Status:    info.Status,
 	}
-
-	newRow, err := convertRecordToDB(rec)
+	row, err := convertRecordToDB(rec)
 	if err != nil {
 		return err
 	}
 
-	return db.Create(&newRow).Error
+	// 不存在时新增；已存在且路径相同视为重复注册，路径不同只更新 localPath 和 config，保留状态和用户设置
+	_, err = sqllite.ModelRegistry().Upsert(row, func(old *domain.LocalModelRegistryModel) error {
+		if filepath.Clean(old.LocalPath) == path {
+			log.Warn("重复注册模型", zap.String("key", key))
+			return errs.New("模型已存在")
+		}
+
+		log.Warn("模型路径更新",
+			zap.String("old", old.LocalPath),
+			zap.String("new", path),
+		)
+		old.LocalPath = row.LocalPath
+		old.Config = row.Config
+		return nil
+	})
+	return err
 }
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"xiacutai-server/internal/component/errs"
+	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/component/modelcall/cloud"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/component/sqllite"
+	"xiacutai-server/internal/domain"
+	"xiacutai-server/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// CloudModelRequest 注册云端模型：模型信息 + cloud 配置（endpoint、凭证、远程模型名）
+type CloudModelRequest struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Functions   []string `json:"functions"` // soundTts / asr，默认两者都有
+	cloud.Config
+}
+
+// maskedAPIKey 列表中返回的凭证
+const maskedAPIKey = "******"
+
+// ModelCloudAdd 注册或更新云端模型，同 key 的云端模型直接覆盖配置
+func (s *model) ModelCloudAdd(req CloudModelRequest) (domain.LocalModelConfigInfo, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Version = strings.TrimSpace(req.Version)
+	if req.Name == "" || req.Version == "" {
+		return domain.LocalModelConfigInfo{}, errs.New("name 和 version 不能为空")
+	}
+	if len(req.Functions) == 0 {
+		req.Functions = []string{string(easyserver.FunctionSoundTts), string(easyserver.FunctionAsr)}
+	}
+	for _, function := range req.Functions {
+		if !cloud.Supported(function) {
+			return domain.LocalModelConfigInfo{}, errs.New("云端模型不支持的功能: " + function)
+		}
+	}
+
+	key := domain.ModelKey(req.Name, req.Version)
+	// 更新时不传凭证（或传回列表中的掩码）则沿用原来的
+	if req.APIKey == "" || req.APIKey == maskedAPIKey {
+		req.APIKey = ""
+		if old, err := Model.Get(key); err == nil && isCloudModel(old) {
+			if oldCfg, err := cloudConfigOf(old); err == nil {
+				req.APIKey = oldCfg.APIKey
+			}
+		}
+	}
+	if err := req.Config.Validate(); err != nil {
+		return domain.LocalModelConfigInfo{}, err
+	}
+
+	raw, err := json.Marshal(map[string]any{
+		"name":          req.Name,
+		"version":       req.Version,
+		"title":         firstNonEmpty(req.Title, req.Name),
+		"description":   req.Description,
+		"serverRequire": "*",
+		"functions":     req.Functions,
+		"cloud":         req.Config,
+	})
+	if err != nil {
+		return domain.LocalModelConfigInfo{}, err
+	}
+	var cfg map[string]any
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return domain.LocalModelConfigInfo{}, err
+	}
+	info, err := parseConfigToInfo(cfg, "")
+	if err != nil {
+		return domain.LocalModelConfigInfo{}, err
+	}
+	info.Key = key
+	info.Type = string(easyserver.ServerCloud)
+
+	log.Info("注册云端模型",
+		zap.String("key", key),
+		zap.String("provider", req.Provider),
+		zap.String("endpoint", req.Endpoint),
+		zap.String("model", req.Model),
+	)
+	if err := registerCloudModel(info); err != nil {
+		return domain.LocalModelConfigInfo{}, err
+	}
+	maskCloudSecret(&info)
+	return info, nil
+}
+
+func registerCloudModel(info domain.LocalModelConfigInfo) error {
+	rec := ModelRecord{
+		Key:       info.Key,
+		Name:      info.Name,
+		Title:     info.Title,
+		Version:   info.Version,
+		Type:      string(easyserver.ServerCloud),
+		Functions: info.Functions,
+		Settings:  info.Settings,
+		Setting:   info.Setting,
+		Config:    info.Config,
+		Status:    info.Status,
+	}
+	row, err := convertRecordToDB(rec)
+	if err != nil {
+		return err
+	}
+
+	// 同名的本地模型不允许覆盖，已有的云端模型只更新标题、功能和配置
+	_, err = sqllite.ModelRegistry().Upsert(row, func(old *domain.LocalModelRegistryModel) error {
+		if old.Type != string(easyserver.ServerCloud) {
+			return errs.New("模型已存在")
+		}
+		old.Title = row.Title
+		old.Functions = row.Functions
+		old.Config = row.Config
+		return nil
+	})
+	return err
+}
+
+func isCloudModel(info *domain.LocalModelConfigInfo) bool {
+	return info != nil && info.Type == string(easyserver.ServerCloud)
+}
+
+// isCloudModelKey key 对应的是否为云端模型，模型不存在时返回 false，由后续启动报错
+func isCloudModelKey(key string) bool {
+	info, err := Model.Get(key)
+	return err == nil && isCloudModel(info)
+}
+
+func cloudConfigOf(info *domain.LocalModelConfigInfo) (cloud.Config, error) {
+	var cfg cloud.Config
+	raw, err := json.Marshal(info.Config["cloud"])
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, errs.New("云端模型配置损坏")
+	}
+	return cfg, nil
+}
+
+// maskCloudSecret 返回给前端的模型信息中隐藏凭证
+func maskCloudSecret(info *domain.LocalModelConfigInfo) {
+	if info.Config == nil {
+		return
+	}
+	cfg, ok := info.Config["cloud"].(map[string]any)
+	if !ok {
+		return
+	}
+	if key, _ := cfg["apiKey"].(string); key != "" {
+		cfg["apiKey"] = maskedAPIKey
+	}
+}
+
+// startCloudServerByKey 云端模型没有进程需要启动，只按配置创建适配器
+func startCloudServerByKey(serverKey string, cfg *TaskConfig) (*cloud.Server, error) {
+	info, err := Model.Get(serverKey)
+	if err != nil {
+		return nil, err
+	}
+	cloudCfg, err := cloudConfigOf(info)
+	if err != nil {
+		return nil, err
+	}
+	server, err := cloud.NewServer(cloudCfg, nil, utils.StorageDir)
+	if err != nil {
+		return nil, err
+	}
+	if cfg != nil {
+		server.TimeoutOverride = cfg.callTimeout().Total
+	}
+	return server, nil
+}
+
+// cloudCacheModel 云端模型的缓存 key 部分：规范化的 key 加上影响输出的云端配置
+// 不包含凭证和超时，更换 apiKey 后仍命中之前的结果；/model/cloudAdd 修改远程模型或音色后不再命中
+func cloudCacheModel(serverKey string, server *cloud.Server) resultCacheModel {
+	cfg := server.Config
+	return resultCacheModel{
+		Key: domain.NormalizeModelKey(serverKey),
+		Setting: map[string]any{
+			"provider":       cfg.Provider,
+			"endpoint":       cfg.Endpoint,
+			"model":          cfg.Model,
+			"voice":          cfg.Voice,
+			"responseFormat": cfg.ResponseFormat,
+		},
+	}
+}
+
+// runCloudTask 调用单个云端模型，与 runEasyServerTask 对应，任务取消时中断请求
+func runCloudTask(task domain.DataTaskModel, cfg *TaskConfig, serverKey string, call func(server *cloud.Server, data easyserver.ServerFunctionDataType) (*easyserver.TaskResult, error)) (*easyserver.TaskResult, error) {
+	server, err := startCloudServerByKey(serverKey, cfg)
+	if err != nil {
+		return nil, err
+	}
+	registerTaskCanceller(task.ID, server)
+	defer unregisterTaskServer(task.ID)
+
+	return call(server, easyserver.ServerFunctionDataType{
+		ID:     fmt.Sprintf("task-%d", task.ID),
+		Result: map[string]interface{}{},
+	})
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"xiacutai-server/internal/component/modelcall/cloud"
+	"xiacutai-server/internal/domain"
+)
+
+func TestCloudAsrTask(t *testing.T) {
+	resetTasks(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.FormValue("model") != "whisper-1" || r.FormValue("language") != "zh" {
+			t.Errorf("unexpected form %v", r.MultipartForm.Value)
+		}
+		_, _ = w.Write([]byte(`{"text":"你好 世界","duration":2.0,"segments":[{"start":0,"end":0.8,"text":"你好"},{"start":1.0,"end":1.85,"text":"世界"}]}`))
+	}))
+	defer srv.Close()
+
+	info, err := Model.ModelCloudAdd(CloudModelRequest{
+		Name:    "cloud-asr",
+		Version: "1.0",
+		Config:  cloud.Config{Endpoint: srv.URL + "/v1", APIKey: "sk-test", Model: "whisper-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = Model.ModelDelete(info.Name, info.Version) })
+
+	audio := filepath.Join(t.TempDir(), "in.wav")
+	if err := os.WriteFile(audio, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	task, err := DataTask.CreateFromRequest(TaskCreateRequest{
+		Type:      domain.FunctionSoundAsr,
+		ServerKey: "cloud-asr@1.0",
+		Audio:     audio,
+		Param:     map[string]any{"language": "zh"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := runTask(t, task.ID)
+	if got.Status != domain.TaskStatusSuccess {
+		t.Fatalf("expected success, got %s %s", got.Status, got.StatusMsg)
+	}
+	records, _ := taskResult(t, got)["records"].([]any)
+	if len(records) != 1 {
+		t.Fatalf("unexpected result %s", got.Result)
+	}
+	record := records[0].(map[string]any)
+	segments, _ := record["segments"].([]any)
+	if record["text"] != "你好 世界" || len(segments) != 2 {
+		t.Fatalf("transcript missing from result: %s", got.Result)
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/component/sqllite"
+
+	"go.uber.org/zap"
+)
+
+// StartResidentModels 在后台预先拉起注册表中 autoStart 的常驻模型（easyServer.resident=true 或 transport=http），不等待就绪
+// 与第一次调用时启动的进程一样空闲退出；启动进度和失败原因见 /model/status
+func StartResidentModels() {
+	rows, err := sqllite.ModelRegistry().List()
+	if err != nil {
+		log.Error("Load model registry failed", zap.Error(err))
+		return
+	}
+	for _, record := range rows {
+		if !record.AutoStart {
+			continue
+		}
+		info, err := Model.Get(record.Key)
+		if err != nil || isCloudModel(info) {
+			continue
+		}
+		serverInfo, err := registerServerRuntime(record.Key, info)
+		if err != nil {
+			log.Warn("Load resident model failed", zap.String("key", record.Key), zap.Error(err))
+			continue
+		}
+		if !serverInfo.Config.EasyServer.Persistent() {
+			continue
+		}
+		go func(key string) {
+			if err := easyserver.Servers.Preload(key); err != nil {
+				log.Error("Start resident model failed", zap.String("key", key), zap.Error(err))
+			}
+		}(record.Key)
+	}
+}
+
+// StopResidentModels 停止所有常驻模型进程
+func StopResidentModels() {
+	easyserver.StopAllResidents()
+}
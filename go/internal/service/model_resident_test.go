@@ -0,0 +1,82 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/component/modelcall/modeltest"
+	"xiacutai-server/internal/component/sqllite"
+	"xiacutai-server/internal/domain"
+)
+
+// waitModelState 等待模型状态满足 cond
+func waitModelState(t *testing.T, key string, cond func(state easyserver.ServerState) bool) easyserver.ServerState {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		state, err := Model.ModelStatus(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cond(state) {
+			return state
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected model state %+v", state)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestStartResidentModels(t *testing.T) {
+	// 默认不随服务启动
+	basic := addFakeModel(t)
+	if row, err := sqllite.ModelRegistry().Get(basic); err != nil || row.AutoStart {
+		t.Fatalf("autoStart should default to false: %+v %v", row, err)
+	}
+
+	dir := modeltest.ModelDir(t, "resident")
+	raw, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg map[string]any
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	easyServer := cfg["easyServer"].(map[string]any)
+	easyServer["autoStart"] = true
+	easyServer["idleShutdown"] = 1
+	raw, _ = json.Marshal(cfg)
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := Model.ModelAdd(filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = Model.ModelDelete(info.Name, info.Version) })
+	key := domain.ModelKey(info.Name, info.Version)
+
+	start := time.Now()
+	StartResidentModels()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("StartResidentModels should not wait for models, took %s", elapsed)
+	}
+
+	state := waitModelState(t, key, func(s easyserver.ServerState) bool { return s.Resident != nil })
+	if state.Resident.KeepAlive || state.Status == easyserver.ServerError {
+		t.Fatalf("auto-started model should honor idle shutdown: %+v", state)
+	}
+	if state, _ := Model.ModelStatus(basic); state.Resident != nil || state.Status != easyserver.ServerStopped {
+		t.Fatalf("basic model should not start: %+v", state)
+	}
+
+	// 空闲 idleShutdown 后退出，状态回到 stopped
+	waitModelState(t, key, func(s easyserver.ServerState) bool {
+		return s.Resident == nil && s.Status == easyserver.ServerStopped
+	})
+}
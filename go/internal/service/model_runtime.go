@@ -0,0 +1,103 @@
+package service
+
+import (
+	"path/filepath"
+	"xiacutai-server/internal/component/errs"
+	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/component/modelcall"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+// registerServerRuntime 按注册表生成 ServerInfo 并同步到模型运行时，启动/停止/状态与任务调用按同一份配置
+func registerServerRuntime(serverKey string, modelInfo *domain.LocalModelConfigInfo) (*easyserver.ServerInfo, error) {
+	serverConfig, err := modelcall.LoadConfigFromJSON(filepath.Join(modelInfo.Path, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+	serverInfo := &easyserver.ServerInfo{LocalPath: modelInfo.Path, Name: modelInfo.Name, Version: modelInfo.Version, Setting: modelInfo.Setting, Config: *serverConfig}
+	easyserver.Servers.Register(serverKey, *serverInfo)
+	return serverInfo, nil
+}
+
+// runtimeModel 取模型信息，本地模型同时注册到运行时（云端模型没有运行时）
+func runtimeModel(key string) (*domain.LocalModelConfigInfo, error) {
+	info, err := Model.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if isCloudModel(info) {
+		return info, nil
+	}
+	if _, err := registerServerRuntime(key, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ModelStart 启动模型：常驻模型拉起进程并等待就绪，一次性模型只检查入口
+func (s *model) ModelStart(key string) (easyserver.ServerState, error) {
+	key = domain.NormalizeModelKey(key)
+	info, err := runtimeModel(key)
+	if err != nil {
+		return easyserver.ServerState{}, err
+	}
+	if isCloudModel(info) {
+		return easyserver.ServerState{}, errs.New("云端模型无需启动")
+	}
+	log.Info("启动模型", zap.String("key", key))
+	if err := easyserver.Servers.Start(key); err != nil {
+		log.Warn("启动模型失败", zap.String("key", key), zap.Error(err))
+		return easyserver.ServerState{}, errs.New("启动模型失败: " + err.Error())
+	}
+	return easyserver.Servers.Status(key)
+}
+
+// ModelStop 停止模型：取消进行中的调用（包括正在执行的任务）并结束常驻进程
+func (s *model) ModelStop(key string) (easyserver.ServerState, error) {
+	key = domain.NormalizeModelKey(key)
+	info, err := runtimeModel(key)
+	if err != nil {
+		return easyserver.ServerState{}, err
+	}
+	if isCloudModel(info) {
+		return easyserver.ServerState{}, errs.New("云端模型无需停止")
+	}
+	log.Info("停止模型", zap.String("key", key))
+	if err := easyserver.Servers.Stop(key); err != nil {
+		return easyserver.ServerState{}, err
+	}
+	return easyserver.Servers.Status(key)
+}
+
+// ModelStatus 模型运行状态，云端模型总是 running
+func (s *model) ModelStatus(key string) (easyserver.ServerState, error) {
+	key = domain.NormalizeModelKey(key)
+	info, err := runtimeModel(key)
+	if err != nil {
+		return easyserver.ServerState{}, err
+	}
+	if isCloudModel(info) {
+		return easyserver.ServerState{Key: key, Name: info.Name, Version: info.Version, Status: easyserver.ServerRunning}, nil
+	}
+	return easyserver.Servers.Status(key)
+}
+
+// ModelStatusList 注册表中所有模型的运行状态，配置损坏的模型为 error
+func (s *model) ModelStatusList() ([]easyserver.ServerState, error) {
+	list, err := s.ModelList("")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]easyserver.ServerState, 0, len(list))
+	for _, info := range list {
+		state, err := s.ModelStatus(info.Key)
+		if err != nil {
+			state = easyserver.ServerState{Key: info.Key, Name: info.Name, Version: info.Version, Status: easyserver.ServerError, Error: err.Error()}
+		}
+		out = append(out, state)
+	}
+	return out, nil
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"xiacutai-server/internal/component/errs"
+	"xiacutai-server/internal/domain"
+)
+
+// 设置项类型（与前端表单组件一致），未列出的类型不做类型校验
+const (
+	fieldKindString = "string"
+	fieldKindNumber = "number"
+	fieldKindBool   = "boolean"
+	fieldKindAny    = ""
+)
+
+var fieldKinds = map[string]string{
+	"text":        fieldKindString,
+	"input":       fieldKindString,
+	"textarea":    fieldKindString,
+	"password":    fieldKindString,
+	"string":      fieldKindString,
+	"gpuSelector": fieldKindString,
+	"select":      fieldKindString,
+	"radio":       fieldKindString,
+	"inputNumber": fieldKindNumber,
+	"slider":      fieldKindNumber,
+	"number":      fieldKindNumber,
+	"switch":      fieldKindBool,
+	"checkbox":    fieldKindBool,
+	"boolean":     fieldKindBool,
+}
+
+// SettingOption 下拉/单选的可选值
+type SettingOption struct {
+	Value any    `json:"value"`
+	Label string `json:"label"`
+}
+
+// SettingField config.json 中 settings / easyServer.functions.<fn>.param 的一项
+type SettingField struct {
+	Name        string          `json:"name"`
+	Type        string          `json:"type"`
+	Title       string          `json:"title"`
+	Required    bool            `json:"required"`
+	Default     any             `json:"defaultValue"`
+	Min         *float64        `json:"min"`
+	Max         *float64        `json:"max"`
+	Options     []SettingOption `json:"options"`
+	Placeholder string          `json:"placeholder"`
+	Tips        string          `json:"tips"`
+}
+
+func (f SettingField) kind() string {
+	return fieldKinds[f.Type]
+}
+
+func (f SettingField) label() string {
+	return firstNonEmpty(f.Title, f.Name)
+}
+
+// parseSettingFields 解析设置项定义，兼容 defaultValue / default 两种写法，options 可以是字符串数组
+func parseSettingFields(raw []any) []SettingField {
+	fields := make([]SettingField, 0, len(raw))
+	for _, item := range raw {
+		m := asMap(item)
+		name := asString(m["name"])
+		if name == "" {
+			continue
+		}
+		field := SettingField{
+			Name:        name,
+			Type:        asString(m["type"]),
+			Title:       asString(m["title"]),
+			Required:    m["required"] == true,
+			Default:     m["defaultValue"],
+			Min:         toFloatPtr(m["min"]),
+			Max:         toFloatPtr(m["max"]),
+			Placeholder: asString(m["placeholder"]),
+			Tips:        asString(m["tips"]),
+		}
+		if field.Default == nil {
+			field.Default = m["default"]
+		}
+		if options, ok := m["options"].([]any); ok {
+			for _, option := range options {
+				if om, ok := option.(map[string]any); ok {
+					field.Options = append(field.Options, SettingOption{Value: om["value"], Label: asString(om["label"])})
+				} else {
+					field.Options = append(field.Options, SettingOption{Value: option, Label: fmt.Sprint(option)})
+				}
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// functionParamFields 取 easyServer.functions.<fn>.param
+func functionParamFields(config map[string]any, function string) []SettingField {
+	functions := asMap(asMap(config["easyServer"])["functions"])
+	raw, _ := asMap(functions[function])["param"].([]any)
+	return parseSettingFields(raw)
+}
+
+// validateFields 按定义校验并规范化取值：未定义的字段报错，缺失的字段补默认值，数字/布尔的字符串形式转成对应类型
+func validateFields(fields []SettingField, values map[string]any) (map[string]any, error) {
+	if name := undefinedField(fields, values); name != "" {
+		return nil, errs.New("存在未定义的设置字段: " + name)
+	}
+
+	out := make(map[string]any, len(fields))
+	for _, field := range fields {
+		value, ok := values[field.Name]
+		if !ok || value == nil {
+			value = field.Default
+		}
+		if value == nil || value == "" {
+			if field.Required {
+				return nil, errs.New(field.label() + " 不能为空")
+			}
+			if value != nil {
+				out[field.Name] = value
+			}
+			continue
+		}
+		normalized, err := field.normalize(value)
+		if err != nil {
+			return nil, err
+		}
+		out[field.Name] = normalized
+	}
+	return out, nil
+}
+
+func undefinedField(fields []SettingField, values map[string]any) string {
+	for name := range values {
+		found := false
+		for _, field := range fields {
+			if field.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return name
+		}
+	}
+	return ""
+}
+
+func (f SettingField) normalize(value any) (any, error) {
+	switch f.kind() {
+	case fieldKindNumber:
+		n, ok := toFloat(value)
+		if !ok {
+			return nil, errs.New(f.label() + " 必须是数字")
+		}
+		if f.Min != nil && n < *f.Min {
+			return nil, errs.New(fmt.Sprintf("%s 不能小于 %v", f.label(), *f.Min))
+		}
+		if f.Max != nil && n > *f.Max {
+			return nil, errs.New(fmt.Sprintf("%s 不能大于 %v", f.label(), *f.Max))
+		}
+		value = n
+	case fieldKindBool:
+		switch v := value.(type) {
+		case bool:
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, errs.New(f.label() + " 必须是布尔值")
+			}
+			value = b
+		default:
+			return nil, errs.New(f.label() + " 必须是布尔值")
+		}
+	case fieldKindString:
+		switch v := value.(type) {
+		case string:
+		case float64, int, int64, json.Number:
+			// gpuSelector 等经常直接传数字序号
+			value = fmt.Sprint(v)
+		default:
+			return nil, errs.New(f.label() + " 必须是字符串")
+		}
+	}
+
+	if len(f.Options) > 0 {
+		for _, option := range f.Options {
+			if fmt.Sprint(option.Value) == fmt.Sprint(value) {
+				return option.Value, nil
+			}
+		}
+		return nil, errs.New(fmt.Sprintf("%s 的取值 %v 不在可选范围内", f.label(), value))
+	}
+	return value, nil
+}
+
+// fieldsJSONSchema 把设置项定义转换成 JSON Schema，x-component 保留原始组件类型供前端渲染
+func fieldsJSONSchema(fields []SettingField) map[string]any {
+	properties := map[string]any{}
+	required := make([]string, 0)
+	order := make([]string, 0, len(fields))
+	for _, f := range fields {
+		prop := map[string]any{
+			"title":       f.label(),
+			"x-component": f.Type,
+		}
+		if kind := f.kind(); kind != fieldKindAny {
+			prop["type"] = kind
+		}
+		if f.Default != nil {
+			prop["default"] = f.Default
+		}
+		if f.Min != nil {
+			prop["minimum"] = *f.Min
+		}
+		if f.Max != nil {
+			prop["maximum"] = *f.Max
+		}
+		if f.Tips != "" {
+			prop["description"] = f.Tips
+		}
+		if f.Placeholder != "" {
+			prop["x-placeholder"] = f.Placeholder
+		}
+		if len(f.Options) > 0 {
+			enum := make([]any, 0, len(f.Options))
+			names := make([]string, 0, len(f.Options))
+			for _, option := range f.Options {
+				enum = append(enum, option.Value)
+				names = append(names, firstNonEmpty(option.Label, fmt.Sprint(option.Value)))
+			}
+			prop["enum"] = enum
+			prop["x-enumNames"] = names
+		}
+		if f.Required {
+			required = append(required, f.Name)
+		}
+		properties[f.Name] = prop
+		order = append(order, f.Name)
+	}
+	return map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+		"x-order":              order,
+	}
+}
+
+// ModelSchema 模型设置及各功能参数的 JSON Schema
+type ModelSchema struct {
+	Key       string                    `json:"key"`
+	Setting   map[string]any            `json:"setting"`
+	Functions map[string]map[string]any `json:"functions"`
+}
+
+func (s *model) ModelSchema(key string) (*ModelSchema, error) {
+	info, err := s.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	schema := &ModelSchema{
+		Key:       key,
+		Setting:   fieldsJSONSchema(parseSettingFields(info.Settings)),
+		Functions: map[string]map[string]any{},
+	}
+	for _, function := range info.Functions {
+		schema.Functions[function] = fieldsJSONSchema(functionParamFields(info.Config, function))
+	}
+	return schema, nil
+}
+
+// paramFunctionName 任务类型对应 config.json 中的功能名
+func paramFunctionName(taskType string) string {
+	if taskType == domain.FunctionSoundAsr {
+		return "asr"
+	}
+	return taskType
+}
+
+// validateTaskParam 按模型声明的功能参数校验任务 param，模型未声明参数时原样返回
+func validateTaskParam(model *domain.LocalModelConfigInfo, taskType string, param map[string]any) (map[string]any, error) {
+	if model == nil || model.Config == nil {
+		return param, nil
+	}
+	fields := functionParamFields(model.Config, paramFunctionName(taskType))
+	if len(fields) == 0 {
+		return param, nil
+	}
+	return validateFields(fields, param)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toFloatPtr(v any) *float64 {
+	if v == nil {
+		return nil
+	}
+	f, ok := toFloat(v)
+	if !ok {
+		return nil
+	}
+	return &f
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateFields(t *testing.T) {
+	one, ten := 1.0, 10.0
+	fields := []SettingField{
+		{Name: "speed", Type: "slider", Min: &one, Max: &ten, Default: 5.0},
+		{Name: "gpu", Type: "gpuSelector"},
+		{Name: "fp16", Type: "switch"},
+		{Name: "voice", Type: "select", Required: true, Options: []SettingOption{{Value: "a"}, {Value: "b"}}},
+		{Name: "mode", Type: "radio", Options: []SettingOption{{Value: 1.0}, {Value: 2.0}}},
+	}
+
+	cases := []struct {
+		name    string
+		values  map[string]any
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			values: map[string]any{"voice": "a"},
+			want:   map[string]any{"speed": 5.0, "voice": "a"},
+		},
+		{
+			name:   "string forms",
+			values: map[string]any{"speed": "2", "gpu": 0.0, "fp16": "true", "voice": "b", "mode": "2"},
+			want:   map[string]any{"speed": 2.0, "gpu": "0", "fp16": true, "voice": "b", "mode": 2.0},
+		},
+		{name: "undefined field", values: map[string]any{"voice": "a", "unknown": 1}, wantErr: true},
+		{name: "required", values: map[string]any{"voice": ""}, wantErr: true},
+		{name: "below min", values: map[string]any{"voice": "a", "speed": 0.5}, wantErr: true},
+		{name: "above max", values: map[string]any{"voice": "a", "speed": 11}, wantErr: true},
+		{name: "not a number", values: map[string]any{"voice": "a", "speed": "fast"}, wantErr: true},
+		{name: "not a bool", values: map[string]any{"voice": "a", "fp16": 1}, wantErr: true},
+		{name: "not an option", values: map[string]any{"voice": "c"}, wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := validateFields(fields, c.values)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got %v", c.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
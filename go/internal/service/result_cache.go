@@ -0,0 +1,340 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/component/sqllite"
+	"xiacutai-server/internal/domain"
+	"xiacutai-server/internal/utils"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const defaultResultCacheMaxMB = 2048
+
+// resultCacheService 生成结果缓存
+// 结果中的文件复制到 CacheDir/<key>/<写入时间>/ 下保存，命中时再复制一份到 StorageDir 给新任务使用，
+// 避免任务删除文件后缓存失效
+type resultCacheService struct {
+	mu sync.Mutex
+}
+
+var ResultCache = new(resultCacheService)
+
+// resultCacheEnabled AIGCPANEL_RESULT_CACHE=off 时关闭缓存
+func resultCacheEnabled() bool {
+	switch strings.ToLower(strings.TrimSpace(utils.GetEnv("AIGCPANEL_RESULT_CACHE", "on"))) {
+	case "off", "0", "false", "no":
+		return false
+	}
+	return true
+}
+
+// resultCacheMaxBytes 缓存目录大小上限，超出后按最近使用时间淘汰
+func resultCacheMaxBytes() int64 {
+	mb, err := strconv.ParseInt(utils.GetEnv("AIGCPANEL_RESULT_CACHE_MAX_MB", strconv.Itoa(defaultResultCacheMaxMB)), 10, 64)
+	if err != nil || mb <= 0 {
+		mb = defaultResultCacheMaxMB
+	}
+	return mb * 1024 * 1024
+}
+
+// resultCacheModel 缓存 key 中的模型部分
+type resultCacheModel struct {
+	Key     string         // name|version，写入缓存记录
+	Setting map[string]any // 模型收到的运行设置（setting），修改设置后不再命中之前的结果
+}
+
+// resultCacheKey 根据模型及其设置、功能、文本、参考声音内容和参数计算缓存 key
+func resultCacheKey(model resultCacheModel, function string, data easyserver.ServerFunctionDataType) string {
+	setting := model.Setting
+	if len(setting) == 0 {
+		setting = nil
+	}
+	payload := map[string]any{
+		"model":       model.Key,
+		"setting":     setting,
+		"function":    function,
+		"text":        data.Text,
+		"promptText":  data.PromptText,
+		"promptAudio": fileDigest(data.PromptAudio),
+		"param":       data.Param,
+	}
+	// map 序列化时 key 有序（包括嵌套的 map），同样的输入得到同样的 key
+	raw, _ := json.Marshal(payload)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// serverCacheModel 本地模型的 name|version 和当前生效的设置
+func serverCacheModel(server *easyserver.EasyServer) resultCacheModel {
+	if server.ServerInfo == nil {
+		return resultCacheModel{Key: domain.ModelKey(server.ServerConfig.Name, server.ServerConfig.Version)}
+	}
+	return resultCacheModel{
+		Key:     domain.ModelKey(server.ServerInfo.Name, server.ServerInfo.Version),
+		Setting: server.ServerInfo.Setting,
+	}
+}
+
+// fileDigest 文件内容的 sha256，文件不存在时使用路径本身
+func fileDigest(path string) string {
+	if path == "" {
+		return ""
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "path:" + path
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "path:" + path
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// callWithResultCache 命中缓存时直接返回缓存结果，否则调用模型并写入缓存
+// 本地模型的 model 用 serverCacheModel 取得
+func callWithResultCache(
+	model resultCacheModel,
+	function string,
+	data easyserver.ServerFunctionDataType,
+	noCache bool,
+	call func(easyserver.ServerFunctionDataType) (*easyserver.TaskResult, error),
+) (*easyserver.TaskResult, error) {
+	if noCache || !resultCacheEnabled() {
+		return call(data)
+	}
+
+	key := resultCacheKey(model, function, data)
+	if cached, ok := ResultCache.Get(key); ok {
+		log.Info("Result cache hit", zap.String("id", data.ID), zap.String("function", function), zap.String("key", key))
+		now := time.Now().Unix()
+		return &easyserver.TaskResult{
+			Code: 0,
+			Msg:  "ok",
+			Data: map[string]interface{}{
+				"type":   "success",
+				"start":  now,
+				"end":    now,
+				"data":   cached,
+				"cached": true,
+			},
+		}, nil
+	}
+
+	result, err := call(data)
+	if err != nil {
+		return nil, err
+	}
+	if resultData, err := extractResultData(result); err == nil {
+		if err := ResultCache.Put(key, model.Key, function, resultData); err != nil {
+			log.Warn("Result cache store failed", zap.String("key", key), zap.Error(err))
+		}
+	}
+	return result, nil
+}
+
+// Get 读取缓存，缓存的文件复制到 StorageDir 后返回
+func (s *resultCacheService) Get(key string) (map[string]any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []domain.DataResultCacheModel
+	if err := sqllite.GetSession().Where("cacheKey = ?", key).Limit(1).Find(&entries).Error; err != nil || len(entries) == 0 {
+		return nil, false
+	}
+	entry := entries[0]
+
+	var files []string
+	_ = json.Unmarshal([]byte(entry.Files), &files)
+	for _, file := range files {
+		if _, err := os.Stat(file); err != nil {
+			// 缓存文件被删除，整条缓存作废
+			s.remove(entry)
+			return nil, false
+		}
+	}
+
+	var cached map[string]any
+	if err := json.Unmarshal([]byte(entry.Result), &cached); err != nil {
+		s.remove(entry)
+		return nil, false
+	}
+	copied, err := rewriteResultFiles(cached, func(path string) (string, bool, error) {
+		if !utils.Contains(files, path) {
+			return path, false, nil
+		}
+		dst, err := utils.CopyToStorage(path)
+		return dst, true, err
+	})
+	if err != nil {
+		log.Warn("Result cache copy failed", zap.String("key", key), zap.Error(err))
+		return nil, false
+	}
+
+	sqllite.GetSession().Model(&domain.DataResultCacheModel{}).
+		Where("id = ?", entry.ID).
+		Updates(map[string]any{
+			"hitCount":   entry.HitCount + 1,
+			"lastUsedAt": time.Now().UnixMilli(),
+		})
+	return copied.(map[string]any), true
+}
+
+// Put 保存结果，结果中的本地文件复制到缓存目录
+// 每次写入使用新的目录，记录在事务中替换后再删除旧目录，不会覆盖其它进程正在读取的文件
+func (s *resultCacheService) Put(key, modelKey, function string, result map[string]any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	generation := strconv.FormatInt(time.Now().UnixNano(), 10)
+	dir := filepath.Join(utils.CacheDir, key, generation)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	files := make([]string, 0)
+	var size int64
+	stored, err := rewriteResultFiles(result, func(path string) (string, bool, error) {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			return path, false, nil
+		}
+		dst := filepath.Join(dir, fmt.Sprintf("%d_%s", len(files), filepath.Base(path)))
+		if err := copyFile(path, dst); err != nil {
+			return "", false, err
+		}
+		files = append(files, dst)
+		size += info.Size()
+		return dst, true, nil
+	})
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return err
+	}
+
+	resultRaw, err := json.Marshal(stored)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return err
+	}
+	filesRaw, err := json.Marshal(files)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return err
+	}
+
+	now := time.Now().UnixMilli()
+	err = sqllite.GetSession().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("cacheKey = ?", key).Delete(&domain.DataResultCacheModel{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&domain.DataResultCacheModel{
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			CacheKey:   key,
+			ModelKey:   modelKey,
+			Function:   function,
+			Result:     string(resultRaw),
+			Files:      string(filesRaw),
+			Size:       size,
+			LastUsedAt: now,
+		}).Error
+	})
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return err
+	}
+
+	// 删除同一个 key 之前写入的文件
+	if olds, err := os.ReadDir(filepath.Join(utils.CacheDir, key)); err == nil {
+		for _, old := range olds {
+			if old.Name() != generation {
+				_ = os.RemoveAll(filepath.Join(utils.CacheDir, key, old.Name()))
+			}
+		}
+	}
+
+	s.evict(resultCacheMaxBytes())
+	return nil
+}
+
+// evict 缓存总大小超过上限时，删除最久未使用的缓存
+func (s *resultCacheService) evict(maxBytes int64) {
+	var entries []domain.DataResultCacheModel
+	if err := sqllite.GetSession().Order("lastUsedAt ASC").Find(&entries).Error; err != nil {
+		log.Error("Load result cache failed", zap.Error(err))
+		return
+	}
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+	for _, entry := range entries {
+		if total <= maxBytes {
+			return
+		}
+		s.remove(entry)
+		total -= entry.Size
+	}
+}
+
+func (s *resultCacheService) remove(entry domain.DataResultCacheModel) {
+	if err := sqllite.GetSession().Delete(&domain.DataResultCacheModel{}, entry.ID).Error; err != nil {
+		log.Error("Delete result cache failed", zap.String("key", entry.CacheKey), zap.Error(err))
+		return
+	}
+	_ = os.RemoveAll(filepath.Join(utils.CacheDir, entry.CacheKey))
+}
+
+// rewriteResultFiles 遍历结果，对每个字符串调用 fn，fn 返回 true 时替换为新值
+func rewriteResultFiles(v any, fn func(string) (string, bool, error)) (any, error) {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			rewritten, err := rewriteResultFiles(item, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = rewritten
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			rewritten, err := rewriteResultFiles(item, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rewritten
+		}
+		return out, nil
+	case string:
+		if val == "" {
+			return val, nil
+		}
+		rewritten, ok, err := fn(val)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return rewritten, nil
+		}
+		return val, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"xiacutai-server/internal/component/modelcall/cloud"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/component/sqllite"
+	"xiacutai-server/internal/domain"
+	"xiacutai-server/internal/utils"
+)
+
+func TestResultCacheKeySetting(t *testing.T) {
+	data := easyserver.ServerFunctionDataType{Text: "你好", Param: map[string]any{"speed": 1.0}}
+	key := func(setting map[string]any) string {
+		return resultCacheKey(resultCacheModel{Key: "tts|1.0", Setting: setting}, domain.FunctionSoundTts, data)
+	}
+
+	base := key(map[string]any{"device": "cuda", "fp16": true})
+	if key(map[string]any{"fp16": true, "device": "cuda"}) != base {
+		t.Fatal("key should not depend on map order")
+	}
+	if key(map[string]any{"device": "cpu", "fp16": true}) == base {
+		t.Fatal("changing a setting should change the key")
+	}
+	if key(nil) != key(map[string]any{}) {
+		t.Fatal("nil and empty setting should share the key")
+	}
+	if key(nil) == base {
+		t.Fatal("setting should be part of the key")
+	}
+}
+
+func TestCloudCacheModel(t *testing.T) {
+	data := easyserver.ServerFunctionDataType{Text: "你好"}
+	key := func(serverKey string, cfg cloud.Config) string {
+		t.Helper()
+		server, err := cloud.NewServer(cfg, nil, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resultCacheKey(cloudCacheModel(serverKey, server), domain.FunctionSoundTts, data)
+	}
+
+	cfg := cloud.Config{Endpoint: "https://api.example.com/v1", APIKey: "sk-1", Model: "tts-1", Voice: "alloy"}
+	base := key("cloud-tts|1.0", cfg)
+	if key(" cloud-tts@1.0", cfg) != base {
+		t.Fatal("server key should be normalized")
+	}
+	rotated := cfg
+	rotated.APIKey = "sk-2"
+	if key("cloud-tts|1.0", rotated) != base {
+		t.Fatal("apiKey should not be part of the key")
+	}
+	for _, change := range []func(c *cloud.Config){
+		func(c *cloud.Config) { c.Voice = "nova" },
+		func(c *cloud.Config) { c.Model = "tts-1-hd" },
+		func(c *cloud.Config) { c.Endpoint = "https://other.example.com/v1" },
+		func(c *cloud.Config) { c.ResponseFormat = "mp3" },
+	} {
+		changed := cfg
+		change(&changed)
+		if key("cloud-tts|1.0", changed) == base {
+			t.Errorf("config change %+v should change the key", changed)
+		}
+	}
+}
+
+// resetResultCache 清空缓存记录和缓存目录
+func resetResultCache(t *testing.T) {
+	t.Helper()
+	wipe := func() {
+		if err := sqllite.GetSession().Where("1 = 1").Delete(&domain.DataResultCacheModel{}).Error; err != nil {
+			t.Fatal(err)
+		}
+		entries, _ := os.ReadDir(utils.CacheDir)
+		for _, entry := range entries {
+			_ = os.RemoveAll(filepath.Join(utils.CacheDir, entry.Name()))
+		}
+	}
+	wipe()
+	t.Cleanup(wipe)
+}
+
+// resultFile 在临时目录中生成 size 字节的结果文件
+func resultFile(t *testing.T, name string, size int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, bytes.Repeat([]byte("a"), size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func cacheEntry(t *testing.T, key string) (domain.DataResultCacheModel, []string, bool) {
+	t.Helper()
+	var entries []domain.DataResultCacheModel
+	if err := sqllite.GetSession().Where("cacheKey = ?", key).Find(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) == 0 {
+		return domain.DataResultCacheModel{}, nil, false
+	}
+	var files []string
+	_ = json.Unmarshal([]byte(entries[0].Files), &files)
+	return entries[0], files, true
+}
+
+func TestResultCacheHit(t *testing.T) {
+	resetResultCache(t)
+	src := resultFile(t, "out.wav", 16)
+	if err := ResultCache.Put("hit", "tts|1.0", domain.FunctionSoundTts, map[string]any{"url": src, "text": "你好"}); err != nil {
+		t.Fatal(err)
+	}
+	// 任务删除自己的文件不影响缓存
+	_ = os.Remove(src)
+	_, files, _ := cacheEntry(t, "hit")
+	if len(files) != 1 || !strings.HasPrefix(files[0], filepath.Join(utils.CacheDir, "hit")) {
+		t.Fatalf("file should be copied into the cache dir: %v", files)
+	}
+
+	seen := map[string]bool{}
+	for i := 1; i <= 2; i++ {
+		got, ok := ResultCache.Get("hit")
+		if !ok {
+			t.Fatal("expected cache hit")
+		}
+		url, _ := got["url"].(string)
+		if filepath.Dir(url) != utils.StorageDir || seen[url] || got["text"] != "你好" {
+			t.Fatalf("hit should copy into storage: %v", got)
+		}
+		seen[url] = true
+		if raw, err := os.ReadFile(url); err != nil || len(raw) != 16 {
+			t.Fatalf("copied file: %d bytes, %v", len(raw), err)
+		}
+		if _, err := os.Stat(files[0]); err != nil {
+			t.Fatalf("cache copy should stay: %v", err)
+		}
+		if entry, _, _ := cacheEntry(t, "hit"); entry.HitCount != i {
+			t.Fatalf("hitCount %d, want %d", entry.HitCount, i)
+		}
+	}
+
+	// 同一个 key 再次写入时替换记录，旧文件随之删除
+	if err := ResultCache.Put("hit", "tts|1.0", domain.FunctionSoundTts, map[string]any{"url": resultFile(t, "new.wav", 8)}); err != nil {
+		t.Fatal(err)
+	}
+	_, newFiles, _ := cacheEntry(t, "hit")
+	if len(newFiles) != 1 || newFiles[0] == files[0] {
+		t.Fatalf("unexpected files after rewrite: %v", newFiles)
+	}
+	if _, err := os.Stat(files[0]); !os.IsNotExist(err) {
+		t.Fatalf("old cache file should be removed: %v", err)
+	}
+	if _, err := os.Stat(newFiles[0]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResultCacheInvalidatedByMissingFile(t *testing.T) {
+	resetResultCache(t)
+	if err := ResultCache.Put("missing", "tts|1.0", domain.FunctionSoundTts, map[string]any{"url": resultFile(t, "out.wav", 16)}); err != nil {
+		t.Fatal(err)
+	}
+	_, files, _ := cacheEntry(t, "missing")
+	_ = os.Remove(files[0])
+
+	if _, ok := ResultCache.Get("missing"); ok {
+		t.Fatal("missing cache file should invalidate the entry")
+	}
+	if _, _, ok := cacheEntry(t, "missing"); ok {
+		t.Fatal("entry should be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(utils.CacheDir, "missing")); !os.IsNotExist(err) {
+		t.Fatalf("cache dir should be removed: %v", err)
+	}
+}
+
+func TestCallWithResultCacheNoCache(t *testing.T) {
+	resetResultCache(t)
+	calls := 0
+	call := func(data easyserver.ServerFunctionDataType) (*easyserver.TaskResult, error) {
+		calls++
+		return &easyserver.TaskResult{Data: map[string]interface{}{
+			"type": "success",
+			"data": map[string]interface{}{"url": resultFile(t, "out.wav", 16)},
+		}}, nil
+	}
+	model := resultCacheModel{Key: "tts|1.0"}
+	data := easyserver.ServerFunctionDataType{Text: "你好"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := callWithResultCache(model, domain.FunctionSoundTts, data, true, call); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, ok := cacheEntry(t, resultCacheKey(model, domain.FunctionSoundTts, data)); ok || calls != 2 {
+		t.Fatalf("noCache should call the model and skip the cache: calls %d cached %v", calls, ok)
+	}
+
+	// 使用缓存时第二次直接命中
+	for i := 0; i < 2; i++ {
+		result, err := callWithResultCache(model, domain.FunctionSoundTts, data, false, call)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cached := result.Data.(map[string]interface{})["cached"] == true; cached != (i == 1) {
+			t.Fatalf("call %d: cached %v", i, cached)
+		}
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 model calls, got %d", calls)
+	}
+}
+
+func TestResultCacheEvict(t *testing.T) {
+	resetResultCache(t)
+	t.Setenv("AIGCPANEL_RESULT_CACHE_MAX_MB", "1")
+	put := func(key string) {
+		t.Helper()
+		if err := ResultCache.Put(key, "tts|1.0", domain.FunctionSoundTts, map[string]any{"url": resultFile(t, key+".wav", 400*1024)}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	put("a")
+	put("b")
+	// a 最近使用过，超出上限时先淘汰 b
+	if _, ok := ResultCache.Get("a"); !ok {
+		t.Fatal("expected cache hit")
+	}
+	time.Sleep(5 * time.Millisecond)
+	put("c")
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, _, ok := cacheEntry(t, key); ok != want {
+			t.Errorf("%s cached %v, want %v", key, ok, want)
+		}
+		if _, err := os.Stat(filepath.Join(utils.CacheDir, key)); (err == nil) != want {
+			t.Errorf("%s cache dir exists %v, want %v", key, err == nil, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/component/modelcall/modeltest"
+	"xiacutai-server/internal/component/sqllite"
+	"xiacutai-server/internal/domain"
+	"xiacutai-server/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "service-test-*")
+	if err != nil {
+		panic(err)
+	}
+	_ = os.Setenv("DataDir", dir)
+	_ = os.Setenv("AIGCPANEL_KILL_GRACE_MS", "300")
+	utils.InitDirs()
+	sqllite.Init()
+	code := m.Run()
+	easyserver.StopAllResidents()
+	modeltest.Cleanup()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// resetTasks 清空任务表，测试结束后也清空
+func resetTasks(t *testing.T) {
+	t.Helper()
+	wipe := func() {
+		if err := sqllite.GetSession().Where("1 = 1").Delete(&domain.DataTaskModel{}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	wipe()
+	t.Cleanup(wipe)
+}
+
+// newQueuedTask 创建一个排队中的任务，cfg 为任务的 modelConfig，task.Biz 为空时为 SoundGenerate
+func newQueuedTask(t *testing.T, cfg map[string]any, task domain.DataTaskModel) domain.DataTaskModel {
+	t.Helper()
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Biz == "" {
+		task.Biz = "SoundGenerate"
+	}
+	task.Status = domain.TaskStatusQueue
+	task.ModelConfig = string(raw)
+	created, err := DataTask.CreateTask(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created
+}
+
+func mustGetTask(t *testing.T, id int64) domain.DataTaskModel {
+	t.Helper()
+	task, err := DataTask.GetTask(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
@@ -18,11 +18,11 @@ func RecognizeSoundPromptText(audioPath string) (string, error) {
 		return "", err
 	}
 
-	server, err := startEasyServerByKey(serverKey)
+	server, release, err := startEasyServerByKey(serverKey, nil)
 	if err != nil {
 		return "", err
 	}
-	defer server.Stop()
+	defer release()
 
 	result, err := server.Asr(easyserver.ServerFunctionDataType{
 		ID:     fmt.Sprintf("storage-sound-asr-%d", time.Now().UnixMilli()),
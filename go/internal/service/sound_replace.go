@@ -11,7 +11,6 @@ import (
 	"strings"
 	"time"
 	"xiacutai-server/internal/component/errs"
-	"xiacutai-server/internal/component/modelcall"
 	"xiacutai-server/internal/component/modelcall/easyserver"
 	"xiacutai-server/internal/domain"
 	"xiacutai-server/internal/utils"
@@ -26,7 +25,7 @@ type soundReplaceRecord struct {
 	ActualEnd   int64  `json:"actualEnd,omitempty"`
 }
 
-func runSoundReplaceTask(task domain.DataTaskModel, cfg *taskConfig) error {
+func runSoundReplaceTask(task domain.DataTaskModel, cfg *TaskConfig) error {
 	videoPath := cfg.Video
 	if videoPath == "" {
 		return errs.New("video is required")
@@ -56,7 +55,7 @@ func runSoundReplaceTask(task domain.DataTaskModel, cfg *taskConfig) error {
 	return runSoundReplaceGeneratePhase(task, cfg, job)
 }
 
-func runSoundReplaceAsrPhase(task domain.DataTaskModel, cfg *taskConfig, job map[string]any) error {
+func runSoundReplaceAsrPhase(task domain.DataTaskModel, cfg *TaskConfig, job map[string]any) error {
 	videoPath := cfg.Video
 	if err := saveSoundReplaceProgress(task.ID, domain.TaskStatusRunning, job, nil, ""); err != nil {
 		return err
@@ -97,13 +96,13 @@ func runSoundReplaceAsrPhase(task domain.DataTaskModel, cfg *taskConfig, job map
 		return errs.New("soundAsr.serverKey is required")
 	}
 	asrParam := asMap(cfg.SoundAsr["param"])
-	asrServer, err := startEasyServerByKey(asrServerKey)
+	asrServer, releaseAsr, err := startEasyServerByKey(asrServerKey, cfg)
 	if err != nil {
 		return err
 	}
 	registerTaskServer(task.ID, asrServer)
 	asrResult, err := asrServer.Asr(easyserver.ServerFunctionDataType{ID: fmt.Sprintf("task-%d-asr", task.ID), Param: asrParam, Result: map[string]interface{}{}, Audio: audioPath})
-	_ = asrServer.Stop()
+	releaseAsr()
 	if err != nil {
 		unregisterTaskServer(task.ID)
 		return err
@@ -146,7 +145,7 @@ func runSoundReplaceAsrPhase(task domain.DataTaskModel, cfg *taskConfig, job map
 	return nil
 }
 
-func runSoundReplaceGeneratePhase(task domain.DataTaskModel, cfg *taskConfig, job map[string]any) error {
+func runSoundReplaceGeneratePhase(task domain.DataTaskModel, cfg *TaskConfig, job map[string]any) error {
 	confirm := asMap(job["Confirm"])
 	confirmRecords, err := parseSoundReplaceRecords(confirm["records"])
 	if err != nil {
@@ -184,13 +183,13 @@ func runSoundReplaceGeneratePhase(task domain.DataTaskModel, cfg *taskConfig, jo
 	if serverKey == "" {
 		return errs.New("soundGenerate server key is required")
 	}
-	server, err := startEasyServerByKey(serverKey)
+	server, release, err := startEasyServerByKey(serverKey, cfg)
 	if err != nil {
 		return err
 	}
 	registerTaskServer(task.ID, server)
 	defer func() {
-		_ = server.Stop()
+		release()
 		unregisterTaskServer(task.ID)
 	}()
 
@@ -213,7 +212,7 @@ func runSoundReplaceGeneratePhase(task domain.DataTaskModel, cfg *taskConfig, jo
 		} else {
 			rec.Text = text
 			rawOutput := filepath.Join(persistDir, fmt.Sprintf("sound_replace_%d_seg_%d_raw.wav", stamp, i))
-			if err := generateSpeechForRecord(task.ID, i, rec, cfg.SoundGenerate, server, rawOutput); err != nil {
+			if err := generateSpeechForRecord(task.ID, i, rec, cfg.SoundGenerate, cfg.NoCache, server, rawOutput); err != nil {
 				if err := createSilenceAudio(aligned, targetMs); err != nil {
 					return errs.New(fmt.Sprintf("segment %d generate failed: %v", i, err))
 				}
@@ -318,26 +317,69 @@ func saveSoundReplaceProgress(taskID int64, status string, jobResult map[string]
 		}
 		updates["result"] = string(retRaw)
 	}
-	_, err = DataTask.UpdateTask(taskID, updates)
-	return err
+	// 离开 running 即本次执行结束，释放租约
+	if status != domain.TaskStatusRunning {
+		updates["leaseOwner"] = ""
+		updates["leaseExpireAt"] = 0
+	}
+	ok, err := DataTask.UpdateOwnedTask(taskID, taskWorkerID, updates)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errTaskLeaseLost
+	}
+	publishSoundReplaceStep(taskID, jobResult)
+	return nil
+}
+
+// publishSoundReplaceStep 推送当前步骤，分段生成时附带已完成的分段数
+func publishSoundReplaceStep(taskID int64, job map[string]any) {
+	task, err := DataTask.GetTask(taskID)
+	if err != nil {
+		return
+	}
+	step := asString(job["step"])
+	var progress map[string]any
+	if step == "SoundGenerate" {
+		records, _ := parseSoundReplaceRecords(asMap(job["SoundGenerate"])["records"])
+		done := 0
+		for _, rec := range records {
+			if strings.TrimSpace(rec.Audio) != "" {
+				done++
+			}
+		}
+		progress = map[string]any{"current": done, "total": len(records)}
+	}
+	publishTaskStep(task, step, progress)
 }
 
-func startEasyServerByKey(serverKey string) (*easyserver.EasyServer, error) {
+// startEasyServerByKey 从模型运行时取一个调用用的模型，cfg 不为空时使用任务指定的超时
+// 调用计入 /model/status 的进行中调用数，停止模型时会被取消；用完后必须调用 release
+func startEasyServerByKey(serverKey string, cfg *TaskConfig) (*easyserver.EasyServer, func(), error) {
 	modelInfo, err := Model.Get(serverKey)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if isCloudModel(modelInfo) {
+		return nil, nil, errs.New("云端模型只支持语音合成和语音识别任务")
 	}
-	serverConfig, err := modelcall.LoadConfigFromJSON(modelInfo.Path + "/config.json")
+	runtimeKey := domain.ModelKey(modelInfo.Name, modelInfo.Version)
+	if _, err := registerServerRuntime(runtimeKey, modelInfo); err != nil {
+		return nil, nil, err
+	}
+	server, err := easyserver.Servers.Acquire(runtimeKey)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	serverInfo := &easyserver.ServerInfo{LocalPath: modelInfo.Path, Name: modelInfo.Name, Version: modelInfo.Version, Setting: modelInfo.Setting, Config: *serverConfig}
-	server := easyserver.NewEasyServer(*serverConfig)
-	server.ServerInfo = serverInfo
-	if err := server.Start(); err != nil {
-		return nil, err
+	if cfg != nil {
+		server.TimeoutOverride = cfg.callTimeout()
+	}
+	release := func() {
+		_ = server.Stop()
+		easyserver.Servers.Release(runtimeKey, server)
 	}
-	return server, nil
+	return server, release, nil
 }
 
 func parseAsrRecords(asrData map[string]any) ([]*soundReplaceRecord, error) {
@@ -389,7 +431,7 @@ func parseSoundReplaceRecords(v any) ([]*soundReplaceRecord, error) {
 	return records, nil
 }
 
-func generateSpeechForRecord(taskID int64, idx int, rec *soundReplaceRecord, soundGenerate map[string]any, server *easyserver.EasyServer, outputPath string) error {
+func generateSpeechForRecord(taskID int64, idx int, rec *soundReplaceRecord, soundGenerate map[string]any, noCache bool, server *easyserver.EasyServer, outputPath string) error {
 	generateType := strings.ToLower(asString(soundGenerate["type"]))
 	param := asMap(soundGenerate["ttsParam"])
 	if strings.Contains(generateType, "clone") {
@@ -401,9 +443,9 @@ func generateSpeechForRecord(taskID int64, idx int, rec *soundReplaceRecord, sou
 	if strings.Contains(generateType, "clone") {
 		data.PromptAudio = asString(soundGenerate["promptUrl"])
 		data.PromptText = asString(soundGenerate["promptText"])
-		result, err = server.SoundClone(data)
+		result, err = callWithResultCache(serverCacheModel(server), domain.FunctionSoundClone, data, noCache, server.SoundClone)
 	} else {
-		result, err = server.SoundTts(data)
+		result, err = callWithResultCache(serverCacheModel(server), domain.FunctionSoundTts, data, noCache, server.SoundTts)
 	}
 	if err != nil {
 		return err
@@ -559,7 +601,7 @@ func ffmpegReplaceVideoAudio(video, audio, output string) error {
 func ffprobeDurationMs(file string) (int64, error) {
 	out, err := exec.Command(GetFFprobePath(), "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", file).CombinedOutput()
 	if err != nil {
-		return 0, errs.New(fmt.Sprintf("ffprobe failed: %w, output: %s", err, string(out)))
+		return 0, errs.New(fmt.Sprintf("ffprobe failed: %v, output: %s", err, string(out)))
 	}
 	sec, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
 	if err != nil {
@@ -575,7 +617,7 @@ func runCommand(cmd string, args ...string) error {
 
 	out, err := exec.Command(cmd, args...).CombinedOutput()
 	if err != nil {
-		return errs.New(fmt.Sprintf("%s failed: %w, output: %s", cmd, err, string(out)))
+		return errs.New(fmt.Sprintf("%s failed: %v, output: %s", cmd, err, string(out)))
 	}
 	return nil
 }
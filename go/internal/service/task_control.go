@@ -7,17 +7,27 @@ import (
 	"xiacutai-server/internal/component/modelcall/easyserver"
 )
 
+// taskCanceller 正在执行任务的模型，本地进程或云端请求
+type taskCanceller interface {
+	Cancel() error
+}
+
 var taskServerRegistry = struct {
 	sync.Mutex
-	servers map[int64]*easyserver.EasyServer
+	servers map[int64]taskCanceller
 }{
-	servers: make(map[int64]*easyserver.EasyServer),
+	servers: make(map[int64]taskCanceller),
 }
 
 func registerTaskServer(taskID int64, server *easyserver.EasyServer) {
 	if server == nil {
 		return
 	}
+	server.OnProgress = newTaskProgressReporter(taskID)
+	registerTaskCanceller(taskID, server)
+}
+
+func registerTaskCanceller(taskID int64, server taskCanceller) {
 	taskServerRegistry.Lock()
 	defer taskServerRegistry.Unlock()
 	taskServerRegistry.servers[taskID] = server
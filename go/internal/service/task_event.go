@@ -0,0 +1,193 @@
+package service
+
+import (
+	"sync"
+	"time"
+	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+// 任务事件类型，状态变化时事件类型即任务状态（queue/running/wait/success/fail）
+const (
+	TaskEventCreated  = "created"  // 任务创建
+	TaskEventStep     = "step"     // 多步骤任务进入新的步骤
+	TaskEventProgress = "progress" // 步骤内进度（如声音替换的分段生成）
+)
+
+// TaskEvent 任务事件
+type TaskEvent struct {
+	Type      string         `json:"type"`
+	TaskID    int64          `json:"taskId"`
+	Biz       string         `json:"biz"`
+	Status    string         `json:"status"`
+	StatusMsg string         `json:"statusMsg,omitempty"`
+	Step      string         `json:"step,omitempty"`
+	Progress  map[string]any `json:"progress,omitempty"`
+	Time      int64          `json:"time"`
+}
+
+// TaskEventFilter 订阅过滤条件，零值表示不过滤
+type TaskEventFilter struct {
+	TaskID int64
+	Biz    string
+}
+
+func (f TaskEventFilter) match(event TaskEvent) bool {
+	if f.TaskID > 0 && f.TaskID != event.TaskID {
+		return false
+	}
+	if f.Biz != "" && f.Biz != event.Biz {
+		return false
+	}
+	return true
+}
+
+const taskEventBuffer = 64
+
+type taskEventSubscriber struct {
+	filter TaskEventFilter
+	ch     chan TaskEvent
+}
+
+// taskEventHub 进程内任务事件分发
+// 订阅者消费过慢时丢弃事件而不是阻塞任务执行，客户端可通过 list 接口补齐状态
+type taskEventHub struct {
+	mu          sync.Mutex
+	nextID      int64
+	subscribers map[int64]*taskEventSubscriber
+	lastStatus  map[int64]string // 最近一次推送的状态，避免重复推送
+	lastStep    map[int64]string
+}
+
+var TaskEvents = newTaskEventHub()
+
+func newTaskEventHub() *taskEventHub {
+	return &taskEventHub{
+		subscribers: make(map[int64]*taskEventSubscriber),
+		lastStatus:  make(map[int64]string),
+		lastStep:    make(map[int64]string),
+	}
+}
+
+// Subscribe 订阅任务事件，返回事件通道和取消订阅函数
+func (h *taskEventHub) Subscribe(filter TaskEventFilter) (<-chan TaskEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextID++
+	id := h.nextID
+	sub := &taskEventSubscriber{filter: filter, ch: make(chan TaskEvent, taskEventBuffer)}
+	h.subscribers[id] = sub
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers, id)
+			h.mu.Unlock()
+		})
+	}
+}
+
+// Subscribers 当前订阅数
+func (h *taskEventHub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+func (h *taskEventHub) Publish(event TaskEvent) {
+	if event.Time == 0 {
+		event.Time = time.Now().UnixMilli()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sub := range h.subscribers {
+		if !sub.filter.match(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			log.Warn("Task event dropped, subscriber too slow", zap.Int64("taskId", event.TaskID), zap.String("type", event.Type))
+		}
+	}
+}
+
+// publishTaskCreated 推送任务创建事件
+func publishTaskCreated(task domain.DataTaskModel) {
+	TaskEvents.mu.Lock()
+	TaskEvents.lastStatus[task.ID] = task.Status
+	TaskEvents.mu.Unlock()
+	TaskEvents.Publish(TaskEvent{
+		Type:   TaskEventCreated,
+		TaskID: task.ID,
+		Biz:    task.Biz,
+		Status: task.Status,
+	})
+}
+
+// publishTaskStatus 任务状态变化时推送，状态未变化不推送
+func publishTaskStatus(task domain.DataTaskModel) {
+	TaskEvents.mu.Lock()
+	if TaskEvents.lastStatus[task.ID] == task.Status {
+		TaskEvents.mu.Unlock()
+		return
+	}
+	if task.Status == domain.TaskStatusSuccess || task.Status == domain.TaskStatusFail {
+		delete(TaskEvents.lastStatus, task.ID)
+		delete(TaskEvents.lastStep, task.ID)
+	} else {
+		TaskEvents.lastStatus[task.ID] = task.Status
+	}
+	TaskEvents.mu.Unlock()
+
+	TaskEvents.Publish(TaskEvent{
+		Type:      task.Status,
+		TaskID:    task.ID,
+		Biz:       task.Biz,
+		Status:    task.Status,
+		StatusMsg: task.StatusMsg,
+	})
+}
+
+// publishTaskStatusByID 只知道任务 id 时，读取最新状态后推送
+func publishTaskStatusByID(taskID int64) {
+	task, err := DataTask.GetTask(taskID)
+	if err != nil {
+		return
+	}
+	publishTaskStatus(task)
+}
+
+// publishTaskStep 推送多步骤任务的步骤变化，步骤未变化时推送步骤内进度
+func publishTaskStep(task domain.DataTaskModel, step string, progress map[string]any) {
+	TaskEvents.mu.Lock()
+	changed := TaskEvents.lastStep[task.ID] != step
+	TaskEvents.lastStep[task.ID] = step
+	TaskEvents.mu.Unlock()
+
+	eventType := TaskEventStep
+	if !changed {
+		if progress == nil {
+			return
+		}
+		eventType = TaskEventProgress
+	}
+	TaskEvents.Publish(TaskEvent{
+		Type:     eventType,
+		TaskID:   task.ID,
+		Biz:      task.Biz,
+		Status:   task.Status,
+		Step:     step,
+		Progress: progress,
+	})
+}
+
+func forgetTaskEvents(taskID int64) {
+	TaskEvents.mu.Lock()
+	delete(TaskEvents.lastStatus, taskID)
+	delete(TaskEvents.lastStep, taskID)
+	TaskEvents.mu.Unlock()
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"xiacutai-server/internal/domain"
+)
+
+// drainEvents 取出通道中已经推送的事件
+func drainEvents(ch <-chan TaskEvent) []TaskEvent {
+	var events []TaskEvent
+	for {
+		select {
+		case event := <-ch:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestTaskEventHubFilter(t *testing.T) {
+	hub := newTaskEventHub()
+	oldHub := TaskEvents
+	TaskEvents = hub
+	t.Cleanup(func() { TaskEvents = oldHub })
+
+	byTask, unsubscribeTask := hub.Subscribe(TaskEventFilter{TaskID: 1})
+	defer unsubscribeTask()
+	byBiz, unsubscribeBiz := hub.Subscribe(TaskEventFilter{Biz: "SoundReplace"})
+	defer unsubscribeBiz()
+	all, unsubscribeAll := hub.Subscribe(TaskEventFilter{})
+	defer unsubscribeAll()
+
+	task1 := domain.DataTaskModel{ID: 1, Biz: "SoundGenerate", Status: domain.TaskStatusRunning}
+	task2 := domain.DataTaskModel{ID: 2, Biz: "SoundReplace", Status: domain.TaskStatusRunning}
+	task3 := domain.DataTaskModel{ID: 3, Biz: "VideoGen", Status: domain.TaskStatusRunning}
+	for _, task := range []domain.DataTaskModel{task1, task2, task3} {
+		publishTaskStatus(task)
+		publishTaskStatus(task) // 状态未变化不推送
+		publishTaskStep(task, "Asr", nil)
+		publishTaskStep(task, "Asr", nil) // 步骤未变化且没有进度不推送
+		publishTaskStep(task, "Asr", map[string]any{"current": 1, "total": 2})
+	}
+	task1.Status = domain.TaskStatusSuccess
+	publishTaskStatus(task1)
+
+	type event struct {
+		taskID    int64
+		eventType string
+	}
+	summary := func(events []TaskEvent) []event {
+		out := make([]event, 0, len(events))
+		for _, e := range events {
+			out = append(out, event{e.TaskID, e.Type})
+		}
+		return out
+	}
+	steps := func(id int64) []event {
+		return []event{{id, domain.TaskStatusRunning}, {id, TaskEventStep}, {id, TaskEventProgress}}
+	}
+	check := func(name string, got []TaskEvent, want []event) {
+		t.Helper()
+		if g := summary(got); !reflect.DeepEqual(g, want) {
+			t.Fatalf("%s: got %v, want %v", name, g, want)
+		}
+	}
+
+	gotTask := drainEvents(byTask)
+	check("taskId", gotTask, append(steps(1), event{1, domain.TaskStatusSuccess}))
+	if gotTask[1].Step != "Asr" || gotTask[2].Progress["total"] != 2 || gotTask[0].Biz != "SoundGenerate" {
+		t.Fatalf("unexpected event payload %+v", gotTask)
+	}
+	check("biz", drainEvents(byBiz), steps(2))
+	check("all", drainEvents(all), append(append(append(steps(1), steps(2)...), steps(3)...), event{1, domain.TaskStatusSuccess}))
+
+	// 取消订阅后不再收到事件
+	unsubscribeTask()
+	unsubscribeTask()
+	publishTaskStatus(domain.DataTaskModel{ID: 1, Biz: "SoundGenerate", Status: domain.TaskStatusQueue})
+	if got := drainEvents(byTask); len(got) != 0 {
+		t.Fatalf("unsubscribed channel received %v", got)
+	}
+	if n := hub.Subscribers(); n != 2 {
+		t.Fatalf("expected 2 subscribers, got %d", n)
+	}
+}
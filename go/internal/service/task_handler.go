@@ -0,0 +1,183 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"xiacutai-server/internal/component/errs"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/domain"
+	"xiacutai-server/internal/utils"
+)
+
+// TaskCreateRequest 创建任务的请求
+type TaskCreateRequest struct {
+	Text            string         `json:"text"`
+	Type            string         `json:"type"` // 功能类型
+	ServerKey       string         `json:"serverKey"`
+	VideoTemplateId int64          `json:"videoTemplateId"` // 数字人模板ID
+	Param           map[string]any `json:"param"`
+	SoundAsr        map[string]any `json:"soundAsr"`
+	SoundGenerate   map[string]any `json:"soundGenerate"`
+	PromptId        int64          `json:"promptId"`    // 声音克隆-声音ID
+	Audio           string         `json:"audio"`       // 语音转文字-声音文件
+	Video           string         `json:"video"`       // 声音替换-视频文件
+	Image           string         `json:"image"`       // 图像编辑/放大-输入图片
+	Mask            string         `json:"mask"`        // 图像编辑-蒙版图片，可选
+	Priority        int            `json:"priority"`    // 优先级，越大越先执行
+	CallbackURL     string         `json:"callbackUrl"` // 任务结束时回调的地址
+	Timeout         int            `json:"timeout"`     // 模型调用最长执行时间（秒），0 使用模型配置
+	IdleTimeout     int            `json:"idleTimeout"` // 多久没有中间结果视为超时（秒），0 使用模型配置
+	NoCache         bool           `json:"noCache"`     // 不使用结果缓存，总是重新生成
+}
+
+// TaskHandler 一种功能类型的任务处理器
+// 新增功能只需实现该接口并调用 RegisterTaskHandler，创建、调度、执行都会按类型找到对应处理器
+type TaskHandler interface {
+	// Type 功能类型，对应 modelConfig.type
+	Type() string
+	// Biz 任务所属业务，写入 DataTaskModel.Biz
+	Biz() string
+	// BuildConfig 根据创建请求生成 modelConfig
+	BuildConfig(req TaskCreateRequest) (map[string]any, error)
+	// Validate 执行前校验任务配置
+	Validate(cfg *TaskConfig) error
+	// ModelKeys 执行任务会占用的模型 key，用于并发控制
+	ModelKeys(task domain.DataTaskModel, cfg *TaskConfig) []string
+	// Execute 执行任务，返回 nil 结果表示处理器已自行保存任务状态（多步骤工作流）
+	Execute(task domain.DataTaskModel, cfg *TaskConfig) (*easyserver.TaskResult, error)
+	// ExtractResult 从模型返回中提取写入 DataTaskModel.Result 的数据
+	ExtractResult(result *easyserver.TaskResult) (map[string]any, error)
+}
+
+var taskHandlers = struct {
+	sync.RWMutex
+	handlers map[string]TaskHandler
+}{
+	handlers: make(map[string]TaskHandler),
+}
+
+// RegisterTaskHandler 注册任务处理器，同类型重复注册时后者覆盖前者
+func RegisterTaskHandler(handler TaskHandler) {
+	taskHandlers.Lock()
+	defer taskHandlers.Unlock()
+	taskHandlers.handlers[handler.Type()] = handler
+}
+
+func GetTaskHandler(taskType string) (TaskHandler, bool) {
+	taskHandlers.RLock()
+	defer taskHandlers.RUnlock()
+	handler, ok := taskHandlers.handlers[taskType]
+	return handler, ok
+}
+
+func getTaskHandler(taskType string) (TaskHandler, error) {
+	handler, ok := GetTaskHandler(taskType)
+	if !ok {
+		return nil, errs.New("不支持的功能")
+	}
+	return handler, nil
+}
+
+// CreateFromRequest 按功能类型生成配置并创建任务
+func (s *taskService) CreateFromRequest(req TaskCreateRequest) (domain.DataTaskModel, error) {
+	handler, err := getTaskHandler(req.Type)
+	if err != nil {
+		return domain.DataTaskModel{}, err
+	}
+
+	model := &domain.LocalModelConfigInfo{}
+	if req.ServerKey != "" {
+		model, err = Model.Get(req.ServerKey)
+		if err != nil {
+			return domain.DataTaskModel{}, err
+		}
+		if req.Param, err = validateTaskParam(model, handler.Type(), req.Param); err != nil {
+			return domain.DataTaskModel{}, err
+		}
+	}
+
+	modelConfig, err := handler.BuildConfig(req)
+	if err != nil {
+		return domain.DataTaskModel{}, err
+	}
+	modelConfig["type"] = handler.Type()
+	if req.Timeout > 0 {
+		modelConfig["timeout"] = req.Timeout
+	}
+	if req.IdleTimeout > 0 {
+		modelConfig["idleTimeout"] = req.IdleTimeout
+	}
+	if req.NoCache {
+		modelConfig["noCache"] = true
+	}
+	modelConfigRaw, err := json.Marshal(modelConfig)
+	if err != nil {
+		return domain.DataTaskModel{}, err
+	}
+	paramRaw, err := json.Marshal(map[string]any{})
+	if err != nil {
+		return domain.DataTaskModel{}, err
+	}
+
+	return s.CreateTask(domain.DataTaskModel{
+		Biz:           handler.Biz(),
+		Title:         req.Text,
+		Status:        domain.TaskStatusQueue,
+		ServerName:    model.Name,
+		ServerTitle:   model.Title,
+		ServerVersion: model.Version,
+		Param:         string(paramRaw),
+		ModelConfig:   string(modelConfigRaw),
+		JobResult:     "{}",
+		Result:        "{}",
+		Type:          1,
+		Priority:      req.Priority,
+		CallbackURL:   req.CallbackURL,
+	})
+}
+
+// resultExtractor 默认的结果提取：取模型返回的 data.data
+type resultExtractor struct{}
+
+func (resultExtractor) ExtractResult(result *easyserver.TaskResult) (map[string]any, error) {
+	return extractResultData(result)
+}
+
+// runEasyServerTask 从模型运行时取单个模型并执行一次调用
+func runEasyServerTask(task domain.DataTaskModel, cfg *TaskConfig, serverKey string, call func(server *easyserver.EasyServer, data easyserver.ServerFunctionDataType) (*easyserver.TaskResult, error)) (*easyserver.TaskResult, error) {
+	server, release, err := startEasyServerByKey(serverKey, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	registerTaskServer(task.ID, server)
+	defer unregisterTaskServer(task.ID)
+
+	return call(server, easyserver.ServerFunctionDataType{
+		ID:     fmt.Sprintf("task-%d", task.ID),
+		Result: map[string]interface{}{},
+	})
+}
+
+// uniqueModelKeys 把 key 规范成 name|version，去掉空 key 和重复 key
+// name@version 等别名和规范 key 占用同一个模型并发槽位
+func uniqueModelKeys(keys ...string) []string {
+	out := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = domain.NormalizeModelKey(key)
+		if key == "" || utils.Contains(out, key) {
+			continue
+		}
+		out = append(out, key)
+	}
+	return out
+}
+
+func requireServerKey(name, key string) error {
+	if strings.TrimSpace(key) == "" {
+		return errs.New(name + " is required")
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"encoding/json"
+	"xiacutai-server/internal/component/errs"
+	"xiacutai-server/internal/component/modelcall/cloud"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/domain"
+)
+
+func init() {
+	RegisterTaskHandler(soundTtsHandler{})
+	RegisterTaskHandler(soundCloneHandler{})
+	RegisterTaskHandler(soundAsrHandler{})
+	RegisterTaskHandler(videoGenHandler{})
+	RegisterTaskHandler(videoGenFlowHandler{})
+	RegisterTaskHandler(soundReplaceHandler{})
+}
+
+// fillPrompt 根据 promptId 补充参考声音信息
+func fillPrompt(target map[string]any, promptId int64) error {
+	storageModel, err := DataStorage.GetStorage(promptId)
+	if err != nil {
+		return err
+	}
+	var prompt SoundPromptContent
+	_ = json.Unmarshal([]byte(storageModel.Content), &prompt)
+	target["promptId"] = promptId
+	target["promptTitle"] = storageModel.Title
+	target["promptUrl"] = prompt.URL
+	target["promptText"] = prompt.PromptText
+	return nil
+}
+
+// fillServerInfo 补充模型名称、标题、版本
+func fillServerInfo(target map[string]any, serverKey string) error {
+	model, err := Model.Get(serverKey)
+	if err != nil {
+		return err
+	}
+	target["serverName"] = model.Name
+	target["serverTitle"] = model.Title
+	target["serverVersion"] = model.Version
+	return nil
+}
+
+// ---------- soundTts ----------
+
+type soundTtsHandler struct{ resultExtractor }
+
+func (soundTtsHandler) Type() string { return domain.FunctionSoundTts }
+func (soundTtsHandler) Biz() string  { return "SoundGenerate" }
+
+func (soundTtsHandler) BuildConfig(req TaskCreateRequest) (map[string]any, error) {
+	return map[string]any{
+		"ttsServerKey": req.ServerKey,
+		"ttsParam":     req.Param,
+		"text":         req.Text,
+	}, nil
+}
+
+func (soundTtsHandler) Validate(cfg *TaskConfig) error {
+	return requireServerKey("ttsServerKey", cfg.TtsServerKey)
+}
+
+func (soundTtsHandler) ModelKeys(task domain.DataTaskModel, cfg *TaskConfig) []string {
+	return uniqueModelKeys(cfg.TtsServerKey)
+}
+
+func (soundTtsHandler) Execute(task domain.DataTaskModel, cfg *TaskConfig) (*easyserver.TaskResult, error) {
+	if isCloudModelKey(cfg.TtsServerKey) {
+		return runCloudTask(task, cfg, cfg.TtsServerKey, func(server *cloud.Server, data easyserver.ServerFunctionDataType) (*easyserver.TaskResult, error) {
+			data.Param = cfg.TtsParam
+			data.Text = cfg.Text
+			return callWithResultCache(cloudCacheModel(cfg.TtsServerKey, server), domain.FunctionSoundTts, data, cfg.NoCache, server.SoundTts)
+		})
+	}
+	return runEasyServerTask(task, cfg, cfg.TtsServerKey, func(server *easyserver.EasyServer, data easyserver.ServerFunctionDataType) (*easyserver.TaskResult, error) {
+		data.Param = cfg.TtsParam
+		data.Text = cfg.Text
+		return callWithResultCache(serverCacheModel(server), domain.FunctionSoundTts, data, cfg.NoCache, server.SoundTts)
+	})
+}
+
+// ---------- soundClone ----------
+
+type soundCloneHandler struct{ resultExtractor }
+
+func (soundCloneHandler) Type() string { return domain.FunctionSoundClone }
+func (soundCloneHandler) Biz() string  { return "SoundGenerate" }
+
+func (soundCloneHandler) BuildConfig(req TaskCreateRequest) (map[string]any, error) {
+	config := map[string]any{
+		"cloneServerKey": req.ServerKey,
+		"cloneParam":     req.Param,
+		"text":           req.Text,
+	}
+	// 参考声音不存在时仍允许创建，执行时由模型报错
+	if err := fillPrompt(config, req.PromptId); err != nil {
+		config["promptId"] = req.PromptId
+	}
+	return config, nil
+}
+
+func (soundCloneHandler) Validate(cfg *TaskConfig) error {
+	return requireServerKey("cloneServerKey", cfg.CloneServerKey)
+}
+
+func (soundCloneHandler) ModelKeys(task domain.DataTaskModel, cfg *TaskConfig) []string {
+	return uniqueModelKeys(cfg.CloneServerKey)
+}
+
+func (soundCloneHandler) Execute(task domain.DataTaskModel, cfg *TaskConfig) (*easyserver.TaskResult, error) {
+	return runEasyServerTask(task, cfg, cfg.CloneServerKey, func(server *easyserver.EasyServer, data easyserver.ServerFunctionDataType) (*easyserver.TaskResult, error) {
+		data.Param = cfg.CloneParam
+		data.PromptAudio = cfg.PromptURL
+		data.PromptText = cfg.PromptText
+		data.Text = cfg.Text
+		return callWithResultCache(serverCacheModel(server), domain.FunctionSoundClone, data, cfg.NoCache, server.SoundClone)
+	})
+}
+
+// ---------- soundAsr ----------
+
+type soundAsrHandler struct{ resultExtractor }
+
+func (soundAsrHandler) Type() string { return domain.FunctionSoundAsr }
+func (soundAsrHandler) Biz() string  { return "SoundAsr" }
+
+func (soundAsrHandler) BuildConfig(req TaskCreateRequest) (map[string]any, error) {
+	if req.Param == nil {
+		req.Param = map[string]any{}
+	}
+	return map[string]any{
+		"serverKey": req.ServerKey,
+		"audio":     req.Audio,
+		"param":     req.Param,
+	}, nil
+}
+
+func (soundAsrHandler) Validate(cfg *TaskConfig) error {
+	if err := requireServerKey("serverKey", cfg.ServerKey); err != nil {
+		return err
+	}
+	if cfg.Audio == "" {
+		return errs.New("audio is required")
+	}
+	return nil
+}
+
+func (soundAsrHandler) ModelKeys(task domain.DataTaskModel, cfg *TaskConfig) []string {
+	return uniqueModelKeys(cfg.ServerKey)
+}
+
+func (soundAsrHandler) Execute(task domain.DataTaskModel, cfg *TaskConfig) (*easyserver.TaskResult, error) {
+	if isCloudModelKey(cfg.ServerKey) {
+		return runCloudTask(task, cfg, cfg.ServerKey, func(server *cloud.Server, data easyserver.ServerFunctionDataType) (*easyserver.TaskResult, error) {
+			data.Param = cfg.VideoParam
+			data.Audio = cfg.Audio
+			return server.Asr(data)
+		})
+	}
+	return runEasyServerTask(task, cfg, cfg.ServerKey, func(server *easyserver.EasyServer, data easyserver.ServerFunctionDataType) (*easyserver.TaskResult, error) {
+		data.Param = cfg.VideoParam
+		data.Audio = cfg.Audio
+		return server.Asr(data)
+	})
+}
+
+// ---------- videoGen ----------
+
+type videoGenHandler struct{ resultExtractor }
+
+func (videoGenHandler) Type() string { return domain.FunctionVideoGen }
+func (videoGenHandler) Biz() string  { return "VideoGen" }
+
+func (videoGenHandler) BuildConfig(req TaskCreateRequest) (map[string]any, error) {
+	return map[string]any{
+		"serverKey": req.ServerKey,
+		"video":     req.Video,
+		"audio":     req.Audio,
+		"param":     req.Param,
+	}, nil
+}
+
+func (videoGenHandler) Validate(cfg *TaskConfig) error {
+	return requireServerKey("serverKey", cfg.ServerKey)
+}
+
+func (videoGenHandler) ModelKeys(task domain.DataTaskModel, cfg *TaskConfig) []string {
+	return uniqueModelKeys(cfg.ServerKey)
+}
+
+func (videoGenHandler) Execute(task domain.DataTaskModel, cfg *TaskConfig) (*easyserver.TaskResult, error) {
+	return runEasyServerTask(task, cfg, cfg.ServerKey, func(server *easyserver.EasyServer, data easyserver.ServerFunctionDataType) (*easyserver.TaskResult, error) {
+		data.Param = cfg.VideoParam
+		data.Video = cfg.Video
+		data.Audio = cfg.Audio
+		return server.VideoGen(data)
+	})
+}
+
+// ---------- videoGenFlow ----------
+
+// videoGenFlowHandler 数字人工作流：先生成声音再生成视频，结果由工作流自行保存
+type videoGenFlowHandler struct{ resultExtractor }
+
+func (videoGenFlowHandler) Type() string { return domain.FunctionVideoGenFlow }
+func (videoGenFlowHandler) Biz() string  { return "VideoGenFlow" }
+
+func (videoGenFlowHandler) BuildConfig(req TaskCreateRequest) (map[string]any, error) {
+	if req.SoundGenerate == nil {
+		req.SoundGenerate = map[string]any{}
+	}
+	soundGenerateServerKey := asString(req.SoundGenerate["cloneServerKey"])
+	if soundGenerateServerKey == "" {
+		soundGenerateServerKey = asString(req.SoundGenerate["ttsServerKey"])
+	}
+	if soundGenerateServerKey != "" {
+		if err := fillServerInfo(req.SoundGenerate, soundGenerateServerKey); err != nil {
+			return nil, err
+		}
+	}
+	if promptId := toInt64(req.SoundGenerate["promptId"]); promptId > 0 {
+		_ = fillPrompt(req.SoundGenerate, promptId)
+	}
+
+	videoTemplate, err := DataVideoTemplate.Get(req.VideoTemplateId)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"videoTemplateId":   req.VideoTemplateId,
+		"videoTemplateName": videoTemplate.Name,
+		"videoTemplateUrl":  videoTemplate.Video,
+		"soundGenerate":     req.SoundGenerate,
+		"text":              req.Text,
+	}, nil
+}
+
+func (videoGenFlowHandler) Validate(cfg *TaskConfig) error {
+	return requireServerKey("soundGenerate server key", soundGenerateServerKey(cfg.SoundGenerate))
+}
+
+func (videoGenFlowHandler) ModelKeys(task domain.DataTaskModel, cfg *TaskConfig) []string {
+	keys := []string{soundGenerateServerKey(cfg.SoundGenerate)}
+	if task.ServerName != "" {
+		keys = append(keys, domain.ModelKey(task.ServerName, task.ServerVersion))
+	}
+	return uniqueModelKeys(keys...)
+}
+
+func (videoGenFlowHandler) Execute(task domain.DataTaskModel, cfg *TaskConfig) (*easyserver.TaskResult, error) {
+	return nil, runVideoGenFlowTask(task, cfg)
+}
+
+// ---------- soundReplace ----------
+
+// soundReplaceHandler 声音替换工作流：识别 -> 人工确认 -> 逐段生成 -> 合成，结果由工作流自行保存
+type soundReplaceHandler struct{ resultExtractor }
+
+func (soundReplaceHandler) Type() string { return domain.FunctionSoundReplace }
+func (soundReplaceHandler) Biz() string  { return "SoundReplace" }
+
+func (soundReplaceHandler) BuildConfig(req TaskCreateRequest) (map[string]any, error) {
+	if req.SoundGenerate == nil {
+		return nil, errs.New("soundGenerate is required")
+	}
+	if err := fillServerInfo(req.SoundGenerate, asString(req.SoundGenerate["cloneServerKey"])); err != nil {
+		return nil, err
+	}
+	if err := fillPrompt(req.SoundGenerate, toInt64(req.SoundGenerate["promptId"])); err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"video":         req.Video,
+		"soundAsr":      req.SoundAsr,
+		"soundGenerate": req.SoundGenerate,
+	}, nil
+}
+
+func (soundReplaceHandler) Validate(cfg *TaskConfig) error {
+	if cfg.Video == "" {
+		return errs.New("video is required")
+	}
+	if err := requireServerKey("soundAsr.serverKey", asString(cfg.SoundAsr["serverKey"])); err != nil {
+		return err
+	}
+	return requireServerKey("soundGenerate server key", soundGenerateServerKey(cfg.SoundGenerate))
+}
+
+func (soundReplaceHandler) ModelKeys(task domain.DataTaskModel, cfg *TaskConfig) []string {
+	return uniqueModelKeys(asString(cfg.SoundAsr["serverKey"]), soundGenerateServerKey(cfg.SoundGenerate))
+}
+
+func (soundReplaceHandler) Execute(task domain.DataTaskModel, cfg *TaskConfig) (*easyserver.TaskResult, error) {
+	return nil, runSoundReplaceTask(task, cfg)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"os"
+	"xiacutai-server/internal/component/errs"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/domain"
+	"xiacutai-server/internal/utils"
+)
+
+func init() {
+	RegisterTaskHandler(imageHandler{function: domain.FunctionImageGen, biz: "ImageGen"})
+	RegisterTaskHandler(imageHandler{function: domain.FunctionImageEdit, biz: "ImageEdit", needImage: true})
+	RegisterTaskHandler(imageHandler{function: domain.FunctionImageUpscale, biz: "ImageUpscale", needImage: true})
+}
+
+// imageHandler 图像生成 / 编辑 / 放大，三者只在输入和调用的功能上不同
+type imageHandler struct {
+	function  string
+	biz       string
+	needImage bool // 需要输入图片
+}
+
+func (h imageHandler) Type() string { return h.function }
+func (h imageHandler) Biz() string  { return h.biz }
+
+func (h imageHandler) BuildConfig(req TaskCreateRequest) (map[string]any, error) {
+	return map[string]any{
+		"serverKey":  req.ServerKey,
+		"imageParam": req.Param,
+		"text":       req.Text,
+		"image":      req.Image,
+		"mask":       req.Mask,
+	}, nil
+}
+
+func (h imageHandler) Validate(cfg *TaskConfig) error {
+	if err := requireServerKey("serverKey", cfg.ServerKey); err != nil {
+		return err
+	}
+	if h.needImage && cfg.Image == "" {
+		return errs.New("image is required")
+	}
+	if h.function == domain.FunctionImageGen && cfg.Text == "" {
+		return errs.New("text is required")
+	}
+	return nil
+}
+
+func (h imageHandler) ModelKeys(task domain.DataTaskModel, cfg *TaskConfig) []string {
+	return uniqueModelKeys(cfg.ServerKey)
+}
+
+func (h imageHandler) Execute(task domain.DataTaskModel, cfg *TaskConfig) (*easyserver.TaskResult, error) {
+	return runEasyServerTask(task, cfg, cfg.ServerKey, func(server *easyserver.EasyServer, data easyserver.ServerFunctionDataType) (*easyserver.TaskResult, error) {
+		data.Param = cfg.ImageParam
+		if data.Param == nil {
+			data.Param = map[string]interface{}{}
+		}
+		data.Text = cfg.Text
+		data.Image = cfg.Image
+		data.Mask = cfg.Mask
+		return server.Call(easyserver.ServerFunction(h.function), data)
+	})
+}
+
+// ExtractResult 模型输出的图片复制到 storage 目录，模型目录下的输出可能被下一次调用覆盖
+func (h imageHandler) ExtractResult(result *easyserver.TaskResult) (map[string]any, error) {
+	data, err := extractResultData(result)
+	if err != nil {
+		return nil, err
+	}
+	urls := make([]any, 0)
+	if list, ok := data["urls"].([]any); ok {
+		urls = list
+	} else if url := asString(data["url"]); url != "" {
+		urls = append(urls, url)
+	}
+	if len(urls) == 0 {
+		return nil, errs.New("模型没有返回图片")
+	}
+
+	stored := make([]any, 0, len(urls))
+	for _, item := range urls {
+		url := asString(item)
+		if _, err := os.Stat(url); err != nil {
+			// 模型返回的是远程地址等非本地文件时原样保存
+			stored = append(stored, url)
+			continue
+		}
+		dst, err := utils.CopyToStorage(url)
+		if err != nil {
+			return nil, err
+		}
+		stored = append(stored, dst)
+	}
+	return map[string]any{
+		"url":  stored[0],
+		"urls": stored,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"xiacutai-server/internal/domain"
+	"xiacutai-server/internal/utils"
+)
+
+func TestRunTaskWorkerImage(t *testing.T) {
+	resetTasks(t)
+	key := addFakeModel(t)
+	dir := t.TempDir()
+	image, mask := filepath.Join(dir, "in.png"), filepath.Join(dir, "mask.png")
+	for _, path := range []string{image, mask} {
+		if err := os.WriteFile(path, []byte("png"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		biz   string
+		req   TaskCreateRequest
+		count int
+	}{
+		{"ImageGen", TaskCreateRequest{Type: domain.FunctionImageGen, ServerKey: key, Text: "一只猫", Param: map[string]any{"count": 2}}, 2},
+		{"ImageEdit", TaskCreateRequest{Type: domain.FunctionImageEdit, ServerKey: key, Text: "换成狗", Image: image, Mask: mask}, 1},
+		{"ImageUpscale", TaskCreateRequest{Type: domain.FunctionImageUpscale, ServerKey: key, Image: image}, 1},
+	}
+	for _, c := range cases {
+		t.Run(c.biz, func(t *testing.T) {
+			task, err := DataTask.CreateFromRequest(c.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := runTask(t, task.ID)
+			if got.Status != domain.TaskStatusSuccess || got.Biz != c.biz {
+				t.Fatalf("unexpected task: status %s biz %s msg %s", got.Status, got.Biz, got.StatusMsg)
+			}
+			result := taskResult(t, got)
+			urls, _ := result["urls"].([]any)
+			if len(urls) != c.count || result["url"] != urls[0] {
+				t.Fatalf("unexpected result %s", got.Result)
+			}
+			// 图片复制到 storage 目录，不引用模型目录下会被覆盖的输出
+			for _, item := range urls {
+				path, _ := item.(string)
+				if filepath.Dir(path) != utils.StorageDir {
+					t.Errorf("image %s not in storage", path)
+				}
+				if _, err := os.Stat(path); err != nil {
+					t.Errorf("image missing: %v", err)
+				}
+			}
+		})
+	}
+
+	// 模型找不到输入图片时任务失败
+	task, err := DataTask.CreateFromRequest(TaskCreateRequest{Type: domain.FunctionImageUpscale, ServerKey: key, Image: filepath.Join(dir, "missing.png")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := runTask(t, task.ID); got.Status != domain.TaskStatusFail || !strings.Contains(got.StatusMsg, "image not found") {
+		t.Fatalf("expected fail, got %s %q", got.Status, got.StatusMsg)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"xiacutai-server/internal/domain"
+)
+
+// TestBuiltinHandlersConfigRoundTrip 创建任务生成的 modelConfig 能被调度器解析并通过校验
+func TestBuiltinHandlersConfigRoundTrip(t *testing.T) {
+	key := addFakeModel(t)
+	prompt, err := DataStorage.CreateStorage(domain.DataStorageModel{Biz: "SoundPrompt", Title: "参考声音", Content: `{"url":"/tmp/prompt.wav","promptText":"你好"}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = DataStorage.DeleteStorage(prompt.ID) })
+	template, err := DataVideoTemplate.Create(domain.DataVideoTemplateModel{Name: "模板", Video: "/tmp/template.mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = DataVideoTemplate.Delete(template.ID) })
+
+	param := map[string]any{"speed": 1.0}
+	requests := map[string]TaskCreateRequest{
+		domain.FunctionSoundTts:   {ServerKey: key, Text: "你好", Param: param},
+		domain.FunctionSoundClone: {ServerKey: key, Text: "你好", Param: param, PromptId: prompt.ID},
+		domain.FunctionSoundAsr:   {ServerKey: key, Audio: "/tmp/in.wav", Param: param},
+		domain.FunctionVideoGen:   {ServerKey: key, Video: "/tmp/in.mp4", Audio: "/tmp/in.wav", Param: param},
+		domain.FunctionVideoGenFlow: {Text: "你好", VideoTemplateId: template.ID,
+			SoundGenerate: map[string]any{"type": "soundTts", "ttsServerKey": key, "ttsParam": param}},
+		domain.FunctionSoundReplace: {Video: "/tmp/in.mp4", SoundAsr: map[string]any{"serverKey": key},
+			SoundGenerate: map[string]any{"type": "soundClone", "cloneServerKey": key, "promptId": prompt.ID}},
+		domain.FunctionImageGen:     {ServerKey: key, Text: "一只猫", Param: param},
+		domain.FunctionImageEdit:    {ServerKey: key, Text: "换成狗", Image: "/tmp/in.png", Mask: "/tmp/mask.png", Param: param},
+		domain.FunctionImageUpscale: {ServerKey: key, Image: "/tmp/in.png", Param: param},
+	}
+
+	taskHandlers.RLock()
+	handlers := make([]TaskHandler, 0, len(taskHandlers.handlers))
+	for _, handler := range taskHandlers.handlers {
+		handlers = append(handlers, handler)
+	}
+	taskHandlers.RUnlock()
+
+	for _, handler := range handlers {
+		t.Run(handler.Type(), func(t *testing.T) {
+			req, ok := requests[handler.Type()]
+			if !ok {
+				t.Fatalf("no request for handler %s", handler.Type())
+			}
+			req.Type = handler.Type()
+			modelConfig, err := handler.BuildConfig(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			modelConfig["type"] = handler.Type()
+			raw, err := json.Marshal(modelConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := parseSoundTaskConfig(string(raw))
+			if err != nil {
+				t.Fatalf("parse %s: %v", raw, err)
+			}
+			if cfg.Type != handler.Type() {
+				t.Fatalf("type %q, want %q", cfg.Type, handler.Type())
+			}
+			if err := handler.Validate(cfg); err != nil {
+				t.Fatalf("validate %s: %v", raw, err)
+			}
+			task := domain.DataTaskModel{ServerName: "fake-model", ServerVersion: "1.0.0"}
+			if keys := handler.ModelKeys(task, cfg); len(keys) == 0 {
+				t.Fatalf("no model keys from %s", raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/component/sqllite"
+	"xiacutai-server/internal/domain"
+	"xiacutai-server/internal/utils"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const defaultTaskLease = 60 * time.Second
+
+// taskWorkerID 当前服务实例的执行者标识，写入任务的 leaseOwner
+var taskWorkerID = newTaskWorkerID()
+
+var taskLease = getTaskLease()
+
+// errTaskLeaseLost 任务已被取消或被其它执行者接管，当前执行应立即停止
+var errTaskLeaseLost = errors.New("task lease lost")
+
+func newTaskWorkerID() string {
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "local"
+	}
+	buf := make([]byte, 4)
+	_, _ = rand.Read(buf)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(buf))
+}
+
+func getTaskLease() time.Duration {
+	raw := utils.GetEnv("AIGCPANEL_TASK_LEASE_MS", "")
+	if raw == "" {
+		return defaultTaskLease
+	}
+	ms := toInt64(raw)
+	if ms < 1000 {
+		return defaultTaskLease
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// ClaimTask 原子地将排队中的任务置为运行中并写入租约
+// 只有 status 仍为 queue 时才会成功，多个执行者同时抢同一个任务只有一个能拿到
+func (s *taskService) ClaimTask(task domain.DataTaskModel, owner string, lease time.Duration) (bool, error) {
+	maxAttempts := task.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = taskRetryPolicy(task).MaxAttempts
+	}
+	now := time.Now().UnixMilli()
+	result := sqllite.GetSession().
+		Model(&domain.DataTaskModel{}).
+		Where("id = ? AND status = ?", task.ID, domain.TaskStatusQueue).
+		Updates(map[string]any{
+			"status":        domain.TaskStatusRunning,
+			"startTime":     now,
+			"attempt":       gorm.Expr("attempt + 1"),
+			"maxAttempts":   maxAttempts,
+			"nextRunAt":     0,
+			"progress":      "",
+			"leaseOwner":    owner,
+			"leaseExpireAt": now + lease.Milliseconds(),
+			"heartbeatAt":   now,
+			"updatedAt":     now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return false, nil
+	}
+	publishTaskStatusByID(task.ID)
+	return true, nil
+}
+
+// RenewLease 续租，任务已不再由 owner 持有时返回 false
+func (s *taskService) RenewLease(id int64, owner string, lease time.Duration) (bool, error) {
+	now := time.Now().UnixMilli()
+	result := sqllite.GetSession().
+		Model(&domain.DataTaskModel{}).
+		Where("id = ? AND status = ? AND leaseOwner = ?", id, domain.TaskStatusRunning, owner).
+		Updates(map[string]any{
+			"leaseExpireAt": now + lease.Milliseconds(),
+			"heartbeatAt":   now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// UpdateOwnedTask 仅当任务仍在运行且由 owner 持有时才更新
+// 租约丢失（被取消或被其它执行者接管）时返回 false，调用方应放弃写入结果
+func (s *taskService) UpdateOwnedTask(id int64, owner string, updates map[string]any) (bool, error) {
+	updates["updatedAt"] = time.Now().UnixMilli()
+	affected, err := updateTaskRow(id, func(db *gorm.DB) *gorm.DB {
+		return db.Where("status = ? AND leaseOwner = ?", domain.TaskStatusRunning, owner)
+	}, updates)
+	if err != nil {
+		return false, err
+	}
+	if affected != 1 {
+		return false, nil
+	}
+	if _, ok := updates["status"]; ok {
+		publishTaskStatusByID(id)
+	}
+	return true, nil
+}
+
+// ReclaimTask 接管没有租约或租约已过期的运行中任务
+func (s *taskService) ReclaimTask(id int64, updates map[string]any) (bool, error) {
+	now := time.Now().UnixMilli()
+	updates["leaseOwner"] = ""
+	updates["leaseExpireAt"] = 0
+	updates["updatedAt"] = now
+	affected, err := updateTaskRow(id, func(db *gorm.DB) *gorm.DB {
+		return leaseExpiredCondition(db, now)
+	}, updates)
+	if err != nil {
+		return false, err
+	}
+	if affected != 1 {
+		return false, nil
+	}
+	publishTaskStatusByID(id)
+	return true, nil
+}
+
+// finishTask 以当前执行者身份写入任务的最终状态并释放租约
+func finishTask(taskID int64, updates map[string]any) error {
+	updates["leaseOwner"] = ""
+	updates["leaseExpireAt"] = 0
+	ok, err := DataTask.UpdateOwnedTask(taskID, taskWorkerID, updates)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		log.Warn("Task lease lost, result discarded", zap.Int64("taskId", taskID), zap.Any("status", updates["status"]))
+	}
+	return nil
+}
+
+// startTaskHeartbeat 任务执行期间定期续租，租约丢失时取消本地执行
+func startTaskHeartbeat(taskID int64) (stop func()) {
+	interval := taskLease / 3
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			ok, err := DataTask.RenewLease(taskID, taskWorkerID, taskLease)
+			if err != nil {
+				// 数据库暂时不可用时继续尝试，租约过期前恢复即可
+				log.Warn("Renew task lease failed", zap.Int64("taskId", taskID), zap.Error(err))
+				continue
+			}
+			if !ok {
+				log.Warn("Task lease lost, cancel local execution", zap.Int64("taskId", taskID))
+				_ = CancelEasyServerTask(taskID)
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// leaseExpiredCondition 没有租约或租约已过期的运行中任务可以被接管
+func leaseExpiredCondition(db *gorm.DB, now int64) *gorm.DB {
+	return db.Where("status = ? AND (leaseExpireAt = 0 OR leaseExpireAt < ?)", domain.TaskStatusRunning, now)
+}
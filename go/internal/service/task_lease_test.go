@@ -0,0 +1,91 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"xiacutai-server/internal/domain"
+)
+
+func TestClaimTaskOnlyOneWinner(t *testing.T) {
+	resetTasks(t)
+	task := newQueuedTask(t, map[string]any{"type": domain.FunctionSoundTts}, domain.DataTaskModel{})
+
+	var wins int32
+	var winner atomic.Value
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(owner string) {
+			defer wg.Done()
+			ok, err := DataTask.ClaimTask(task, owner, time.Minute)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if ok {
+				atomic.AddInt32(&wins, 1)
+				winner.Store(owner)
+			}
+		}(fmt.Sprintf("worker-%d", i))
+	}
+	wg.Wait()
+
+	if wins != 1 {
+		t.Fatalf("expected exactly one claim, got %d", wins)
+	}
+	got := mustGetTask(t, task.ID)
+	if got.Status != domain.TaskStatusRunning || got.LeaseOwner != winner.Load() || got.Attempt != 1 {
+		t.Fatalf("unexpected claimed task: status %s owner %s attempt %d", got.Status, got.LeaseOwner, got.Attempt)
+	}
+	if got.LeaseExpireAt <= time.Now().UnixMilli() {
+		t.Fatalf("lease should be in the future: %d", got.LeaseExpireAt)
+	}
+}
+
+func TestLeaseStealing(t *testing.T) {
+	resetTasks(t)
+	task := newQueuedTask(t, map[string]any{"type": domain.FunctionSoundTts}, domain.DataTaskModel{})
+	if ok, err := DataTask.ClaimTask(task, "worker-a", time.Minute); err != nil || !ok {
+		t.Fatalf("claim: %v %v", ok, err)
+	}
+
+	if ok, _ := DataTask.RenewLease(task.ID, "worker-b", time.Minute); ok {
+		t.Fatal("renew by another worker should fail")
+	}
+	if ok, _ := DataTask.RenewLease(task.ID, "worker-a", time.Minute); !ok {
+		t.Fatal("renew by owner should succeed")
+	}
+	// 租约有效时不能被接管
+	if ok, _ := DataTask.ReclaimTask(task.ID, map[string]any{"status": domain.TaskStatusQueue}); ok {
+		t.Fatal("reclaim should fail while the lease is alive")
+	}
+
+	// worker-a 失联，租约过期后被接管并重新排队，再由 worker-b 领取
+	if _, err := DataTask.UpdateTask(task.ID, map[string]any{"leaseExpireAt": time.Now().Add(-time.Second).UnixMilli()}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := DataTask.ReclaimTask(task.ID, map[string]any{"status": domain.TaskStatusQueue}); err != nil || !ok {
+		t.Fatalf("reclaim expired lease: %v %v", ok, err)
+	}
+	if ok, err := DataTask.ClaimTask(mustGetTask(t, task.ID), "worker-b", time.Minute); err != nil || !ok {
+		t.Fatalf("claim by worker-b: %v %v", ok, err)
+	}
+
+	// worker-a 恢复后既不能续租也不能写入结果
+	if ok, _ := DataTask.RenewLease(task.ID, "worker-a", time.Minute); ok {
+		t.Fatal("stale owner should not renew")
+	}
+	if ok, _ := DataTask.UpdateOwnedTask(task.ID, "worker-a", map[string]any{"status": domain.TaskStatusSuccess}); ok {
+		t.Fatal("stale owner should not write the result")
+	}
+	if ok, _ := DataTask.UpdateOwnedTask(task.ID, "worker-b", map[string]any{"status": domain.TaskStatusSuccess}); !ok {
+		t.Fatal("current owner should write the result")
+	}
+	got := mustGetTask(t, task.ID)
+	if got.Status != domain.TaskStatusSuccess || got.Attempt != 2 {
+		t.Fatalf("unexpected task: status %s attempt %d", got.Status, got.Attempt)
+	}
+}
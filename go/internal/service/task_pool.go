@@ -0,0 +1,150 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"xiacutai-server/internal/domain"
+	"xiacutai-server/internal/utils"
+)
+
+const (
+	defaultTaskConcurrency  = 4
+	defaultModelConcurrency = 1
+)
+
+// taskPool 任务执行池
+// 全局最多同时运行 size 个任务，同一个模型 key 同时运行的任务数不超过该模型的并发上限
+type taskPool struct {
+	mu         sync.Mutex
+	size       int
+	running    map[int64][]string // taskID -> 占用的模型 key
+	modelSlots map[string]int     // 模型 key -> 正在运行的任务数
+	wake       chan struct{}      // 有任务结束时通知调度器立即调度
+}
+
+func newTaskPool(size int) *taskPool {
+	if size <= 0 {
+		size = defaultTaskConcurrency
+	}
+	return &taskPool{
+		size:       size,
+		running:    make(map[int64][]string),
+		modelSlots: make(map[string]int),
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+func (p *taskPool) isFull() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.running) >= p.size
+}
+
+func (p *taskPool) isRunning(taskID int64) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.running[taskID]
+	return ok
+}
+
+// tryAcquire 尝试为任务占用全局槽位和所有模型槽位，任何一个不满足都不占用
+func (p *taskPool) tryAcquire(taskID int64, keys []string, limits map[string]int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.running[taskID]; ok {
+		return false
+	}
+	if len(p.running) >= p.size {
+		return false
+	}
+	for _, key := range keys {
+		limit := limits[key]
+		if limit <= 0 {
+			limit = defaultModelConcurrency
+		}
+		if p.modelSlots[key] >= limit {
+			return false
+		}
+	}
+
+	for _, key := range keys {
+		p.modelSlots[key]++
+	}
+	p.running[taskID] = keys
+	return true
+}
+
+func (p *taskPool) release(taskID int64) {
+	p.mu.Lock()
+	keys, ok := p.running[taskID]
+	if ok {
+		delete(p.running, taskID)
+		for _, key := range keys {
+			p.modelSlots[key]--
+			if p.modelSlots[key] <= 0 {
+				delete(p.modelSlots, key)
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func getTaskConcurrency() int {
+	raw := utils.GetEnv("AIGCPANEL_TASK_CONCURRENCY", "")
+	if raw == "" {
+		return defaultTaskConcurrency
+	}
+	n := int(toInt64(raw))
+	if n <= 0 {
+		return defaultTaskConcurrency
+	}
+	return n
+}
+
+// modelConcurrencyLimit 读取模型的并发上限
+// 优先级：注册表 setting.concurrency > config.json easyServer.concurrency > config.json concurrency
+func modelConcurrencyLimit(serverKey string) int {
+	info, err := Model.Get(serverKey)
+	if err != nil {
+		return defaultModelConcurrency
+	}
+	if n := toInt64(info.Setting["concurrency"]); n > 0 {
+		return int(n)
+	}
+	if n := toInt64(asMap(info.Config["easyServer"])["concurrency"]); n > 0 {
+		return int(n)
+	}
+	if n := toInt64(info.Config["concurrency"]); n > 0 {
+		return int(n)
+	}
+	return defaultModelConcurrency
+}
+
+// taskModelKeys 返回任务执行过程中会用到的所有模型 key
+func taskModelKeys(task domain.DataTaskModel) []string {
+	cfg, err := parseSoundTaskConfig(task.ModelConfig)
+	if err != nil {
+		return nil
+	}
+	handler, ok := GetTaskHandler(cfg.Type)
+	if !ok {
+		return nil
+	}
+	return handler.ModelKeys(task, cfg)
+}
+
+func soundGenerateServerKey(soundGenerate map[string]any) string {
+	if strings.Contains(strings.ToLower(asString(soundGenerate["type"])), "clone") {
+		return asString(soundGenerate["cloneServerKey"])
+	}
+	return asString(soundGenerate["ttsServerKey"])
+}
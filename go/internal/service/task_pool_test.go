@@ -0,0 +1,87 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"xiacutai-server/internal/domain"
+)
+
+func TestTaskPoolAdmission(t *testing.T) {
+	p := newTaskPool(3)
+	limits := map[string]int{"tts|1.0": 2, "asr|1.0": 1}
+
+	steps := []struct {
+		taskID int64
+		keys   []string
+		want   bool
+	}{
+		{1, []string{"tts|1.0"}, true},
+		{1, []string{"tts|1.0"}, false},            // 同一任务不能重复占用
+		{2, []string{"tts|1.0"}, true},             // tts 并发上限 2
+		{3, []string{"tts|1.0"}, false},            // tts 已满
+		{4, []string{"asr|1.0", "tts|1.0"}, false}, // 任何一个模型已满都不占用
+		{5, []string{"asr|1.0"}, true},             // 上一步没有占用 asr
+		{6, []string{"other|1.0"}, false},          // 全局已满
+	}
+	for i, s := range steps {
+		if got := p.tryAcquire(s.taskID, s.keys, limits); got != s.want {
+			t.Fatalf("step %d: tryAcquire(%d, %v) = %v, want %v", i, s.taskID, s.keys, got, s.want)
+		}
+	}
+	if !p.isFull() || !p.isRunning(5) || p.isRunning(4) {
+		t.Fatalf("unexpected pool state: %v", p.running)
+	}
+
+	p.release(1)
+	select {
+	case <-p.wake:
+	case <-time.After(time.Second):
+		t.Fatal("release should wake the scheduler")
+	}
+	if p.isFull() {
+		t.Fatal("pool should have a free slot")
+	}
+	// 未设置上限的模型按默认并发 1
+	if !p.tryAcquire(6, []string{"other|1.0"}, limits) {
+		t.Fatal("expected free slot for task 6")
+	}
+	p.release(6)
+	p.release(6) // 重复释放不影响计数
+	if !p.tryAcquire(3, []string{"tts|1.0"}, limits) {
+		t.Fatal("expected tts slot after release")
+	}
+	if p.modelSlots["tts|1.0"] != 2 || p.modelSlots["other|1.0"] != 0 {
+		t.Fatalf("unexpected model slots: %v", p.modelSlots)
+	}
+}
+
+func TestTaskModelKeysShareAliasSlot(t *testing.T) {
+	resetTasks(t)
+	tts := newQueuedTask(t, map[string]any{"type": domain.FunctionSoundTts, "ttsServerKey": "fake-model@1.0.0"}, domain.DataTaskModel{})
+	asr := newQueuedTask(t, map[string]any{"type": domain.FunctionSoundAsr, "serverKey": " fake-model:1.0.0 "}, domain.DataTaskModel{})
+	flow := newQueuedTask(t, map[string]any{
+		"type":          domain.FunctionVideoGenFlow,
+		"soundGenerate": map[string]any{"type": "soundTts", "ttsServerKey": "fake-model@1.0.0"},
+	}, domain.DataTaskModel{ServerName: "fake-model", ServerVersion: " 1.0.0"})
+
+	want := []string{"fake-model|1.0.0"}
+	for _, task := range []domain.DataTaskModel{tts, asr, flow} {
+		if keys := taskModelKeys(task); !reflect.DeepEqual(keys, want) {
+			t.Fatalf("task %d: keys %v, want %v", task.ID, keys, want)
+		}
+	}
+
+	// 别名占用同一个槽位，默认并发 1
+	p := newTaskPool(4)
+	if !p.tryAcquire(tts.ID, taskModelKeys(tts), nil) {
+		t.Fatal("first task should be admitted")
+	}
+	if p.tryAcquire(asr.ID, taskModelKeys(asr), nil) {
+		t.Fatal("alias key should share the model slot")
+	}
+	p.release(tts.ID)
+	if !p.tryAcquire(asr.ID, taskModelKeys(asr), nil) {
+		t.Fatal("slot should be free after release")
+	}
+}
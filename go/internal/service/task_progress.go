@@ -0,0 +1,70 @@
+package service
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+// taskProgressInterval 同一阶段内进度写库的最小间隔，阶段变化或完成时立即写入
+const taskProgressInterval = time.Second
+
+// taskProgressReporter 把模型上报的进度写入任务的 progress 字段并推送 progress 事件
+type taskProgressReporter struct {
+	taskID    int64
+	mu        sync.Mutex
+	biz       string
+	lastSave  time.Time
+	lastStage string
+}
+
+func newTaskProgressReporter(taskID int64) func(easyserver.Progress) {
+	r := &taskProgressReporter{taskID: taskID}
+	return r.report
+}
+
+func (r *taskProgressReporter) report(progress easyserver.Progress) {
+	r.mu.Lock()
+	now := time.Now()
+	if progress.Stage == r.lastStage && progress.Percent < 100 && now.Sub(r.lastSave) < taskProgressInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastSave = now
+	r.lastStage = progress.Stage
+	if r.biz == "" {
+		if task, err := DataTask.GetTask(r.taskID); err == nil {
+			r.biz = task.Biz
+		}
+	}
+	biz := r.biz
+	r.mu.Unlock()
+
+	raw, err := json.Marshal(progress)
+	if err != nil {
+		return
+	}
+	ok, err := DataTask.UpdateOwnedTask(r.taskID, taskWorkerID, map[string]any{"progress": string(raw)})
+	if err != nil {
+		log.Warn("Save task progress failed", zap.Int64("taskId", r.taskID), zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
+
+	payload := map[string]any{}
+	_ = json.Unmarshal(raw, &payload)
+	TaskEvents.Publish(TaskEvent{
+		Type:     TaskEventProgress,
+		TaskID:   r.taskID,
+		Biz:      biz,
+		Status:   domain.TaskStatusRunning,
+		Progress: payload,
+	})
+}
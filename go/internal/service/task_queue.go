@@ -0,0 +1,162 @@
+package service
+
+import (
+	"time"
+	"xiacutai-server/internal/component/errs"
+	"xiacutai-server/internal/component/sqllite"
+	"xiacutai-server/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// UpdatePriority 修改任务优先级
+func (s *taskService) UpdatePriority(id int64, priority int) (domain.DataTaskModel, error) {
+	if _, err := s.GetTask(id); err != nil {
+		return domain.DataTaskModel{}, err
+	}
+	return s.UpdateTask(id, map[string]any{
+		"priority": priority,
+	})
+}
+
+// MoveToTop 将排队中的任务移到队首
+// 优先级提升到当前排队任务的最高优先级，并排在该优先级所有任务之前
+func (s *taskService) MoveToTop(id int64) (domain.DataTaskModel, error) {
+	err := sqllite.GetSession().Transaction(func(tx *gorm.DB) error {
+		var task domain.DataTaskModel
+		if err := tx.First(&task, id).Error; err != nil {
+			return err
+		}
+		if task.Status != domain.TaskStatusQueue {
+			return errs.New("只能调整排队中任务的顺序")
+		}
+
+		var head domain.DataTaskModel
+		err := tx.Model(&domain.DataTaskModel{}).
+			Where("status = ? AND id <> ?", domain.TaskStatusQueue, id).
+			Order(sqllite.TaskQueueOrder).
+			First(&head).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil
+			}
+			return err
+		}
+
+		priority := task.Priority
+		if head.Priority > priority {
+			priority = head.Priority
+		}
+		queueSort := task.QueueSort
+		if head.Priority == priority && head.QueueSort <= queueSort {
+			queueSort = head.QueueSort - 1
+		}
+		return tx.Model(&domain.DataTaskModel{}).
+			Where("id = ?", id).
+			Updates(map[string]any{
+				"priority":  priority,
+				"queueSort": queueSort,
+				"updatedAt": time.Now().UnixMilli(),
+			}).Error
+	})
+	if err != nil {
+		return domain.DataTaskModel{}, err
+	}
+	return s.GetTask(id)
+}
+
+// Reorder 按 ids 给出的顺序重排这些排队中的任务
+// 只在各自原有的优先级内调整 queueSort，不改变优先级：同优先级的任务沿用它们原有的排队位置区间，
+// 不同优先级之间的先后仍由优先级决定
+func (s *taskService) Reorder(ids []int64) error {
+	if len(ids) == 0 {
+		return errs.ParamError
+	}
+	return sqllite.GetSession().Transaction(func(tx *gorm.DB) error {
+		var tasks []domain.DataTaskModel
+		if err := tx.Where("id IN ?", ids).Order(sqllite.TaskQueueOrder).Find(&tasks).Error; err != nil {
+			return err
+		}
+		if len(tasks) != len(ids) {
+			return errs.New("任务不存在或存在重复")
+		}
+
+		priorities := make(map[int64]int, len(tasks))
+		sorts := map[int][]int64{} // 优先级 -> 该优先级下被重排任务原有的 queueSort（升序）
+		for _, task := range tasks {
+			if task.Status != domain.TaskStatusQueue {
+				return errs.New("只能调整排队中任务的顺序")
+			}
+			priorities[task.ID] = task.Priority
+			sorts[task.Priority] = append(sorts[task.Priority], task.QueueSort)
+		}
+		// 原有排序值可能相同（例如历史数据都是 0），保证严格递增
+		for _, list := range sorts {
+			for i := 1; i < len(list); i++ {
+				if list[i] <= list[i-1] {
+					list[i] = list[i-1] + 1
+				}
+			}
+		}
+
+		now := time.Now().UnixMilli()
+		next := map[int]int{}
+		for _, id := range ids {
+			priority := priorities[id]
+			if err := tx.Model(&domain.DataTaskModel{}).
+				Where("id = ?", id).
+				Updates(map[string]any{
+					"queueSort": sorts[priority][next[priority]],
+					"updatedAt": now,
+				}).Error; err != nil {
+				return err
+			}
+			next[priority]++
+		}
+		return nil
+	})
+}
+
+// FillQueuePositions 为列表中排队中的任务填充排队位置
+func (s *taskService) FillQueuePositions(tasks []domain.DataTaskModel) error {
+	hasQueued := false
+	for _, task := range tasks {
+		if task.Status == domain.TaskStatusQueue {
+			hasQueued = true
+			break
+		}
+	}
+	if !hasQueued {
+		return nil
+	}
+
+	// 与调度器出队顺序一致：已到期的任务按队列顺序在前，退避等待中的任务按到期时间排在后面
+	now := time.Now().UnixMilli()
+	var ids, waiting []int64
+	if err := sqllite.GetSession().
+		Model(&domain.DataTaskModel{}).
+		Where("status = ? AND nextRunAt <= ?", domain.TaskStatusQueue, now).
+		Order(sqllite.TaskQueueOrder).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if err := sqllite.GetSession().
+		Model(&domain.DataTaskModel{}).
+		Where("status = ? AND nextRunAt > ?", domain.TaskStatusQueue, now).
+		Order("nextRunAt ASC, "+sqllite.TaskQueueOrder).
+		Pluck("id", &waiting).Error; err != nil {
+		return err
+	}
+	ids = append(ids, waiting...)
+
+	positions := make(map[int64]int, len(ids))
+	for i, id := range ids {
+		positions[id] = i + 1
+	}
+	for i := range tasks {
+		if tasks[i].Status == domain.TaskStatusQueue {
+			tasks[i].QueuePosition = positions[tasks[i].ID]
+		}
+	}
+	return nil
+}
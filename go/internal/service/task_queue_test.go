@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"xiacutai-server/internal/domain"
+)
+
+func TestReorderKeepsPriority(t *testing.T) {
+	resetTasks(t)
+	cfg := map[string]any{"type": domain.FunctionSoundTts}
+	a := newQueuedTask(t, cfg, domain.DataTaskModel{Priority: 5, QueueSort: 10})
+	b := newQueuedTask(t, cfg, domain.DataTaskModel{Priority: 0, QueueSort: 20})
+	c := newQueuedTask(t, cfg, domain.DataTaskModel{Priority: 0, QueueSort: 30})
+	d := newQueuedTask(t, cfg, domain.DataTaskModel{Priority: 5, QueueSort: 40})
+
+	if err := DataTask.Reorder([]int64{c.ID, d.ID, b.ID, a.ID}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[int64][2]int64{ // id -> priority, queueSort
+		a.ID: {5, 40},
+		b.ID: {0, 30},
+		c.ID: {0, 20},
+		d.ID: {5, 10},
+	}
+	for id, w := range want {
+		task := mustGetTask(t, id)
+		if int64(task.Priority) != w[0] || task.QueueSort != w[1] {
+			t.Errorf("task %d: priority %d queueSort %d, want %v", id, task.Priority, task.QueueSort, w)
+		}
+	}
+
+	running := newQueuedTask(t, cfg, domain.DataTaskModel{})
+	if _, err := DataTask.UpdateTask(running.ID, map[string]any{"status": domain.TaskStatusRunning}); err != nil {
+		t.Fatal(err)
+	}
+	if err := DataTask.Reorder([]int64{a.ID, running.ID}); err == nil {
+		t.Fatal("reorder should reject tasks that are not queued")
+	}
+}
+
+func TestFillQueuePositions(t *testing.T) {
+	resetTasks(t)
+	cfg := map[string]any{"type": domain.FunctionSoundTts}
+	now := time.Now()
+	backoffLate := newQueuedTask(t, cfg, domain.DataTaskModel{Priority: 9, NextRunAt: now.Add(2 * time.Minute).UnixMilli()})
+	backoffSoon := newQueuedTask(t, cfg, domain.DataTaskModel{Priority: 0, NextRunAt: now.Add(time.Minute).UnixMilli()})
+	low := newQueuedTask(t, cfg, domain.DataTaskModel{Priority: 0})
+	high := newQueuedTask(t, cfg, domain.DataTaskModel{Priority: 1})
+
+	tasks := []domain.DataTaskModel{backoffLate, backoffSoon, low, high}
+	if err := DataTask.FillQueuePositions(tasks); err != nil {
+		t.Fatal(err)
+	}
+	want := []int{4, 3, 2, 1}
+	for i, task := range tasks {
+		if task.QueuePosition != want[i] {
+			t.Errorf("task %d: position %d, want %d", task.ID, task.QueuePosition, want[i])
+		}
+	}
+}
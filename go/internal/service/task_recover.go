@@ -0,0 +1,126 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/component/sqllite"
+	"xiacutai-server/internal/domain"
+	"xiacutai-server/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// 对中断任务（服务重启或执行者租约过期）的处理方式，环境变量 AIGCPANEL_TASK_RECOVER_MODE
+const (
+	recoverModeRetry = "retry" // 按重试策略重新排队，次数用完则失败（默认）
+	recoverModeQueue = "queue" // 全部重新排队
+	recoverModeFail  = "fail"  // 全部标记失败
+)
+
+const taskInterruptedMsg = "任务执行中断"
+
+func getTaskRecoverMode() string {
+	mode := strings.ToLower(strings.TrimSpace(utils.GetEnv("AIGCPANEL_TASK_RECOVER_MODE", "")))
+	switch mode {
+	case recoverModeQueue, recoverModeFail:
+		return mode
+	default:
+		return recoverModeRetry
+	}
+}
+
+// recoverInterruptedTasks 服务启动时清理上次运行留下的现场
+// 结束残留的模型进程，删除残留的调用配置文件，并处理停留在 running 的任务
+func recoverInterruptedTasks() {
+	if killed := easyserver.CleanupOrphanProcesses(); killed > 0 {
+		log.Info("Orphan model processes killed", zap.Int("count", killed))
+	}
+	if removed := easyserver.CleanStaleConfigFiles(); removed > 0 {
+		log.Info("Stale config files removed", zap.Int("count", removed))
+	}
+	reclaimExpiredTasks()
+}
+
+// reclaimExpiredTasks 处理没有租约或租约已过期的运行中任务
+// 租约仍有效的任务属于其它存活的执行者，不做处理
+func reclaimExpiredTasks() {
+	tasks, err := DataTask.ListTasks(sqllite.TaskFilters{
+		Status: []string{domain.TaskStatusRunning},
+	})
+	if err != nil {
+		log.Error("List interrupted tasks failed", zap.Error(err))
+		return
+	}
+
+	mode := getTaskRecoverMode()
+	now := time.Now().UnixMilli()
+	recovered := 0
+	for _, task := range tasks {
+		if task.LeaseExpireAt > 0 && task.LeaseExpireAt >= now {
+			continue
+		}
+		ok, err := recoverTask(task, mode)
+		if err != nil {
+			log.Error("Recover task failed", zap.Int64("taskId", task.ID), zap.Error(err))
+			continue
+		}
+		if ok {
+			recovered++
+		}
+	}
+	if recovered > 0 {
+		log.Info("Interrupted tasks recovered", zap.Int("count", recovered), zap.String("mode", mode))
+	}
+}
+
+func recoverTask(task domain.DataTaskModel, mode string) (bool, error) {
+	requeue := mode == recoverModeQueue
+	if mode == recoverModeRetry {
+		maxAttempts := task.MaxAttempts
+		if maxAttempts <= 0 {
+			maxAttempts = taskRetryPolicy(task).MaxAttempts
+		}
+		requeue = task.Attempt < maxAttempts
+	}
+
+	if !requeue {
+		return DataTask.ReclaimTask(task.ID, map[string]any{
+			"status":    domain.TaskStatusFail,
+			"statusMsg": taskInterruptedMsg,
+			"lastError": taskInterruptedMsg,
+			"endTime":   time.Now().UnixMilli(),
+		})
+	}
+
+	// 声音替换从 jobResult 中记录的步骤继续，其它任务从头执行
+	statusMsg := taskInterruptedMsg + "，重新排队"
+	if step := soundReplaceStep(task); step != "" {
+		statusMsg = fmt.Sprintf("%s，从 %s 步骤继续", taskInterruptedMsg, step)
+	}
+	return DataTask.ReclaimTask(task.ID, map[string]any{
+		"status":    domain.TaskStatusQueue,
+		"statusMsg": statusMsg,
+		"lastError": taskInterruptedMsg,
+		"nextRunAt": 0,
+	})
+}
+
+func soundReplaceStep(task domain.DataTaskModel) string {
+	cfg, err := parseSoundTaskConfig(task.ModelConfig)
+	if err != nil || cfg.Type != domain.FunctionSoundReplace {
+		return ""
+	}
+	job := map[string]any{}
+	if strings.TrimSpace(task.JobResult) != "" {
+		_ = json.Unmarshal([]byte(task.JobResult), &job)
+	}
+	step := asString(job["step"])
+	if step == "" {
+		step = "ToAudio"
+	}
+	return step
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"xiacutai-server/internal/domain"
+)
+
+// newRunningTask 创建一个停留在 running 的任务
+func newRunningTask(t *testing.T, cfg map[string]any, attempt int, leaseExpireAt int64) domain.DataTaskModel {
+	t.Helper()
+	task := newQueuedTask(t, cfg, domain.DataTaskModel{MaxAttempts: 2})
+	task, err := DataTask.UpdateTask(task.ID, map[string]any{
+		"status":        domain.TaskStatusRunning,
+		"attempt":       attempt,
+		"leaseOwner":    "crashed-worker",
+		"leaseExpireAt": leaseExpireAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+func TestReclaimExpiredTasks(t *testing.T) {
+	tts := map[string]any{"type": domain.FunctionSoundTts}
+	replace := map[string]any{"type": domain.FunctionSoundReplace}
+	expired := time.Now().Add(-time.Minute).UnixMilli()
+	alive := time.Now().Add(time.Minute).UnixMilli()
+
+	cases := []struct {
+		mode          string
+		cfg           map[string]any
+		attempt       int
+		leaseExpireAt int64
+		wantStatus    string
+		wantMsg       string
+	}{
+		{recoverModeRetry, tts, 1, expired, domain.TaskStatusQueue, "重新排队"},
+		{recoverModeRetry, tts, 2, expired, domain.TaskStatusFail, taskInterruptedMsg},
+		{recoverModeRetry, tts, 1, 0, domain.TaskStatusQueue, "重新排队"}, // 没有租约的旧数据
+		{recoverModeRetry, replace, 1, expired, domain.TaskStatusQueue, "从 ToAudio 步骤继续"},
+		{recoverModeQueue, tts, 2, expired, domain.TaskStatusQueue, "重新排队"},
+		{recoverModeFail, tts, 1, expired, domain.TaskStatusFail, taskInterruptedMsg},
+		{recoverModeFail, tts, 1, alive, domain.TaskStatusRunning, ""}, // 租约有效，属于其它存活的执行者
+	}
+	for i, c := range cases {
+		resetTasks(t)
+		t.Setenv("AIGCPANEL_TASK_RECOVER_MODE", c.mode)
+		task := newRunningTask(t, c.cfg, c.attempt, c.leaseExpireAt)
+
+		reclaimExpiredTasks()
+
+		got := mustGetTask(t, task.ID)
+		if got.Status != c.wantStatus || !strings.Contains(got.StatusMsg, c.wantMsg) {
+			t.Errorf("case %d (%s): status %s %q, want %s %q", i, c.mode, got.Status, got.StatusMsg, c.wantStatus, c.wantMsg)
+		}
+		if c.wantStatus != domain.TaskStatusRunning && (got.LeaseOwner != "" || got.LeaseExpireAt != 0) {
+			t.Errorf("case %d: lease not released: %s %d", i, got.LeaseOwner, got.LeaseExpireAt)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+// 任务失败的类别
+const (
+	retryClassRetry     = "retry"     // 模型返回 type=retry（如服务未就绪）
+	retryClassTimeout   = "timeout"   // 模型执行超时
+	retryClassProcess   = "process"   // 模型进程启动失败或异常退出
+	retryClassCancelled = "cancelled" // 用户取消，永不重试
+	retryClassLimit     = "limit"     // 超出模型资源限制，默认不重试
+	retryClassError     = "error"     // 其它错误（参数、配置、模型返回失败等）
+)
+
+// retryPolicy 任务重试策略
+// 可在模型 config.json 的 easyServer.retry 或 easyServer.functions.<fn>.retry 中覆盖
+type retryPolicy struct {
+	MaxAttempts  int      `json:"maxAttempts"`  // 最多执行次数（含首次）
+	BackoffMs    int64    `json:"backoffMs"`    // 首次重试前等待时间
+	MaxBackoffMs int64    `json:"maxBackoffMs"` // 最长等待时间
+	Multiplier   float64  `json:"multiplier"`   // 每次重试等待时间的倍数
+	Retryable    []string `json:"retryable"`    // 可重试的失败类别
+}
+
+var defaultRetryPolicy = retryPolicy{
+	MaxAttempts:  3,
+	BackoffMs:    5000,
+	MaxBackoffMs: 60000,
+	Multiplier:   2,
+	Retryable:    []string{retryClassRetry, retryClassTimeout, retryClassProcess},
+}
+
+// 按功能区分的默认策略，未列出的功能使用 defaultRetryPolicy
+var functionRetryPolicies = map[string]retryPolicy{
+	// 视频生成耗时长，超时后重试大概率仍然超时
+	domain.FunctionVideoGen: {
+		MaxAttempts:  2,
+		BackoffMs:    30000,
+		MaxBackoffMs: 120000,
+		Multiplier:   2,
+		Retryable:    []string{retryClassRetry, retryClassProcess},
+	},
+	// 工作流会从已完成的步骤继续，重试代价较小
+	domain.FunctionSoundReplace: {
+		MaxAttempts:  3,
+		BackoffMs:    10000,
+		MaxBackoffMs: 120000,
+		Multiplier:   2,
+		Retryable:    []string{retryClassRetry, retryClassTimeout, retryClassProcess},
+	},
+	domain.FunctionVideoGenFlow: {
+		MaxAttempts:  2,
+		BackoffMs:    30000,
+		MaxBackoffMs: 120000,
+		Multiplier:   2,
+		Retryable:    []string{retryClassRetry, retryClassProcess},
+	},
+}
+
+// classifyTaskError 判断失败类别
+func classifyTaskError(err error) string {
+	if errors.Is(err, errTaskRetry) {
+		return retryClassRetry
+	}
+	switch easyserver.ErrorKind(err) {
+	case easyserver.ErrorKindTimeout:
+		return retryClassTimeout
+	case easyserver.ErrorKindCancelled:
+		return retryClassCancelled
+	case easyserver.ErrorKindProcess:
+		return retryClassProcess
+	case easyserver.ErrorKindLimit:
+		return retryClassLimit
+	}
+	return retryClassError
+}
+
+func (p retryPolicy) retryable(class string) bool {
+	if class == retryClassCancelled {
+		return false
+	}
+	for _, c := range p.Retryable {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff 第 attempt 次执行失败后，下一次执行前的等待时间
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(p.BackoffMs)
+	for i := 1; i < attempt; i++ {
+		delay *= p.Multiplier
+		if p.MaxBackoffMs > 0 && delay >= float64(p.MaxBackoffMs) {
+			break
+		}
+	}
+	if p.MaxBackoffMs > 0 && delay > float64(p.MaxBackoffMs) {
+		delay = float64(p.MaxBackoffMs)
+	}
+	return time.Duration(delay) * time.Millisecond
+}
+
+// merge 用配置中出现的字段覆盖当前策略
+func (p retryPolicy) merge(raw map[string]any) retryPolicy {
+	if raw == nil {
+		return p
+	}
+	if v, ok := raw["maxAttempts"]; ok {
+		p.MaxAttempts = int(toInt64(v))
+	}
+	if v, ok := raw["backoffMs"]; ok {
+		p.BackoffMs = toInt64(v)
+	}
+	if v, ok := raw["maxBackoffMs"]; ok {
+		p.MaxBackoffMs = toInt64(v)
+	}
+	if v, ok := raw["multiplier"].(float64); ok && v >= 1 {
+		p.Multiplier = v
+	}
+	if list, ok := raw["retryable"].([]any); ok {
+		p.Retryable = make([]string, 0, len(list))
+		for _, item := range list {
+			p.Retryable = append(p.Retryable, asString(item))
+		}
+	}
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = 1
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 1
+	}
+	return p
+}
+
+// taskRetryPolicy 解析任务的重试策略
+// 优先级：easyServer.functions.<fn>.retry > easyServer.retry > 功能默认值
+func taskRetryPolicy(task domain.DataTaskModel) retryPolicy {
+	fn := domain.FunctionSoundTts
+	if cfg, err := parseSoundTaskConfig(task.ModelConfig); err == nil {
+		fn = cfg.Type
+	}
+	policy, ok := functionRetryPolicies[fn]
+	if !ok {
+		policy = defaultRetryPolicy
+	}
+
+	// 工作流涉及多个模型，只有单模型任务才读取模型上的配置
+	keys := taskModelKeys(task)
+	if len(keys) != 1 {
+		return policy
+	}
+	info, err := Model.Get(keys[0])
+	if err != nil {
+		return policy
+	}
+	easyServer := asMap(info.Config["easyServer"])
+	policy = policy.merge(asMap(easyServer["retry"]))
+	policy = policy.merge(asMap(asMap(asMap(easyServer["functions"])[fn])["retry"]))
+	return policy
+}
+
+// failTask 任务执行失败：按重试策略重新排队，或标记为失败
+func failTask(taskID int64, err error) error {
+	task, getErr := DataTask.GetTask(taskID)
+	if getErr != nil {
+		return setTaskFailed(taskID, err)
+	}
+	// 执行期间被用户取消、删除或被其它执行者接管的任务不再处理
+	if task.Status != domain.TaskStatusRunning || task.LeaseOwner != taskWorkerID {
+		// 取消时强制结束了模型进程或残留子进程，补充到取消说明中
+		if cleanup := easyserver.ErrorCleanup(err); cleanup != "" && task.Status == domain.TaskStatusFail {
+			_, updateErr := DataTask.UpdateTask(taskID, map[string]any{"statusMsg": joinStatusMsg(task.StatusMsg, cleanup)})
+			return updateErr
+		}
+		return nil
+	}
+
+	class := classifyTaskError(err)
+	policy := taskRetryPolicy(task)
+	maxAttempts := task.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = policy.MaxAttempts
+	}
+	if !policy.retryable(class) || task.Attempt >= maxAttempts {
+		return setTaskFailed(taskID, err)
+	}
+
+	delay := policy.backoff(task.Attempt)
+	msg := err.Error()
+	log.Info("Task retry scheduled",
+		zap.Int64("taskId", taskID),
+		zap.String("class", class),
+		zap.Int("attempt", task.Attempt),
+		zap.Int("maxAttempts", maxAttempts),
+		zap.Duration("delay", delay),
+		zap.String("error", msg))
+
+	return finishTask(taskID, map[string]any{
+		"status":    domain.TaskStatusQueue,
+		"statusMsg": fmt.Sprintf("retry %d/%d in %ds: %s", task.Attempt+1, maxAttempts, int64(delay/time.Second), msg),
+		"lastError": msg,
+		"nextRunAt": time.Now().Add(delay).UnixMilli(),
+	})
+}
+
+func joinStatusMsg(msg, note string) string {
+	if msg == "" {
+		return note
+	}
+	return msg + "; " + note
+}
+
+// Continue 手动继续失败的任务，重新计算重试次数
+func (s *taskService) Continue(id int64) (domain.DataTaskModel, error) {
+	return s.UpdateTask(id, map[string]any{
+		"status":      domain.TaskStatusQueue,
+		"statusMsg":   "",
+		"attempt":     0,
+		"maxAttempts": 0,
+		"lastError":   "",
+		"nextRunAt":   0,
+	})
+}
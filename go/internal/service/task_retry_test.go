@@ -0,0 +1,127 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"xiacutai-server/internal/component/modelcall/easyserver"
+	"xiacutai-server/internal/domain"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	policy := retryPolicy{BackoffMs: 1000, MaxBackoffMs: 5000, Multiplier: 2}
+	cases := []struct {
+		policy  retryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{policy, 1, time.Second},
+		{policy, 2, 2 * time.Second},
+		{policy, 3, 4 * time.Second},
+		{policy, 4, 5 * time.Second}, // 不超过 maxBackoffMs
+		{policy, 50, 5 * time.Second},
+		{retryPolicy{BackoffMs: 1000, Multiplier: 3}, 3, 9 * time.Second}, // 没有上限
+		{retryPolicy{BackoffMs: 1000, Multiplier: 1}, 5, time.Second},
+		{retryPolicy{BackoffMs: 8000, MaxBackoffMs: 5000, Multiplier: 2}, 1, 5 * time.Second},
+	}
+	for _, c := range cases {
+		if got := c.policy.backoff(c.attempt); got != c.want {
+			t.Errorf("%+v backoff(%d) = %s, want %s", c.policy, c.attempt, got, c.want)
+		}
+	}
+}
+
+func TestClassifyTaskError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{errTaskRetry, retryClassRetry},
+		{fmt.Errorf("wrapped: %w", errTaskRetry), retryClassRetry},
+		{&easyserver.CallError{Kind: easyserver.ErrorKindTimeout}, retryClassTimeout},
+		{&easyserver.CallError{Kind: easyserver.ErrorKindCancelled}, retryClassCancelled},
+		{&easyserver.CallError{Kind: easyserver.ErrorKindProcess}, retryClassProcess},
+		{&easyserver.CallError{Kind: easyserver.ErrorKindLimit}, retryClassLimit},
+		{errors.New("bad param"), retryClassError},
+	}
+	for _, c := range cases {
+		if got := classifyTaskError(c.err); got != c.want {
+			t.Errorf("classifyTaskError(%v) = %s, want %s", c.err, got, c.want)
+		}
+	}
+
+	// 取消永不重试，即使配置中列出
+	policy := retryPolicy{Retryable: []string{retryClassCancelled, retryClassTimeout}}
+	if policy.retryable(retryClassCancelled) || !policy.retryable(retryClassTimeout) || policy.retryable(retryClassLimit) {
+		t.Fatal("unexpected retryable classes")
+	}
+}
+
+func TestRetryPolicyMerge(t *testing.T) {
+	got := defaultRetryPolicy.merge(map[string]any{
+		"maxAttempts": float64(0),
+		"backoffMs":   "200",
+		"multiplier":  0.5,
+		"retryable":   []any{"timeout"},
+	})
+	if got.MaxAttempts != 1 || got.BackoffMs != 200 || got.Multiplier != defaultRetryPolicy.Multiplier ||
+		got.MaxBackoffMs != defaultRetryPolicy.MaxBackoffMs || len(got.Retryable) != 1 || got.Retryable[0] != "timeout" {
+		t.Fatalf("unexpected merged policy %+v", got)
+	}
+	if merged := defaultRetryPolicy.merge(nil); merged.MaxAttempts != defaultRetryPolicy.MaxAttempts {
+		t.Fatalf("nil config should keep the policy, got %+v", merged)
+	}
+}
+
+func TestFailTaskRequeuesUntilMaxAttempts(t *testing.T) {
+	resetTasks(t)
+	task := newQueuedTask(t, map[string]any{"type": domain.FunctionSoundTts}, domain.DataTaskModel{MaxAttempts: 2})
+	timeout := &easyserver.CallError{Kind: easyserver.ErrorKindTimeout, Msg: "model timeout"}
+
+	claim := func() {
+		t.Helper()
+		if _, err := DataTask.UpdateTask(task.ID, map[string]any{"nextRunAt": 0}); err != nil {
+			t.Fatal(err)
+		}
+		ok, err := DataTask.ClaimTask(mustGetTask(t, task.ID), taskWorkerID, time.Minute)
+		if err != nil || !ok {
+			t.Fatalf("claim: %v %v", ok, err)
+		}
+	}
+
+	claim()
+	before := time.Now()
+	if err := failTask(task.ID, timeout); err != nil {
+		t.Fatal(err)
+	}
+	got := mustGetTask(t, task.ID)
+	if got.Status != domain.TaskStatusQueue || got.LeaseOwner != "" || got.LastError != timeout.Error() {
+		t.Fatalf("expected requeue, got %+v", got)
+	}
+	// soundTts 使用默认策略，第 1 次失败后等待 backoffMs
+	wait := time.Duration(got.NextRunAt-before.UnixMilli()) * time.Millisecond
+	if wait < time.Duration(defaultRetryPolicy.BackoffMs)*time.Millisecond-time.Second || wait > time.Duration(defaultRetryPolicy.BackoffMs)*time.Millisecond+time.Second {
+		t.Fatalf("unexpected backoff %s", wait)
+	}
+
+	claim()
+	if err := failTask(task.ID, timeout); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGetTask(t, task.ID); got.Status != domain.TaskStatusFail || got.Attempt != 2 {
+		t.Fatalf("expected fail after max attempts, got %s attempt %d", got.Status, got.Attempt)
+	}
+
+	// 不可重试的错误直接失败
+	if _, err := DataTask.Continue(task.ID); err != nil {
+		t.Fatal(err)
+	}
+	claim()
+	if err := failTask(task.ID, errors.New("bad param")); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGetTask(t, task.ID); got.Status != domain.TaskStatusFail || got.Attempt != 1 {
+		t.Fatalf("expected immediate fail, got %s attempt %d", got.Status, got.Attempt)
+	}
+}
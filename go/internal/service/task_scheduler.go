@@ -8,7 +8,6 @@ import (
 	"time"
 	"xiacutai-server/internal/component/errs"
 	"xiacutai-server/internal/component/log"
-	"xiacutai-server/internal/component/modelcall"
 	"xiacutai-server/internal/component/modelcall/easyserver"
 	"xiacutai-server/internal/component/sqllite"
 	"xiacutai-server/internal/domain"
@@ -19,7 +18,7 @@ import (
 
 var errTaskRetry = errors.New("task requires retry")
 
-type taskConfig struct {
+type TaskConfig struct {
 	Type              string                 `json:"type"`
 	TtsServerKey      string                 `json:"ttsServerKey"`
 	TtsParam          map[string]any         `json:"ttsParam"`
@@ -34,17 +33,36 @@ type taskConfig struct {
 	SoundGenerate     map[string]any         `json:"soundGenerate"`
 	Audio             string                 `json:"audio"`
 	Text              string                 `json:"text"`
+	Image             string                 `json:"image"`
+	Mask              string                 `json:"mask"`
+	ImageParam        map[string]any         `json:"imageParam"`
 	VideoTemplateID   int64                  `json:"videoTemplateId"`
 	VideoTemplateName string                 `json:"videoTemplateName"`
 	VideoTemplateURL  string                 `json:"videoTemplateUrl"`
+	Timeout           int                    `json:"timeout"`     // 任务指定的最长执行时间（秒），0 使用模型配置
+	IdleTimeout       int                    `json:"idleTimeout"` // 任务指定的空闲超时（秒），0 使用模型配置
+	NoCache           bool                   `json:"noCache"`     // 不使用结果缓存
 	Extra             map[string]interface{} `json:"-"`
 }
 
+// callTimeout 任务指定的模型调用超时
+func (c *TaskConfig) callTimeout() easyserver.CallTimeout {
+	return easyserver.CallTimeout{
+		Total: time.Duration(c.Timeout) * time.Second,
+		Idle:  time.Duration(c.IdleTimeout) * time.Second,
+	}
+}
+
+var taskWorkers = newTaskPool(getTaskConcurrency())
+
 func StartTaskScheduler(ctx context.Context) {
+	// 上次运行中断的任务先恢复，再开始调度
+	recoverInterruptedTasks()
+
 	interval := getTaskPollInterval()
 	ticker := time.NewTicker(interval)
 
-	log.Info("DataTask scheduler started", zap.Duration("interval", interval))
+	log.Info("DataTask scheduler started", zap.Duration("interval", interval), zap.Int("concurrency", taskWorkers.size))
 
 	go func() {
 		defer ticker.Stop()
@@ -54,9 +72,10 @@ func StartTaskScheduler(ctx context.Context) {
 				log.Info("DataTask scheduler stopped")
 				return
 			case <-ticker.C:
-				if err := runTaskSchedulerOnce(); err != nil {
-					log.Error("DataTask scheduler failed", zap.Error(err))
-				}
+			case <-taskWorkers.wake:
+			}
+			if err := runTaskSchedulerOnce(); err != nil {
+				log.Error("DataTask scheduler failed", zap.Error(err))
 			}
 		}
 	}()
@@ -76,103 +95,100 @@ func getTaskPollInterval() time.Duration {
 }
 
 func runTaskSchedulerOnce() error {
+	// 其它执行者失联留下的任务，租约过期后重新排队
+	reclaimExpiredTasks()
+
 	filters := sqllite.TaskFilters{
-		Status: []string{domain.TaskStatusQueue},
+		Status:     []string{domain.TaskStatusQueue},
+		QueueOrder: true,
+		RunnableAt: time.Now().UnixMilli(),
 	}
 	tasks, err := DataTask.ListTasks(filters)
 	if err != nil {
 		return err
 	}
 
+	// 同一轮调度里模型并发上限只查一次
+	limits := map[string]int{}
 	for _, task := range tasks {
-		//if task.Biz != "SoundGenerate" {
-		//	continue
-		//}
-		if err := handleSoundTask(task); err != nil {
-			log.Error("Handle task failed", zap.Int64("taskId", task.ID), zap.Error(err))
+		if taskWorkers.isFull() {
+			break
+		}
+		if taskWorkers.isRunning(task.ID) {
+			continue
+		}
+		keys := taskModelKeys(task)
+		for _, key := range keys {
+			if _, ok := limits[key]; !ok {
+				limits[key] = modelConcurrencyLimit(key)
+			}
+		}
+		if !taskWorkers.tryAcquire(task.ID, keys, limits) {
+			continue
 		}
+		go runTaskWorker(task.ID)
 	}
 	return nil
 }
 
-func handleSoundTask(task domain.DataTaskModel) error {
-	if task.Status != domain.TaskStatusQueue {
-		return nil
-	}
-
-	if err := setTaskRunning(task.ID); err != nil {
-		return err
-	}
+func runTaskWorker(taskID int64) {
+	defer taskWorkers.release(taskID)
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("Task worker panic", zap.Int64("taskId", taskID), zap.Any("panic", r))
+			_ = failTask(taskID, errs.New(fmt.Sprintf("task panic: %v", r)))
+		}
+	}()
 
-	cfg, err := parseSoundTaskConfig(task.ModelConfig)
+	// 派发到执行之间任务可能已被取消、删除或被其它执行者领取，以数据库最新状态为准
+	task, err := DataTask.GetTask(taskID)
 	if err != nil {
-		return setTaskFailed(task.ID, err)
+		log.Error("Load task failed", zap.Int64("taskId", taskID), zap.Error(err))
+		return
 	}
-	if cfg.Type == domain.FunctionSoundReplace {
-		if runErr := runSoundReplaceTask(task, cfg); runErr != nil {
-			return setTaskFailed(task.ID, runErr)
-		}
-		return nil
+	claimed, err := DataTask.ClaimTask(task, taskWorkerID, taskLease)
+	if err != nil {
+		log.Error("Claim task failed", zap.Int64("taskId", taskID), zap.Error(err))
+		return
 	}
-	if cfg.Type == domain.FunctionVideoGenFlow {
-		if runErr := runVideoGenFlowTask(task, cfg); runErr != nil {
-			return setTaskFailed(task.ID, runErr)
-		}
-		return nil
+	if !claimed {
+		return
 	}
+	stopHeartbeat := startTaskHeartbeat(taskID)
+	defer stopHeartbeat()
 
-	serverKey := cfg.TtsServerKey
-	if cfg.Type == domain.FunctionSoundClone {
-		serverKey = cfg.CloneServerKey
-	}
-	if cfg.Type == domain.FunctionVideoGen {
-		serverKey = cfg.ServerKey
-	}
-	if cfg.Type == domain.FunctionSoundAsr {
-		serverKey = cfg.ServerKey
-	}
-	modelInfo, err := Model.Get(serverKey)
-	if err != nil {
-		return setTaskFailed(task.ID, err)
+	if err := handleSoundTask(task); err != nil {
+		log.Error("Handle task failed", zap.Int64("taskId", task.ID), zap.Error(err))
 	}
+}
 
-	serverConfig, err := modelcall.LoadConfigFromJSON(modelInfo.Path + "/config.json")
+// handleSoundTask 执行已领取的任务
+func handleSoundTask(task domain.DataTaskModel) error {
+	cfg, err := parseSoundTaskConfig(task.ModelConfig)
 	if err != nil {
-		return setTaskFailed(task.ID, err)
+		return failTask(task.ID, err)
 	}
-
-	serverInfo := &easyserver.ServerInfo{
-		LocalPath:        modelInfo.Path,
-		Name:             modelInfo.Name,
-		Version:          modelInfo.Version,
-		Setting:          modelInfo.Setting,
-		LogFile:          "",
-		EventChannelName: "",
-		Config:           *serverConfig,
+	handler, err := getTaskHandler(cfg.Type)
+	if err != nil {
+		return failTask(task.ID, err)
 	}
-
-	server := easyserver.NewEasyServer(*serverConfig)
-	registerTaskServer(task.ID, server)
-	defer unregisterTaskServer(task.ID)
-	server.ServerInfo = serverInfo
-	if err := server.Start(); err != nil {
-		return setTaskFailed(task.ID, err)
+	if err := handler.Validate(cfg); err != nil {
+		return failTask(task.ID, err)
 	}
 
-	result, err := callEasyServerTask(task, cfg, server)
+	result, err := handler.Execute(task, cfg)
 	if err != nil {
-		return setTaskFailed(task.ID, err)
+		return failTask(task.ID, err)
 	}
-
-	if result != nil {
-		return updateTaskResult(task.ID, result)
+	// 多步骤工作流已自行保存状态
+	if result == nil {
+		return nil
 	}
-
-	return setTaskFailed(task.ID, errs.New("empty task result"))
+	return updateTaskResult(task.ID, handler, result)
 }
 
-func parseSoundTaskConfig(raw string) (*taskConfig, error) {
-	cfg := &taskConfig{}
+func parseSoundTaskConfig(raw string) (*TaskConfig, error) {
+	cfg := &TaskConfig{}
 	if err := json.Unmarshal([]byte(raw), cfg); err != nil {
 		return nil, err
 	}
@@ -182,75 +198,29 @@ func parseSoundTaskConfig(raw string) (*taskConfig, error) {
 	return cfg, nil
 }
 
-func setTaskRunning(taskID int64) error {
-	_, err := DataTask.UpdateTask(taskID, map[string]any{
-		"status":    domain.TaskStatusRunning,
-		"startTime": time.Now().UnixMilli(),
-	})
-	return err
-}
-
 func setTaskFailed(taskID int64, err error) error {
 	statusMsg := ""
 	if err != nil {
 		statusMsg = err.Error()
 	}
-	_, updateErr := DataTask.UpdateTask(taskID, map[string]any{
+	return finishTask(taskID, map[string]any{
 		"status":    domain.TaskStatusFail,
 		"statusMsg": statusMsg,
+		"lastError": statusMsg,
 		"endTime":   time.Now().UnixMilli(),
 	})
-	return updateErr
 }
 
-func callEasyServerTask(task domain.DataTaskModel, cfg *taskConfig, server *easyserver.EasyServer) (*easyserver.TaskResult, error) {
-	taskID := fmt.Sprintf("task-%d", task.ID)
-	data := easyserver.ServerFunctionDataType{
-		ID:     taskID,
-		Result: map[string]interface{}{},
-		Param:  cfg.TtsParam,
-		Text:   cfg.Text,
-	}
-
-	switch cfg.Type {
-	case domain.FunctionSoundClone:
-		data.Param = cfg.CloneParam
-		data.PromptAudio = cfg.PromptURL
-		data.PromptText = cfg.PromptText
-		data.Text = cfg.Text
-		return server.SoundClone(data)
-	case domain.FunctionSoundAsr:
-		data.Param = map[string]interface{}{}
-		data.Audio = cfg.Audio
-		return server.Asr(data)
-	case domain.FunctionVideoGen:
-		data.Param = cfg.VideoParam
-		data.Video = cfg.Video
-		data.Audio = cfg.Audio
-		return server.VideoGen(data)
-	default:
-		data.Param = cfg.TtsParam
-		data.Text = cfg.Text
-		return server.SoundTts(data)
-	}
-}
-
-func updateTaskResult(taskID int64, result *easyserver.TaskResult) error {
+func updateTaskResult(taskID int64, handler TaskHandler, result *easyserver.TaskResult) error {
 	jobResult, err := json.Marshal(result)
 	if err != nil {
 		return err
 	}
 
-	resultData, err := extractResultData(result)
+	resultData, err := handler.ExtractResult(result)
 	if err != nil {
-		if errors.Is(err, errTaskRetry) {
-			_, updateErr := DataTask.UpdateTask(taskID, map[string]any{
-				"status":    domain.TaskStatusQueue,
-				"statusMsg": err.Error(),
-			})
-			return updateErr
-		}
-		return setTaskFailed(taskID, err)
+		// type=retry 也受重试次数限制，避免无限重新排队
+		return failTask(taskID, err)
 	}
 
 	resultRaw, err := json.Marshal(resultData)
@@ -265,8 +235,7 @@ func updateTaskResult(taskID int64, result *easyserver.TaskResult) error {
 		"status":    domain.TaskStatusSuccess,
 	}
 
-	_, err = DataTask.UpdateTask(taskID, updates)
-	return err
+	return finishTask(taskID, updates)
 }
 
 func extractResultData(result *easyserver.TaskResult) (map[string]any, error) {
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+	"xiacutai-server/internal/component/modelcall/modeltest"
+	"xiacutai-server/internal/domain"
+)
+
+// addFakeModel 注册一个假模型，返回 key
+func addFakeModel(t *testing.T) string {
+	t.Helper()
+	dir := modeltest.ModelDir(t, "basic")
+	info, err := Model.ModelAdd(filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = Model.ModelDelete(info.Name, info.Version) })
+	return domain.ModelKey(info.Name, info.Version)
+}
+
+// runTask 按调度器的方式占用槽位后同步执行一次任务
+func runTask(t *testing.T, id int64) domain.DataTaskModel {
+	t.Helper()
+	task := mustGetTask(t, id)
+	if !taskWorkers.tryAcquire(id, taskModelKeys(task), nil) {
+		t.Fatalf("task %d not admitted", id)
+	}
+	runTaskWorker(id)
+	return mustGetTask(t, id)
+}
+
+func taskResult(t *testing.T, task domain.DataTaskModel) map[string]any {
+	t.Helper()
+	result := map[string]any{}
+	if err := json.Unmarshal([]byte(task.Result), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestRunTaskWorkerSoundTts(t *testing.T) {
+	resetTasks(t)
+	key := addFakeModel(t)
+
+	task, err := DataTask.CreateFromRequest(TaskCreateRequest{Type: domain.FunctionSoundTts, ServerKey: key, Text: "你好", NoCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := runTask(t, task.ID)
+	if got.Status != domain.TaskStatusSuccess || got.LeaseOwner != "" || got.Attempt != 1 {
+		t.Fatalf("unexpected task: status %s owner %q attempt %d msg %s", got.Status, got.LeaseOwner, got.Attempt, got.StatusMsg)
+	}
+	url, _ := taskResult(t, got)["url"].(string)
+	if _, err := os.Stat(url); err != nil {
+		t.Fatalf("result audio missing: %v", err)
+	}
+
+	// 模型返回的错误不可重试，任务直接失败
+	task, err = DataTask.CreateFromRequest(TaskCreateRequest{Type: domain.FunctionSoundTts, ServerKey: key, Text: "你好", NoCache: true,
+		Param: map[string]any{"fake": map[string]any{"error": "bad input"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := runTask(t, task.ID); got.Status != domain.TaskStatusFail || !strings.Contains(got.StatusMsg, "bad input") {
+		t.Fatalf("expected fail with model error, got %s %q", got.Status, got.StatusMsg)
+	}
+}
+
+func TestModelStopCancelsRunningTask(t *testing.T) {
+	resetTasks(t)
+	key := addFakeModel(t)
+
+	task, err := DataTask.CreateFromRequest(TaskCreateRequest{Type: domain.FunctionSoundTts, ServerKey: key, Text: "你好", NoCache: true,
+		Param: map[string]any{"fake": map[string]any{"hang": true}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !taskWorkers.tryAcquire(task.ID, taskModelKeys(task), nil) {
+		t.Fatal("task not admitted")
+	}
+	done := make(chan struct{})
+	go func() {
+		runTaskWorker(task.ID)
+		close(done)
+	}()
+
+	// 任务的调用计入模型状态
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		state, err := Model.ModelStatus(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.Calls == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task call not reported, state %+v", state)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if _, err := Model.ModelStop(key); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+		// 取消不重试
+		if got := mustGetTask(t, task.ID); got.Status != domain.TaskStatusFail || got.Attempt != 1 {
+			t.Fatalf("expected cancelled task to fail, got %s attempt %d msg %s", got.Status, got.Attempt, got.StatusMsg)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("ModelStop did not cancel the running task")
+	}
+	if state, _ := Model.ModelStatus(key); state.Calls != 0 {
+		t.Fatalf("call should be released, got %d", state.Calls)
+	}
+}
+
+func TestRunTaskWorkerVideoGenFlow(t *testing.T) {
+	resetTasks(t)
+	key := addFakeModel(t)
+	template := filepath.Join(t.TempDir(), "template.mp4")
+	if err := os.WriteFile(template, []byte("template"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	task := newQueuedTask(t, map[string]any{
+		"type":             domain.FunctionVideoGenFlow,
+		"videoTemplateUrl": template,
+		"text":             "你好",
+		"noCache":          true,
+		"soundGenerate":    map[string]any{"type": "soundTts", "ttsServerKey": key},
+	}, domain.DataTaskModel{ServerName: "fake-model", ServerVersion: "1.0.0"})
+	got := runTask(t, task.ID)
+	if got.Status != domain.TaskStatusSuccess {
+		t.Fatalf("expected success, got %s %s", got.Status, got.StatusMsg)
+	}
+	result := taskResult(t, got)
+	for _, field := range []string{"urlSound", "url"} {
+		path, _ := result[field].(string)
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s missing: %v", field, err)
+		}
+	}
+	job := map[string]any{}
+	_ = json.Unmarshal([]byte(got.JobResult), &job)
+	if job["soundTts"] == nil || job["videoGen"] == nil {
+		t.Fatalf("job result should keep both steps: %s", got.JobResult)
+	}
+}
+
+// fakeFFmpeg 用 shell 脚本代替 ffmpeg/ffprobe：ffmpeg 把最后一个参数写成文件，ffprobe 输出 1 秒
+func fakeFFmpeg(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	ffmpeg := "#!/bin/sh\nfor last; do :; done\necho fake > \"$last\"\n"
+	ffprobe := "#!/bin/sh\necho 1.000\n"
+	for path, script := range map[string]string{
+		filepath.Join(dir, "binary", "ffmpeg.exe"):  ffmpeg,
+		filepath.Join(dir, "binary", "ffprobe.exe"): ffprobe,
+		filepath.Join(dir, "bin", "ffmpeg"):         ffmpeg,
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 测试进程中 GetExeDir 返回工作目录
+	t.Chdir(dir)
+	t.Setenv("PATH", filepath.Join(dir, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestRunTaskWorkerSoundReplace(t *testing.T) {
+	resetTasks(t)
+	key := addFakeModel(t)
+	fakeFFmpeg(t)
+	video := filepath.Join(t.TempDir(), "source.mp4")
+	if err := os.WriteFile(video, []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	task := newQueuedTask(t, map[string]any{
+		"type":          domain.FunctionSoundReplace,
+		"video":         video,
+		"noCache":       true,
+		"soundAsr":      map[string]any{"serverKey": key},
+		"soundGenerate": map[string]any{"type": "soundTts", "ttsServerKey": key},
+	}, domain.DataTaskModel{Biz: "SoundReplace"})
+
+	// 识别后等待人工确认，租约已释放
+	got := runTask(t, task.ID)
+	job := map[string]any{}
+	_ = json.Unmarshal([]byte(got.JobResult), &job)
+	if got.Status != domain.TaskStatusWait || job["step"] != "Confirm" || got.LeaseOwner != "" {
+		t.Fatalf("expected wait for confirm, got %s step %v msg %s", got.Status, job["step"], got.StatusMsg)
+	}
+	records, _ := parseSoundReplaceRecords(asMap(job["Confirm"])["records"])
+	if len(records) != 2 {
+		t.Fatalf("expected 2 asr records, got %d", len(records))
+	}
+
+	confirmed := []SoundReplaceConfirmRecord{{Text: "第一段", Start: 0, End: 800}, {Text: "第二段", Start: 1000, End: 1800}}
+	if _, err := SubmitSoundReplaceConfirm(task.ID, confirmed); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGetTask(t, task.ID); got.Status != domain.TaskStatusQueue {
+		t.Fatalf("confirm should requeue the task, got %s", got.Status)
+	}
+
+	// 逐段生成并合成
+	got = runTask(t, task.ID)
+	if got.Status != domain.TaskStatusSuccess {
+		t.Fatalf("expected success, got %s %s", got.Status, got.StatusMsg)
+	}
+	result := taskResult(t, got)
+	for _, field := range []string{"url", "audio"} {
+		path, _ := result[field].(string)
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s missing: %v", field, err)
+		}
+	}
+	generated, _ := parseSoundReplaceRecords(result["records"])
+	if len(generated) != 2 || generated[0].Text != "第一段" || generated[1].Audio == "" {
+		t.Fatalf("unexpected generated records %+v", generated)
+	}
+}
@@ -10,7 +10,7 @@ import (
 	"xiacutai-server/internal/domain"
 )
 
-func runVideoGenFlowTask(task domain.DataTaskModel, cfg *taskConfig) error {
+func runVideoGenFlowTask(task domain.DataTaskModel, cfg *TaskConfig) error {
 	videoPath := strings.TrimSpace(cfg.VideoTemplateURL)
 	if videoPath == "" {
 		videoPath = strings.TrimSpace(cfg.Video)
@@ -28,13 +28,13 @@ func runVideoGenFlowTask(task domain.DataTaskModel, cfg *taskConfig) error {
 	if videoServerKey == "" {
 		return errs.New("serverKey is required")
 	}
-	videoServer, err := startEasyServerByKey(videoServerKey)
+	videoServer, releaseVideo, err := startEasyServerByKey(videoServerKey, cfg)
 	if err != nil {
 		return err
 	}
 	registerTaskServer(task.ID, videoServer)
 	defer func() {
-		_ = videoServer.Stop()
+		releaseVideo()
 		unregisterTaskServer(task.ID)
 	}()
 
@@ -62,13 +62,12 @@ func runVideoGenFlowTask(task domain.DataTaskModel, cfg *taskConfig) error {
 	}
 	resultRaw, _ := json.Marshal(resultData)
 
-	_, err = DataTask.UpdateTask(task.ID, map[string]any{
+	return finishTask(task.ID, map[string]any{
 		"status":    domain.TaskStatusSuccess,
 		"jobResult": string(jobResultRaw),
 		"result":    string(resultRaw),
 		"endTime":   time.Now().UnixMilli(),
 	})
-	return err
 }
 
 type soundResultPayload struct {
@@ -76,7 +75,7 @@ type soundResultPayload struct {
 	raw  *easyserver.TaskResult
 }
 
-func runVideoGenFlowSoundGenerate(task domain.DataTaskModel, cfg *taskConfig) (string, *soundResultPayload, error) {
+func runVideoGenFlowSoundGenerate(task domain.DataTaskModel, cfg *TaskConfig) (string, *soundResultPayload, error) {
 	soundGenerate := cfg.SoundGenerate
 	if len(soundGenerate) == 0 {
 		return "", nil, errs.New("soundGenerate is required")
@@ -108,21 +107,21 @@ func runVideoGenFlowSoundGenerate(task domain.DataTaskModel, cfg *taskConfig) (s
 		return "", nil, errs.New("soundGenerate server key is required")
 	}
 
-	soundServer, err := startEasyServerByKey(serverKey)
+	soundServer, releaseSound, err := startEasyServerByKey(serverKey, cfg)
 	if err != nil {
 		return "", nil, err
 	}
 	registerTaskServer(task.ID, soundServer)
 	defer func() {
-		_ = soundServer.Stop()
+		releaseSound()
 		unregisterTaskServer(task.ID)
 	}()
 
 	var soundRes *easyserver.TaskResult
 	if method == "soundClone" {
-		soundRes, err = soundServer.SoundClone(callData)
+		soundRes, err = callWithResultCache(serverCacheModel(soundServer), domain.FunctionSoundClone, callData, cfg.NoCache, soundServer.SoundClone)
 	} else {
-		soundRes, err = soundServer.SoundTts(callData)
+		soundRes, err = callWithResultCache(serverCacheModel(soundServer), domain.FunctionSoundTts, callData, cfg.NoCache, soundServer.SoundTts)
 	}
 	if err != nil {
 		return "", nil, err
@@ -0,0 +1,362 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"xiacutai-server/internal/component/log"
+	"xiacutai-server/internal/component/sqllite"
+	"xiacutai-server/internal/domain"
+	"xiacutai-server/internal/utils"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	webhookMaxAttempts  = 8
+	webhookBackoff      = 5 * time.Second
+	webhookMaxBackoff   = 10 * time.Minute
+	webhookTimeout      = 10 * time.Second
+	webhookPollInterval = 5 * time.Second
+	webhookBatchSize    = 20
+	webhookConcurrency  = 4 // 同时投递的回调地址数
+)
+
+// 回调请求头
+const (
+	WebhookHeaderEvent     = "X-Aigcpanel-Event"
+	WebhookHeaderDelivery  = "X-Aigcpanel-Delivery"
+	WebhookHeaderTimestamp = "X-Aigcpanel-Timestamp"
+	WebhookHeaderSignature = "X-Aigcpanel-Signature" // sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+)
+
+type webhookService struct {
+	client *http.Client
+	wake   chan struct{}
+}
+
+var Webhook = &webhookService{
+	client: &http.Client{Timeout: webhookTimeout},
+	wake:   make(chan struct{}, 1),
+}
+
+// WebhookPayload 回调内容
+type WebhookPayload struct {
+	Event     string         `json:"event"`
+	Timestamp int64          `json:"timestamp"`
+	Task      webhookTask    `json:"task"`
+	Result    map[string]any `json:"result,omitempty"`
+	Artifacts []string       `json:"artifacts,omitempty"` // 结果中存在于本地的文件
+}
+
+type webhookTask struct {
+	ID            int64  `json:"id"`
+	Biz           string `json:"biz"`
+	Title         string `json:"title"`
+	Status        string `json:"status"`
+	StatusMsg     string `json:"statusMsg"`
+	ServerName    string `json:"serverName"`
+	ServerVersion string `json:"serverVersion"`
+	StartTime     int64  `json:"startTime"`
+	EndTime       int64  `json:"endTime"`
+	Attempt       int    `json:"attempt"`
+}
+
+// ValidWebhookURL 仅允许 http/https 地址
+func ValidWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func globalWebhookURL() string {
+	return strings.TrimSpace(utils.GetEnv("AIGCPANEL_WEBHOOK_URL", ""))
+}
+
+func webhookSecret() string {
+	return utils.GetEnv("AIGCPANEL_WEBHOOK_SECRET", "")
+}
+
+// StartWebhookDispatcher 定期投递待发送的回调
+// 投递记录在任务状态写入时同一个事务中创建（见 updateTaskRow），这里只负责投递和重试
+func StartWebhookDispatcher(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(webhookPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-Webhook.wake:
+			}
+			Webhook.deliverDue()
+		}
+	}()
+}
+
+// isWebhookEvent 任务进入这些状态时回调
+func isWebhookEvent(status string) bool {
+	switch status {
+	case domain.TaskStatusSuccess, domain.TaskStatusFail, domain.TaskStatusWait:
+		return true
+	}
+	return false
+}
+
+// enqueue 在任务状态更新的事务中为每个回调地址写入一条待投递记录，没有回调地址时返回 false
+func (s *webhookService) enqueue(tx *gorm.DB, taskID int64, event string) (bool, error) {
+	var task domain.DataTaskModel
+	if err := tx.First(&task, taskID).Error; err != nil {
+		return false, err
+	}
+	urls := make([]string, 0, 2)
+	for _, u := range []string{task.CallbackURL, globalWebhookURL()} {
+		if u != "" && ValidWebhookURL(u) && !utils.Contains(urls, u) {
+			urls = append(urls, u)
+		}
+	}
+	if len(urls) == 0 {
+		return false, nil
+	}
+
+	payload, err := json.Marshal(buildWebhookPayload(event, task))
+	if err != nil {
+		return false, err
+	}
+	now := time.Now().UnixMilli()
+	for _, u := range urls {
+		delivery := domain.DataWebhookDeliveryModel{
+			CreatedAt: now,
+			UpdatedAt: now,
+			TaskID:    task.ID,
+			Event:     event,
+			URL:       u,
+			Payload:   string(payload),
+			Status:    domain.WebhookDeliveryPending,
+		}
+		if err := tx.Create(&delivery).Error; err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// notify 有新的投递记录时立即投递，不等下一次轮询
+func (s *webhookService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func buildWebhookPayload(event string, task domain.DataTaskModel) WebhookPayload {
+	payload := WebhookPayload{
+		Event:     event,
+		Timestamp: time.Now().UnixMilli(),
+		Task: webhookTask{
+			ID:            task.ID,
+			Biz:           task.Biz,
+			Title:         task.Title,
+			Status:        task.Status,
+			StatusMsg:     task.StatusMsg,
+			ServerName:    task.ServerName,
+			ServerVersion: task.ServerVersion,
+			StartTime:     task.StartTime,
+			EndTime:       task.EndTime,
+			Attempt:       task.Attempt,
+		},
+	}
+	result := map[string]any{}
+	if strings.TrimSpace(task.Result) != "" {
+		_ = json.Unmarshal([]byte(task.Result), &result)
+	}
+	if len(result) > 0 {
+		payload.Result = result
+		payload.Artifacts = collectArtifacts(result)
+	}
+	return payload
+}
+
+// collectArtifacts 收集结果中指向本地文件的路径
+func collectArtifacts(v any) []string {
+	files := make([]string, 0)
+	var walk func(v any)
+	walk = func(v any) {
+		switch val := v.(type) {
+		case map[string]any:
+			for _, item := range val {
+				walk(item)
+			}
+		case []any:
+			for _, item := range val {
+				walk(item)
+			}
+		case string:
+			if val == "" || utils.Contains(files, val) {
+				return
+			}
+			if info, err := os.Stat(val); err == nil && !info.IsDir() {
+				files = append(files, val)
+			}
+		}
+	}
+	walk(v)
+	return files
+}
+
+// deliverDue 投递到期的回调
+// 按地址分组，同一地址按顺序逐条投递，不同地址并发投递，无响应的地址不影响同一批中其它地址
+func (s *webhookService) deliverDue() {
+	var deliveries []domain.DataWebhookDeliveryModel
+	if err := sqllite.GetSession().
+		Where("status = ? AND nextRetryAt <= ?", domain.WebhookDeliveryPending, time.Now().UnixMilli()).
+		Order("id ASC").
+		Limit(webhookBatchSize).
+		Find(&deliveries).Error; err != nil {
+		log.Error("Load webhook deliveries failed", zap.Error(err))
+		return
+	}
+
+	groups := make(map[string][]domain.DataWebhookDeliveryModel)
+	urls := make([]string, 0)
+	for _, delivery := range deliveries {
+		if _, ok := groups[delivery.URL]; !ok {
+			urls = append(urls, delivery.URL)
+		}
+		groups[delivery.URL] = append(groups[delivery.URL], delivery)
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, webhookConcurrency)
+	for _, u := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(group []domain.DataWebhookDeliveryModel) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			for _, delivery := range group {
+				s.deliver(delivery)
+			}
+		}(groups[u])
+	}
+	wg.Wait()
+}
+
+func (s *webhookService) deliver(delivery domain.DataWebhookDeliveryModel) {
+	// 多个实例共用数据库时，先推迟下次投递时间占住这条记录，避免重复投递
+	claim := sqllite.GetSession().
+		Model(&domain.DataWebhookDeliveryModel{}).
+		Where("id = ? AND status = ? AND nextRetryAt = ?", delivery.ID, domain.WebhookDeliveryPending, delivery.NextRetryAt).
+		Update("nextRetryAt", time.Now().Add(2*webhookTimeout).UnixMilli())
+	if claim.Error != nil || claim.RowsAffected != 1 {
+		return
+	}
+
+	code, err := s.post(delivery)
+	attempt := delivery.Attempt + 1
+	updates := map[string]any{
+		"attempt":      attempt,
+		"responseCode": code,
+		"updatedAt":    time.Now().UnixMilli(),
+	}
+	if err == nil {
+		updates["status"] = domain.WebhookDeliverySuccess
+		updates["lastError"] = ""
+	} else {
+		updates["lastError"] = err.Error()
+		if attempt >= webhookMaxAttempts {
+			updates["status"] = domain.WebhookDeliveryFail
+		} else {
+			updates["nextRetryAt"] = time.Now().Add(webhookRetryDelay(attempt)).UnixMilli()
+		}
+		log.Warn("Webhook delivery failed",
+			zap.Int64("deliveryId", delivery.ID),
+			zap.Int64("taskId", delivery.TaskID),
+			zap.String("url", delivery.URL),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+	}
+	if err := sqllite.GetSession().
+		Model(&domain.DataWebhookDeliveryModel{}).
+		Where("id = ?", delivery.ID).
+		Updates(updates).Error; err != nil {
+		log.Error("Update webhook delivery failed", zap.Int64("deliveryId", delivery.ID), zap.Error(err))
+	}
+}
+
+func webhookRetryDelay(attempt int) time.Duration {
+	delay := webhookBackoff
+	for i := 1; i < attempt && delay < webhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxBackoff {
+		delay = webhookMaxBackoff
+	}
+	return delay
+}
+
+func (s *webhookService) post(delivery domain.DataWebhookDeliveryModel) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderEvent, delivery.Event)
+	req.Header.Set(WebhookHeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	if secret := webhookSecret(); secret != "" {
+		req.Header.Set(WebhookHeaderSignature, "sha256="+SignWebhook(secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhook 计算回调签名，接收方用同样的方式校验
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ListDeliveries 查询任务的回调投递记录
+func (s *webhookService) ListDeliveries(taskID int64) ([]domain.DataWebhookDeliveryModel, error) {
+	query := sqllite.GetSession().Model(&domain.DataWebhookDeliveryModel{})
+	if taskID > 0 {
+		query = query.Where("taskId = ?", taskID)
+	}
+	var deliveries []domain.DataWebhookDeliveryModel
+	if err := query.Order("id DESC").Limit(200).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"xiacutai-server/internal/component/sqllite"
+	"xiacutai-server/internal/domain"
+)
+
+func listDeliveries(t *testing.T, taskID int64) []domain.DataWebhookDeliveryModel {
+	t.Helper()
+	deliveries, err := Webhook.ListDeliveries(taskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deliveries
+}
+
+func TestWebhookDeliveryWrittenWithStatus(t *testing.T) {
+	resetTasks(t)
+	t.Cleanup(func() {
+		sqllite.GetSession().Where("1 = 1").Delete(&domain.DataWebhookDeliveryModel{})
+	})
+
+	var mu sync.Mutex
+	received := map[string]string{} // event -> signature
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received[r.Header.Get(WebhookHeaderEvent)] = r.Header.Get(WebhookHeaderSignature)
+		mu.Unlock()
+		want := "sha256=" + SignWebhook("secret", r.Header.Get(WebhookHeaderTimestamp), body)
+		if r.Header.Get(WebhookHeaderSignature) != want {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("AIGCPANEL_WEBHOOK_URL", srv.URL)
+	t.Setenv("AIGCPANEL_WEBHOOK_SECRET", "secret")
+
+	// 不经过事件订阅：没有启动分发协程时状态写入也会留下投递记录
+	task := newQueuedTask(t, map[string]any{"type": domain.FunctionSoundTts}, domain.DataTaskModel{})
+	if _, err := DataTask.UpdateTask(task.ID, map[string]any{"status": domain.TaskStatusRunning}); err != nil {
+		t.Fatal(err)
+	}
+	if got := listDeliveries(t, task.ID); len(got) != 0 {
+		t.Fatalf("running should not be delivered, got %d", len(got))
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := DataTask.UpdateTask(task.ID, map[string]any{"status": domain.TaskStatusFail, "statusMsg": "cancelled"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := listDeliveries(t, task.ID); len(got) != 1 || got[0].Event != domain.TaskStatusFail {
+		t.Fatalf("expected one fail delivery, got %+v", got)
+	}
+
+	// 租约丢失的写入不产生投递记录
+	if _, err := DataTask.Continue(task.ID); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := DataTask.ClaimTask(mustGetTask(t, task.ID), taskWorkerID, time.Minute); err != nil || !ok {
+		t.Fatalf("claim: %v %v", ok, err)
+	}
+	if ok, _ := DataTask.UpdateOwnedTask(task.ID, "other-worker", map[string]any{"status": domain.TaskStatusSuccess}); ok {
+		t.Fatal("other worker should not finish the task")
+	}
+	if err := finishTask(task.ID, map[string]any{"status": domain.TaskStatusSuccess, "result": `{"url":"a.wav"}`}); err != nil {
+		t.Fatal(err)
+	}
+	deliveries := listDeliveries(t, task.ID)
+	if len(deliveries) != 2 || deliveries[0].Event != domain.TaskStatusSuccess {
+		t.Fatalf("expected success delivery, got %+v", deliveries)
+	}
+
+	Webhook.deliverDue()
+	for _, d := range listDeliveries(t, task.ID) {
+		if d.Status != domain.WebhookDeliverySuccess || d.Attempt != 1 {
+			t.Errorf("delivery %d: status %s attempt %d code %d %s", d.ID, d.Status, d.Attempt, d.ResponseCode, d.LastError)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("expected 2 callbacks, got %v", received)
+	}
+}
+
+func TestWebhookDeliverDueGroupsByURL(t *testing.T) {
+	t.Cleanup(func() {
+		sqllite.GetSession().Where("1 = 1").Delete(&domain.DataWebhookDeliveryModel{})
+	})
+
+	// 无响应的地址：收到的请求阻塞到 release 关闭，记录同一地址的最大并发和投递顺序
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	inflight, maxInflight := 0, 0
+	stuck := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inflight++
+		if inflight > maxInflight {
+			maxInflight = inflight
+		}
+		order = append(order, r.Header.Get(WebhookHeaderDelivery))
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		inflight--
+		mu.Unlock()
+	}))
+	defer stuck.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	var once sync.Once
+	unblock := func() { once.Do(func() { close(release) }) }
+	defer unblock()
+
+	create := func(u string) domain.DataWebhookDeliveryModel {
+		t.Helper()
+		delivery := domain.DataWebhookDeliveryModel{TaskID: 1, Event: domain.TaskStatusSuccess, URL: u, Payload: "{}", Status: domain.WebhookDeliveryPending}
+		if err := sqllite.GetSession().Create(&delivery).Error; err != nil {
+			t.Fatal(err)
+		}
+		return delivery
+	}
+	stuckIDs := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		stuckIDs = append(stuckIDs, strconv.FormatInt(create(stuck.URL).ID, 10))
+	}
+	ok := create(healthy.URL)
+
+	done := make(chan struct{})
+	go func() {
+		Webhook.deliverDue()
+		close(done)
+	}()
+
+	// 其它地址的投递不等待无响应的地址
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var got domain.DataWebhookDeliveryModel
+		sqllite.GetSession().First(&got, ok.ID)
+		if got.Status == domain.WebhookDeliverySuccess {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("healthy delivery blocked by the stuck endpoint: %+v", got)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	unblock()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("deliverDue did not finish")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if maxInflight != 1 || strings.Join(order, ",") != strings.Join(stuckIDs, ",") {
+		t.Fatalf("same url should be delivered one by one in order: max %d order %v want %v", maxInflight, order, stuckIDs)
+	}
+	for _, d := range listDeliveries(t, 1) {
+		if d.Status != domain.WebhookDeliverySuccess {
+			t.Errorf("delivery %d: status %s %s", d.ID, d.Status, d.LastError)
+		}
+	}
+}
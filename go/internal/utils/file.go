@@ -16,6 +16,8 @@ var SQLiteFile string
 var StorageDir string
 var JsonDir string
 var LogDir string
+var PidDir string
+var CacheDir string
 
 func InitDirs() {
 
@@ -33,11 +35,15 @@ func InitDirs() {
 	StorageDir = filepath.Join(DataDir, "storage")
 	JsonDir = filepath.Join(DataDir, "json")
 	LogDir = filepath.Join(DataDir, "logs")
+	PidDir = filepath.Join(DataDir, "pid")
+	CacheDir = filepath.Join(DataDir, "cache")
 
 	// ===== 创建目录 =====
 	mustMkdir(DataDir)
 	mustMkdir(StorageDir)
 	mustMkdir(JsonDir)
+	mustMkdir(PidDir)
+	mustMkdir(CacheDir)
 }
 func mustMkdir(dir string) {
 	err := os.MkdirAll(dir, 0755)
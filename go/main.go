@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"os"
 	"xiacutai-server/internal/component/sqllite"
 	"xiacutai-server/internal/router"
 	"xiacutai-server/internal/service"
@@ -10,12 +11,20 @@ import (
 
 func main() {
 
+	// xiacutai-server install [-sha256 <hex>] <model.zip>
+	if len(os.Args) > 1 && os.Args[1] == "install" {
+		os.Exit(install(os.Args[2:]))
+	}
+
 	utils.InitDirs()
 	sqllite.Init()
 	//service.InitModelRegistry()
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	service.StartTaskScheduler(ctx)
+	service.StartWebhookDispatcher(ctx)
+	service.StartResidentModels()
+	defer service.StopResidentModels()
 
 	router.Run()
 